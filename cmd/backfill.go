@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var backfillCmd = &cobra.Command{
+	Use:   "backfill <report> --from <date> --to <date>",
+	Short: "run a report once for each day, week or month in a historical date range",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := config.BackfillOptions{}
+		for flag, t := range map[string]*time.Time{"from": &opts.From, "to": &opts.To} {
+			s, err := cmd.Flags().GetString(flag)
+			if err != nil {
+				return err
+			}
+			*t, err = parseTimeFlag(s)
+			if err != nil {
+				return err
+			}
+		}
+		opts.Step, _ = cmd.Flags().GetString("step")
+		opts.Restart, _ = cmd.Flags().GetBool("restart")
+
+		c, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		return c.Backfill(args[0], opts)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backfillCmd)
+
+	backfillCmd.Flags().String("config", defaultConfigPath(), "Path to encode.yaml")
+	backfillCmd.Flags().String("from", "", "A date in the first window (YYYY-MM-DD)")
+	backfillCmd.Flags().String("to", "", "A date in the last window (YYYY-MM-DD)")
+	backfillCmd.Flags().String("step", config.BackfillStepDay, "Window size: day, week or month")
+	backfillCmd.Flags().Bool("restart", false, "Start over instead of resuming a failed backfill of the same range")
+	_ = backfillCmd.MarkFlagRequired("from")
+	_ = backfillCmd.MarkFlagRequired("to")
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/history"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [report]",
+	Short: "list past report runs, newest first",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter := history.Filter{}
+		if len(args) == 1 {
+			filter.Report = args[0]
+		}
+		filter.Trigger, _ = cmd.Flags().GetString("trigger")
+		filter.Status, _ = cmd.Flags().GetString("status")
+		filter.Limit, _ = cmd.Flags().GetInt("limit")
+		since, _ := cmd.Flags().GetString("since")
+		if since != "" {
+			t, err := parseTimeFlag(since)
+			if err != nil {
+				return err
+			}
+			filter.Since = t
+		}
+
+		c, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if filter.Report != "" {
+			if _, err := c.Report(filter.Report); err != nil {
+				return err
+			}
+		}
+		records, err := c.History().List(filter)
+		if err != nil {
+			return err
+		}
+
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			for _, r := range records {
+				if err := enc.Encode(r); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "RUN\tREPORT\tTRIGGER\tSTARTED\tDURATION\tSTATUS\tROWS\tBYTES\tERROR")
+		for _, r := range records {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%s\n", r.ID, r.Report, r.Trigger, r.Start.Local().Format(time.RFC3339), r.Duration.Round(time.Millisecond), r.Status, r.Rows, r.Bytes, r.Error)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().String("config", defaultConfigPath(), "Path to encode.yaml")
+	historyCmd.Flags().String("trigger", "", "Only runs started by cron, catch-up, manual or backfill")
+	historyCmd.Flags().String("status", "", "Only runs with this status: succeeded, failed, halted or quarantined")
+	historyCmd.Flags().String("since", "", "Only runs started at or after this time (RFC 3339 or YYYY-MM-DD[ HH:MM[:SS]])")
+	historyCmd.Flags().Int("limit", 20, "Most runs to list; 0 lists all")
+	historyCmd.Flags().Bool("json", false, "Print full records, including files and S3 URIs, as JSON lines")
+}
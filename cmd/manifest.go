@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/config"
+	"github.com/lehigh-university-libraries/encode/pkg/storage"
+	"github.com/spf13/cobra"
+)
+
+// timeFlagLayouts are the formats accepted by timestamp flags such as manifest rollback --to
+var timeFlagLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02.15.04.05",
+	"2006-01-02",
+}
+
+var manifestCmd = &cobra.Command{
+	Use:   "manifest",
+	Short: "inspect and repair a report's QuickSight manifest",
+}
+
+var manifestShowCmd = &cobra.Command{
+	Use:   "show <report>",
+	Short: "print the files in a report's manifest with their sizes and dates",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		uploader, err := manifestUploader(cmd, args[0])
+		if err != nil {
+			return err
+		}
+
+		entries, err := uploader.DescribeManifest(args[0])
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "URI\tSIZE\tLAST MODIFIED")
+		for _, e := range entries {
+			if e.Missing {
+				fmt.Fprintf(w, "%s\t-\tMISSING\n", e.URI)
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\n", e.URI, e.Size, e.LastModified.Local().Format(time.RFC3339))
+		}
+		fmt.Fprintf(w, "%d files\n", len(entries))
+		return w.Flush()
+	},
+}
+
+var manifestRebuildCmd = &cobra.Command{
+	Use:   "rebuild <report>",
+	Short: "regenerate a report's manifest from the files under its S3 prefix",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		report, err := c.Report(args[0])
+		if err != nil {
+			return err
+		}
+		uploader, err := c.ManifestUploader(args[0])
+		if err != nil {
+			return err
+		}
+
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		uris, err := uploader.RebuildManifest(args[0], storage.UploadSettingsFor(report.Output), dryRun)
+		if err != nil {
+			return err
+		}
+		return printURIs(uris, dryRun, "would list", "listed")
+	},
+}
+
+var manifestPruneCmd = &cobra.Command{
+	Use:   "prune <report>",
+	Short: "apply the report's retention policy to its manifest",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		uploader, err := manifestUploader(cmd, args[0])
+		if err != nil {
+			return err
+		}
+		if !uploader.Config().Retention.Enabled() {
+			return fmt.Errorf("report '%s' has no s3.retention rules", args[0])
+		}
+
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		uris, err := uploader.PruneManifest(args[0], dryRun)
+		if err != nil {
+			return err
+		}
+		return printURIs(uris, dryRun, "would prune", "pruned")
+	},
+}
+
+var manifestRollbackCmd = &cobra.Command{
+	Use:   "rollback <report> --to <timestamp>",
+	Short: "drop files added to a report's manifest after a point in time",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		to, err := cmd.Flags().GetString("to")
+		if err != nil {
+			return err
+		}
+		t, err := parseTimeFlag(to)
+		if err != nil {
+			return err
+		}
+
+		uploader, err := manifestUploader(cmd, args[0])
+		if err != nil {
+			return err
+		}
+
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		uris, err := uploader.RollbackManifest(args[0], t, dryRun)
+		if err != nil {
+			return err
+		}
+		return printURIs(uris, dryRun, "would remove", "removed")
+	},
+}
+
+func loadConfig(cmd *cobra.Command) (*config.Config, error) {
+	f, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return nil, err
+	}
+	c, err := config.LoadConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return c, nil
+}
+
+func manifestUploader(cmd *cobra.Command, reportName string) (*storage.S3Uploader, error) {
+	c, err := loadConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return c.ManifestUploader(reportName)
+}
+
+func parseTimeFlag(s string) (time.Time, error) {
+	for _, layout := range timeFlagLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp '%s': use RFC 3339 or YYYY-MM-DD[ HH:MM[:SS]]", s)
+}
+
+func printURIs(uris []string, dryRun bool, dryVerb, verb string) error {
+	for _, uri := range uris {
+		fmt.Println(uri)
+	}
+	if dryRun {
+		fmt.Printf("dry run: %s %d files\n", dryVerb, len(uris))
+		return nil
+	}
+	fmt.Printf("%s %d files\n", verb, len(uris))
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(manifestCmd)
+	manifestCmd.PersistentFlags().String("config", defaultConfigPath(), "Path to encode.yaml")
+
+	for _, c := range []*cobra.Command{manifestRebuildCmd, manifestPruneCmd, manifestRollbackCmd} {
+		c.Flags().Bool("dry-run", false, "List the changes without writing the manifest")
+	}
+	manifestRollbackCmd.Flags().String("to", "", "Keep only files that were in the manifest at this time (RFC 3339 or YYYY-MM-DD[ HH:MM[:SS]])")
+	_ = manifestRollbackCmd.MarkFlagRequired("to")
+
+	manifestCmd.AddCommand(manifestShowCmd, manifestRebuildCmd, manifestPruneCmd, manifestRollbackCmd)
+}
@@ -2,13 +2,18 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"strings"
 
+	"github.com/lehigh-university-libraries/encode/pkg/logging"
 	"github.com/spf13/cobra"
 )
 
+// logFile is the file logs are written to with --log-file, closed when the command ends
+var logFile io.Closer
+
 var rootCmd = &cobra.Command{
 	Use:   "encode",
 	Short: "Fetch reports from various sources",
@@ -28,14 +33,28 @@ var rootCmd = &cobra.Command{
 			level = slog.LevelError
 		}
 
-		opts := &slog.HandlerOptions{
-			Level: level,
+		opts := logging.Options{Level: level}
+		opts.Format, _ = cmd.Flags().GetString("log-format")
+		opts.File, _ = cmd.Flags().GetString("log-file")
+		maxSize, _ := cmd.Flags().GetInt64("log-max-size")
+		opts.MaxSize = maxSize * 1024 * 1024
+		opts.MaxFiles, _ = cmd.Flags().GetInt("log-max-files")
+
+		handler, closer, err := logging.NewHandler(opts)
+		if err != nil {
+			return err
 		}
-		handler := slog.New(slog.NewTextHandler(os.Stdout, opts))
-		slog.SetDefault(handler)
+		logFile = closer
+		slog.SetDefault(slog.New(handler))
 
 		return nil
 	},
+	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+		if logFile != nil {
+			return logFile.Close()
+		}
+		return nil
+	},
 }
 
 func Execute() {
@@ -50,9 +69,17 @@ func SetVersionInfo(version, commit, date string) {
 }
 
 func init() {
-	ll := os.Getenv("LOG_LEVEL")
-	if ll == "" {
-		ll = "INFO"
+	rootCmd.PersistentFlags().String("log-level", envOr("LOG_LEVEL", "INFO"), "The logging level for the command")
+	rootCmd.PersistentFlags().String("log-format", envOr("LOG_FORMAT", logging.FormatText), "The log line format: text or json")
+	rootCmd.PersistentFlags().String("log-file", os.Getenv("LOG_FILE"), "Write logs to this file instead of stdout, rotating it by size")
+	rootCmd.PersistentFlags().Int64("log-max-size", 100, "With --log-file, the size in MB the log file is rotated at")
+	rootCmd.PersistentFlags().Int("log-max-files", 5, "With --log-file, how many rotated log files are kept")
+}
+
+// envOr is the environment variable key, or fallback if it is unset
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
 	}
-	rootCmd.PersistentFlags().String("log-level", ll, "The logging level for the command")
+	return fallback
 }
@@ -1,12 +1,20 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"log/slog"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/lehigh-university-libraries/encode/pkg/api"
 	"github.com/lehigh-university-libraries/encode/pkg/config"
+	"github.com/lehigh-university-libraries/encode/pkg/dashboard"
+	"github.com/lehigh-university-libraries/encode/pkg/metrics"
 	"github.com/spf13/cobra"
 )
 
@@ -27,14 +35,54 @@ var runCmd = &cobra.Command{
 		// Check if one-time report execution was requested
 		reportName, _ := cmd.Flags().GetString("report")
 		if reportName != "" {
+			acceptDrift, _ := cmd.Flags().GetBool("accept-schema-drift")
+			if acceptDrift {
+				report, err := c.Report(reportName)
+				if err != nil {
+					return err
+				}
+				return report.Execute(config.RunOptions{Trigger: config.TriggerManual, AcceptDrift: true})
+			}
 			return c.RunReportOnce(reportName)
 		}
 
+		addr, _ := cmd.Flags().GetString("http-addr")
+		withDashboard, _ := cmd.Flags().GetBool("dashboard")
+		dashboardPassword := os.Getenv("ENCODE_DASHBOARD_PASSWORD")
+		if withDashboard && addr == "" {
+			return errors.New("--dashboard requires --http-addr")
+		}
+		if withDashboard && dashboardPassword == "" {
+			return errors.New("--dashboard requires $ENCODE_DASHBOARD_PASSWORD")
+		}
+
+		// Run the ticks missed while encode was down
+		c.CatchUp(time.Now())
+
 		// Start cron scheduler for all reports
 		cron := c.StartCron()
 		cron.Start()
 		slog.Info("Cron scheduler started")
 
+		if addr != "" {
+			c.ObserveSchedule(cron)
+			mux := http.NewServeMux()
+			mux.Handle("GET /metrics", metrics.Default.Handler())
+			if withDashboard {
+				dashboard.New(c, cron, dashboardPassword).Register(mux)
+			}
+			token := os.Getenv("ENCODE_API_TOKEN")
+			if token != "" {
+				api.New(c, cron, token).Register(mux)
+			} else {
+				slog.Info("Admin API disabled; set ENCODE_API_TOKEN to enable it")
+			}
+			err = serveHTTP(addr, mux)
+			if err != nil {
+				return err
+			}
+		}
+
 		// Block forever
 		select {}
 	},
@@ -43,6 +91,30 @@ var runCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(runCmd)
 
+	runCmd.Flags().String("config", defaultConfigPath(), "Path to encode.yaml")
+	runCmd.Flags().String("report", "", "Run a specific report once (for testing) instead of starting the cron scheduler")
+	runCmd.Flags().String("http-addr", "", "Serve Prometheus metrics at /metrics, and the admin API at /api/ if $ENCODE_API_TOKEN is set, on this address, e.g. :9090")
+	runCmd.Flags().Bool("dashboard", false, "With --http-addr, serve a read-only status page of every report at /, behind the password in $ENCODE_DASHBOARD_PASSWORD")
+	runCmd.Flags().Bool("accept-schema-drift", false, "With --report, publish the run even if its schema drifted and make it the new baseline")
+}
+
+// serveHTTP starts encode's HTTP server in the background
+func serveHTTP(addr string, handler http.Handler) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %s: %w", addr, err)
+	}
+	slog.Info("HTTP server started", "addr", ln.Addr().String())
+	go func() {
+		server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+		err := server.Serve(ln)
+		slog.Error("HTTP server stopped", "err", err)
+	}()
+	return nil
+}
+
+// defaultConfigPath is $ENCODE_CONFIG_YAML, or encode.yaml in the home directory
+func defaultConfigPath() string {
 	config := os.Getenv("ENCODE_CONFIG_YAML")
 	if config == "" {
 		h, err := os.UserHomeDir()
@@ -52,6 +124,5 @@ func init() {
 		}
 		config = filepath.Join(h, "encode.yaml")
 	}
-	runCmd.Flags().String("config", config, "Path to encode.yaml")
-	runCmd.Flags().String("report", "", "Run a specific report once (for testing) instead of starting the cron scheduler")
+	return config
 }
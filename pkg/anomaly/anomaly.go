@@ -0,0 +1,194 @@
+// Package anomaly checks a run's row count and column metrics against
+// fixed bounds and the report's earlier runs
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Metrics a check can measure
+const (
+	MetricRows     = "rows"
+	MetricSum      = "sum"
+	MetricDistinct = "distinct"
+)
+
+// DefaultWindow is how many earlier runs a rolling mean covers by default
+const DefaultWindow = 10
+
+// Check is one rule on a metric of a run's results
+type Check struct {
+	// Metric is rows (default), sum or distinct
+	Metric string `yaml:"metric"`
+	// Column is the column sum and distinct measure
+	Column string `yaml:"column"`
+	// Min and Max are absolute bounds on the metric
+	Min *float64 `yaml:"min"`
+	Max *float64 `yaml:"max"`
+	// MaxChange is the largest percent change allowed from the previous run
+	MaxChange *float64 `yaml:"max_change"`
+	// MaxDeviation is the largest percent difference allowed from the mean of the last Window runs
+	MaxDeviation *float64 `yaml:"max_deviation"`
+	// Window is how many earlier runs the rolling mean covers (default 10)
+	Window int `yaml:"window"`
+}
+
+// Name identifies the check's metric in run history, e.g. rows or sum(count)
+func (c Check) Name() string {
+	switch c.Metric {
+	case "", MetricRows:
+		return MetricRows
+	}
+	return c.Metric + "(" + c.Column + ")"
+}
+
+// History is how many earlier runs the check needs to look at
+func (c Check) History() int {
+	n := 0
+	if c.MaxChange != nil {
+		n = 1
+	}
+	if c.MaxDeviation != nil {
+		n = c.window()
+	}
+	return n
+}
+
+func (c Check) window() int {
+	if c.Window > 0 {
+		return c.Window
+	}
+	return DefaultWindow
+}
+
+// Validate checks that the check is complete
+func (c Check) Validate() error {
+	switch c.Metric {
+	case "", MetricRows:
+		if c.Column != "" {
+			return fmt.Errorf("rows check does not take a column")
+		}
+	case MetricSum, MetricDistinct:
+		if c.Column == "" {
+			return fmt.Errorf("%s check needs a column", c.Metric)
+		}
+	default:
+		return fmt.Errorf("invalid metric '%s': must be %s, %s or %s", c.Metric, MetricRows, MetricSum, MetricDistinct)
+	}
+	if c.Min == nil && c.Max == nil && c.MaxChange == nil && c.MaxDeviation == nil {
+		return fmt.Errorf("%s check needs min, max, max_change or max_deviation", c.Name())
+	}
+	if c.Min != nil && c.Max != nil && *c.Min > *c.Max {
+		return fmt.Errorf("%s check min is greater than max", c.Name())
+	}
+	for _, p := range []*float64{c.MaxChange, c.MaxDeviation} {
+		if p != nil && *p < 0 {
+			return fmt.Errorf("%s check percentages cannot be negative", c.Name())
+		}
+	}
+	if c.Window < 0 {
+		return fmt.Errorf("%s check window cannot be negative", c.Name())
+	}
+	return nil
+}
+
+// Measure computes the metric of every check over a result, keyed by check name
+func Measure(checks []Check, columns []string, rows []map[string]string) (map[string]float64, error) {
+	metrics := make(map[string]float64, len(checks))
+	for _, c := range checks {
+		name := c.Name()
+		if _, ok := metrics[name]; ok {
+			continue
+		}
+		if c.Metric != "" && c.Metric != MetricRows && !slices.Contains(columns, c.Column) {
+			return nil, fmt.Errorf("%s check: column '%s' not in results", name, c.Column)
+		}
+		switch c.Metric {
+		case "", MetricRows:
+			metrics[name] = float64(len(rows))
+		case MetricSum:
+			sum := 0.0
+			for i, row := range rows {
+				v := row[c.Column]
+				if v == "" {
+					continue
+				}
+				f, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					return nil, fmt.Errorf("%s check: row %d: '%s' is not a number", name, i+2, v)
+				}
+				sum += f
+			}
+			metrics[name] = sum
+		case MetricDistinct:
+			seen := make(map[string]struct{})
+			for _, row := range rows {
+				seen[row[c.Column]] = struct{}{}
+			}
+			metrics[name] = float64(len(seen))
+		}
+	}
+	return metrics, nil
+}
+
+// Evaluate checks a metric's value. previous holds the metric's values from
+// earlier runs, newest first; rules that compare against history are skipped
+// until there is some. It returns the problems found
+func (c Check) Evaluate(value float64, previous []float64) []string {
+	var problems []string
+	name := c.Name()
+	if c.Min != nil && value < *c.Min {
+		problems = append(problems, fmt.Sprintf("%s is %s, below the minimum of %s", name, format(value), format(*c.Min)))
+	}
+	if c.Max != nil && value > *c.Max {
+		problems = append(problems, fmt.Sprintf("%s is %s, above the maximum of %s", name, format(value), format(*c.Max)))
+	}
+	if c.MaxChange != nil && len(previous) > 0 {
+		if change := percentChange(previous[0], value); change > *c.MaxChange {
+			problems = append(problems, fmt.Sprintf("%s is %s, %s%% away from the previous run's %s (max %s%%)", name, format(value), format(change), format(previous[0]), format(*c.MaxChange)))
+		}
+	}
+	if c.MaxDeviation != nil && len(previous) > 0 {
+		window := previous[:min(len(previous), c.window())]
+		mean := 0.0
+		for _, v := range window {
+			mean += v
+		}
+		mean /= float64(len(window))
+		if change := percentChange(mean, value); change > *c.MaxDeviation {
+			problems = append(problems, fmt.Sprintf("%s is %s, %s%% away from the mean of the last %d runs, %s (max %s%%)", name, format(value), format(change), len(window), format(mean), format(*c.MaxDeviation)))
+		}
+	}
+	return problems
+}
+
+// Error lists the checks a run failed
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("anomaly check failed with %d problems: %s", len(e.Problems), strings.Join(e.Problems, "; "))
+}
+
+// percentChange is how far value is from base, as a percent of base
+func percentChange(base, value float64) float64 {
+	if base == 0 {
+		if value == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return math.Abs(value-base) / math.Abs(base) * 100
+}
+
+func format(f float64) string {
+	if math.IsInf(f, 0) {
+		return "∞"
+	}
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
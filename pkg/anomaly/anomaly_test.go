@@ -0,0 +1,110 @@
+package anomaly_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lehigh-university-libraries/encode/pkg/anomaly"
+)
+
+func ptr(f float64) *float64 {
+	return &f
+}
+
+func TestCheckValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		check       anomaly.Check
+		expectError bool
+	}{
+		{name: "Rows min", check: anomaly.Check{Min: ptr(100)}},
+		{name: "Sum with column", check: anomaly.Check{Metric: "sum", Column: "count", MaxChange: ptr(50)}},
+		{name: "No rules", check: anomaly.Check{}, expectError: true},
+		{name: "Sum without column", check: anomaly.Check{Metric: "sum", Max: ptr(1)}, expectError: true},
+		{name: "Rows with column", check: anomaly.Check{Column: "id", Max: ptr(1)}, expectError: true},
+		{name: "Unknown metric", check: anomaly.Check{Metric: "median", Column: "id", Max: ptr(1)}, expectError: true},
+		{name: "Min above max", check: anomaly.Check{Min: ptr(10), Max: ptr(1)}, expectError: true},
+		{name: "Negative percent", check: anomaly.Check{MaxDeviation: ptr(-5)}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.check.Validate()
+			if tt.expectError && err == nil {
+				t.Errorf("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	columns := []string{"branch", "count"}
+	rows := []map[string]string{
+		{"branch": "Linderman", "count": "10"},
+		{"branch": "Fairchild", "count": "2.5"},
+		{"branch": "Linderman", "count": ""},
+	}
+	checks := []anomaly.Check{
+		{Min: ptr(1)},
+		{Metric: "sum", Column: "count", Min: ptr(1)},
+		{Metric: "distinct", Column: "branch", Min: ptr(1)},
+	}
+
+	metrics, err := anomaly.Measure(checks, columns, rows)
+	if err != nil {
+		t.Fatalf("Measure() failed: %v", err)
+	}
+	expected := map[string]float64{"rows": 3, "sum(count)": 12.5, "distinct(branch)": 2}
+	for name, want := range expected {
+		if metrics[name] != want {
+			t.Errorf("Expected %s = %v, got %v", name, want, metrics[name])
+		}
+	}
+
+	_, err = anomaly.Measure([]anomaly.Check{{Metric: "sum", Column: "branch", Min: ptr(1)}}, columns, rows)
+	if err == nil {
+		t.Errorf("Expected error summing a text column but got none")
+	}
+	_, err = anomaly.Measure([]anomaly.Check{{Metric: "distinct", Column: "patron", Min: ptr(1)}}, columns, rows)
+	if err == nil {
+		t.Errorf("Expected error for a missing column but got none")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name     string
+		check    anomaly.Check
+		value    float64
+		previous []float64
+		expect   []string
+	}{
+		{name: "Within bounds", check: anomaly.Check{Min: ptr(100), Max: ptr(5000)}, value: 3000},
+		{name: "Below minimum", check: anomaly.Check{Min: ptr(100)}, value: 3, expect: []string{"below the minimum"}},
+		{name: "Above maximum", check: anomaly.Check{Max: ptr(10)}, value: 11, expect: []string{"above the maximum"}},
+		{name: "First run skips change", check: anomaly.Check{MaxChange: ptr(10)}, value: 3},
+		{name: "Small change", check: anomaly.Check{MaxChange: ptr(10)}, value: 2950, previous: []float64{3000, 10}},
+		{name: "Large drop", check: anomaly.Check{MaxChange: ptr(50)}, value: 3, previous: []float64{3000}, expect: []string{"from the previous run"}},
+		{name: "Change from zero", check: anomaly.Check{MaxChange: ptr(50)}, value: 3, previous: []float64{0}, expect: []string{"∞%"}},
+		{name: "Near the mean", check: anomaly.Check{MaxDeviation: ptr(20)}, value: 100, previous: []float64{90, 110, 100}},
+		{name: "Far from the mean", check: anomaly.Check{MaxDeviation: ptr(20)}, value: 150, previous: []float64{90, 110, 100}, expect: []string{"mean of the last 3 runs, 100"}},
+		{name: "Window limits the mean", check: anomaly.Check{MaxDeviation: ptr(20), Window: 2}, value: 100, previous: []float64{100, 100, 1000000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := tt.check.Evaluate(tt.value, tt.previous)
+			if len(problems) != len(tt.expect) {
+				t.Fatalf("Expected %d problems, got %v", len(tt.expect), problems)
+			}
+			for i, want := range tt.expect {
+				if !strings.Contains(problems[i], want) {
+					t.Errorf("Expected problem to mention %q, got %q", want, problems[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+// Package api serves encode's admin API: report status, on-demand runs and
+// pausing schedules, behind a bearer token
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/config"
+	"github.com/lehigh-university-libraries/encode/pkg/history"
+	cron "github.com/robfig/cron/v3"
+)
+
+// Server answers the admin API for one config and its scheduler
+type Server struct {
+	config    *config.Config
+	scheduler *cron.Cron
+	token     string
+}
+
+// New returns the API for c. Every request must send token as a bearer token
+func New(c *config.Config, scheduler *cron.Cron, token string) *Server {
+	return &Server{config: c, scheduler: scheduler, token: token}
+}
+
+// Report is a report's schedule and its latest runs
+type Report struct {
+	Name     string   `json:"name"`
+	Schedule string   `json:"schedule"`
+	Owners   []string `json:"owners,omitempty"`
+	// Paused is when the schedule was paused
+	Paused time.Time `json:"paused,omitzero"`
+	// NextRun is when the scheduler runs the report next, unless it is paused
+	NextRun time.Time `json:"next_run,omitzero"`
+	// Running is the run in progress
+	Running *history.Record `json:"running,omitempty"`
+	// LastRun is the most recent finished run
+	LastRun *history.Record `json:"last_run,omitempty"`
+}
+
+// RunRequest starts a run
+type RunRequest struct {
+	// Params override the report's query params and named params by name
+	Params map[string]string `json:"params"`
+	// AcceptSchemaDrift publishes the run even if its schema drifted
+	AcceptSchemaDrift bool `json:"accept_schema_drift"`
+}
+
+// Register adds the API's routes under /api/ to mux
+func (s *Server) Register(mux *http.ServeMux) {
+	mux.Handle("GET /api/reports", s.auth(s.listReports))
+	mux.Handle("GET /api/reports/{name}", s.auth(s.getReport))
+	mux.Handle("POST /api/reports/{name}/runs", s.auth(s.startRun))
+	mux.Handle("POST /api/reports/{name}/pause", s.auth(s.pause))
+	mux.Handle("POST /api/reports/{name}/resume", s.auth(s.resume))
+	mux.Handle("GET /api/runs/{id}", s.auth(s.getRun))
+	mux.Handle("GET /api/runs/{id}/logs", s.auth(s.getRunLogs))
+}
+
+// auth rejects requests without the server's bearer token
+func (s *Server) auth(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || s.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="encode"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+			return
+		}
+		next(w, r)
+	})
+}
+
+func (s *Server) listReports(w http.ResponseWriter, r *http.Request) {
+	next := config.NextRuns(s.scheduler)
+	reports := make([]Report, 0, len(s.config.Reports))
+	for _, report := range s.config.Reports {
+		status, err := s.report(report, next)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		reports = append(reports, status)
+	}
+	writeJSON(w, http.StatusOK, reports)
+}
+
+func (s *Server) getReport(w http.ResponseWriter, r *http.Request) {
+	report, err := s.config.Report(r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	status, err := s.report(*report, config.NextRuns(s.scheduler))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) report(report config.ReportConfig, next map[string]time.Time) (Report, error) {
+	status := Report{
+		Name:     report.Name,
+		Schedule: report.Schedule,
+		Owners:   report.Owners,
+	}
+	var err error
+	status.Paused, err = report.Paused()
+	if err != nil {
+		return status, err
+	}
+	if status.Paused.IsZero() {
+		status.NextRun = next[report.Name]
+	}
+	if running, ok := s.config.Running(report.Name); ok {
+		status.Running = running
+	}
+	last, err := s.config.History().List(history.Filter{Report: report.Name, Limit: 1})
+	if err != nil {
+		return status, err
+	}
+	if len(last) == 1 {
+		status.LastRun = &last[0]
+	}
+	return status, nil
+}
+
+func (s *Server) startRun(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, err := s.config.Report(name); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	var req RunRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	id, err := s.config.Start(name, config.RunOptions{
+		Trigger:     config.TriggerManual,
+		Params:      req.Params,
+		AcceptDrift: req.AcceptSchemaDrift,
+	})
+	switch {
+	case errors.Is(err, config.ErrInvalidParams):
+		writeError(w, http.StatusBadRequest, err)
+		return
+	case errors.Is(err, config.ErrRunning):
+		writeError(w, http.StatusConflict, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	slog.Info("Run started through the API", "report", name, "run", id, "params", len(req.Params))
+
+	rec, err := s.config.RunRecord(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Location", "/api/runs/"+id)
+	writeJSON(w, http.StatusAccepted, rec)
+}
+
+func (s *Server) pause(w http.ResponseWriter, r *http.Request) {
+	s.setPaused(w, r, s.config.Pause)
+}
+
+func (s *Server) resume(w http.ResponseWriter, r *http.Request) {
+	s.setPaused(w, r, s.config.Resume)
+}
+
+func (s *Server) setPaused(w http.ResponseWriter, r *http.Request, change func(string) error) {
+	name := r.PathValue("name")
+	if _, err := s.config.Report(name); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	err := change(name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.getReport(w, r)
+}
+
+func (s *Server) getRun(w http.ResponseWriter, r *http.Request) {
+	rec, err := s.config.RunRecord(r.PathValue("id"))
+	if errors.Is(err, history.ErrNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rec)
+}
+
+func (s *Server) getRunLogs(w http.ResponseWriter, r *http.Request) {
+	log, err := s.config.RunLog(r.PathValue("id"))
+	if errors.Is(err, history.ErrNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write(log)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		slog.Error("Unable to write API response", "err", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,208 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/api"
+	"github.com/lehigh-university-libraries/encode/pkg/config"
+	"github.com/lehigh-university-libraries/encode/pkg/history"
+)
+
+const token = "secret"
+
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	staging := t.TempDir()
+	filename := filepath.Join(staging, "encode.yaml")
+	err := os.WriteFile(filename, []byte(`
+stagingDirectory: `+staging+`
+
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: users
+    connection: mock
+    schedule: "0 12 * * *"
+    owners: [owner@example.edu]
+    query_params:
+      rows: "2"
+    sinks:
+      - type: Local
+        path: `+filepath.Join(staging, "out")+`
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := config.LoadConfig(filename)
+	if err != nil {
+		t.Fatalf("LoadConfig() failed: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	api.New(c, c.StartCron(), token).Register(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func do(t *testing.T, server *httptest.Server, method, path, body string) (int, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, data
+}
+
+func TestAuth(t *testing.T) {
+	server := newServer(t)
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{name: "No token"},
+		{name: "Wrong token", header: "Bearer nope"},
+		{name: "Not a bearer token", header: token},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, server.URL+"/api/reports", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Errorf("Expected status 401, got %d", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestReports(t *testing.T) {
+	server := newServer(t)
+
+	status, body := do(t, server, http.MethodGet, "/api/reports", "")
+	if status != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", status, body)
+	}
+	var reports []api.Report
+	err := json.Unmarshal(body, &reports)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || reports[0].Name != "users" || reports[0].Schedule != "0 12 * * *" || reports[0].NextRun.IsZero() || reports[0].LastRun != nil {
+		t.Fatalf("Unexpected reports %+v", reports)
+	}
+
+	status, body = do(t, server, http.MethodPost, "/api/reports/users/pause", "")
+	var report api.Report
+	err = json.Unmarshal(body, &report)
+	if status != http.StatusOK || err != nil || report.Paused.IsZero() || !report.NextRun.IsZero() {
+		t.Fatalf("Expected paused report without a next run, got %d %s", status, body)
+	}
+	status, body = do(t, server, http.MethodPost, "/api/reports/users/resume", "")
+	report = api.Report{}
+	err = json.Unmarshal(body, &report)
+	if status != http.StatusOK || err != nil || !report.Paused.IsZero() || report.NextRun.IsZero() {
+		t.Fatalf("Expected resumed report with a next run, got %d %s", status, body)
+	}
+
+	status, _ = do(t, server, http.MethodGet, "/api/reports/missing", "")
+	if status != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown report, got %d", status)
+	}
+	status, _ = do(t, server, http.MethodPost, "/api/reports/missing/pause", "")
+	if status != http.StatusNotFound {
+		t.Errorf("Expected status 404 pausing an unknown report, got %d", status)
+	}
+}
+
+func TestRuns(t *testing.T) {
+	server := newServer(t)
+
+	status, body := do(t, server, http.MethodPost, "/api/reports/users/runs", `{"params": {"branch": "Fairchild"}}`)
+	if status != http.StatusBadRequest {
+		t.Errorf("Expected status 400 overriding an unknown param, got %d: %s", status, body)
+	}
+	status, _ = do(t, server, http.MethodPost, "/api/reports/missing/runs", "")
+	if status != http.StatusNotFound {
+		t.Errorf("Expected status 404 running an unknown report, got %d", status)
+	}
+
+	status, body = do(t, server, http.MethodPost, "/api/reports/users/runs", `{"params": {"rows": "1"}}`)
+	if status != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d: %s", status, body)
+	}
+	var rec history.Record
+	err := json.Unmarshal(body, &rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.ID == "" || rec.Report != "users" || rec.Trigger != config.TriggerManual {
+		t.Fatalf("Unexpected run %+v", rec)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for rec.Status == history.StatusRunning && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		status, body = do(t, server, http.MethodGet, "/api/runs/"+rec.ID, "")
+		if status != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", status, body)
+		}
+		err = json.Unmarshal(body, &rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rec.Status != history.StatusSucceeded || rec.Rows != 1 {
+		t.Fatalf("Expected a succeeded run with the overridden row count, got %+v", rec)
+	}
+
+	status, body = do(t, server, http.MethodGet, "/api/runs/"+rec.ID+"/logs", "")
+	if status != http.StatusOK || !strings.Contains(string(body), "Report run finished") || !strings.Contains(string(body), "run="+rec.ID) {
+		t.Errorf("Expected the run's log, got %d: %s", status, body)
+	}
+
+	status, body = do(t, server, http.MethodGet, "/api/reports/users", "")
+	var report api.Report
+	err = json.Unmarshal(body, &report)
+	if status != http.StatusOK || err != nil || report.LastRun == nil || report.LastRun.ID != rec.ID || report.Running != nil {
+		t.Errorf("Expected the run as the report's last run, got %d %s", status, body)
+	}
+
+	status, _ = do(t, server, http.MethodGet, "/api/runs/missing", "")
+	if status != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown run, got %d", status)
+	}
+	status, _ = do(t, server, http.MethodGet, "/api/runs/missing/logs", "")
+	if status != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown run's logs, got %d", status)
+	}
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/lehigh-university-libraries/encode/pkg/anomaly"
+	"github.com/lehigh-university-libraries/encode/pkg/connection"
+	"github.com/lehigh-university-libraries/encode/pkg/format"
+	"github.com/lehigh-university-libraries/encode/pkg/history"
+)
+
+// quarantineDirectory is where runs that fail an anomaly check are written,
+// under the report's staging directory
+const quarantineDirectory = "quarantine"
+
+// checkAnomalies measures a run's metrics into rec and checks them against
+// the report's earlier successful runs. A failed check returns *anomaly.Error
+func (r ReportConfig) checkAnomalies(result *connection.Result, rec *history.Record) error {
+	if len(r.Checks) == 0 {
+		return nil
+	}
+	metrics, err := anomaly.Measure(r.Checks, result.Columns, result.Rows)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	rec.Metrics = metrics
+
+	previous, err := r.previousMetrics()
+	if err != nil {
+		return err
+	}
+	var problems []string
+	for _, check := range r.Checks {
+		name := check.Name()
+		values := previous[name]
+		values = values[:min(len(values), check.History())]
+		problems = append(problems, check.Evaluate(metrics[name], values)...)
+	}
+	if len(problems) > 0 {
+		return &anomaly.Error{Problems: problems}
+	}
+	return nil
+}
+
+// previousMetrics returns the metrics of the report's successful runs,
+// newest first. Backfills cover other periods, so they are left out
+func (r ReportConfig) previousMetrics() (map[string][]float64, error) {
+	if r.history == nil {
+		return nil, nil
+	}
+	records, err := r.history.List(history.Filter{Report: r.Name, Status: history.StatusSucceeded})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read run history: %w", err)
+	}
+	previous := make(map[string][]float64)
+	for _, rec := range records {
+		if rec.Trigger == TriggerBackfill {
+			continue
+		}
+		for _, check := range r.Checks {
+			name := check.Name()
+			v, ok := rec.Metrics[name]
+			// Runs from before the check existed still know their row count
+			if !ok && name == anomaly.MetricRows {
+				v, ok = float64(rec.Rows), true
+			}
+			if ok {
+				previous[name] = append(previous[name], v)
+			}
+		}
+	}
+	return previous, nil
+}
+
+// quarantine writes a run that failed an anomaly check to the quarantine
+// directory, where it can be inspected, instead of publishing it
+func (r ReportConfig) quarantine(reportDir, name string, result *connection.Result, rec *history.Record, aerr *anomaly.Error) error {
+	dir := filepath.Join(reportDir, quarantineDirectory)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("%w: %w (error creating quarantine directory %s: %v)", ErrQuarantined, aerr, dir, err)
+	}
+	filename := filepath.Join(dir, name+"."+r.Output.Extension())
+	err = format.WriteFile(filename, r.Output, result.Columns, result.Rows)
+	if err != nil {
+		return fmt.Errorf("%w: %w (error writing quarantine file %s: %v)", ErrQuarantined, aerr, filename, err)
+	}
+	rec.Rows = len(result.Rows)
+	rec.Files = append(rec.Files, filename)
+	r.logger().Error("Report run quarantined", "filename", filename, "err", aerr)
+	return fmt.Errorf("%w: %w", ErrQuarantined, aerr)
+}
@@ -0,0 +1,134 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/config"
+	"github.com/lehigh-university-libraries/encode/pkg/history"
+)
+
+func TestRunReportOnce_Checks(t *testing.T) {
+	tests := []struct {
+		name             string
+		checks           string
+		previousRows     []int
+		expectStatus     string
+		expectPublish    bool
+		expectQuarantine bool
+	}{
+		{
+			name: "Passing checks publish",
+			checks: `
+      - min: 1
+        max: 10
+      - metric: distinct
+        column: name
+        min: 2`,
+			expectStatus:  history.StatusSucceeded,
+			expectPublish: true,
+		},
+		{
+			name: "Too few rows are quarantined",
+			checks: `
+      - min: 3`,
+			expectStatus:     history.StatusQuarantined,
+			expectQuarantine: true,
+		},
+		{
+			name: "Drop from the previous run is quarantined",
+			checks: `
+      - max_change: 50`,
+			previousRows:     []int{3000},
+			expectStatus:     history.StatusQuarantined,
+			expectQuarantine: true,
+		},
+		{
+			name: "Close to the rolling mean publishes",
+			checks: `
+      - max_deviation: 50
+        window: 3`,
+			previousRows:  []int{2, 3, 2, 3000},
+			expectStatus:  history.StatusSucceeded,
+			expectPublish: true,
+		},
+		{
+			name: "Summing a text column halts",
+			checks: `
+      - metric: sum
+        column: name
+        min: 1`,
+			expectStatus: history.StatusHalted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			staging := t.TempDir()
+			filename := createTempYAML(t, `
+stagingDirectory: `+staging+`
+
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: users
+    connection: mock
+    schedule: "0 12 * * *"
+    checks:`+tt.checks+`
+    sinks:
+      - type: Local
+        path: `+filepath.Join(staging, "out")+`
+`)
+			defer os.Remove(filename)
+
+			cfg, err := config.LoadConfig(filename)
+			if err != nil {
+				t.Fatalf("LoadConfig() failed: %v", err)
+			}
+			start := time.Now().Add(-time.Duration(len(tt.previousRows)+1) * time.Hour)
+			for i, rows := range tt.previousRows {
+				err = cfg.History().Append(history.Record{
+					ID:     history.NewID(start),
+					Report: "users",
+					// previousRows[0] is the most recent run
+					Start:   start.Add(time.Duration(len(tt.previousRows)-i) * time.Minute),
+					Trigger: config.TriggerCron,
+					Status:  history.StatusSucceeded,
+					Rows:    rows,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = cfg.RunReportOnce("users")
+			if err != nil {
+				t.Fatalf("RunReportOnce() failed: %v", err)
+			}
+
+			_, err = os.Stat(filepath.Join(staging, "out", "users"))
+			if published := err == nil; published != tt.expectPublish {
+				t.Errorf("Expected published %v, got %v", tt.expectPublish, published)
+			}
+			quarantined, _ := filepath.Glob(filepath.Join(staging, "users", "quarantine", "*.csv"))
+			if (len(quarantined) == 1) != tt.expectQuarantine {
+				t.Errorf("Expected quarantined %v, got %v", tt.expectQuarantine, quarantined)
+			}
+
+			records, err := cfg.History().List(history.Filter{Report: "users"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != len(tt.previousRows)+1 || records[0].Status != tt.expectStatus {
+				t.Fatalf("Expected the latest run to be %s, got %+v", tt.expectStatus, records)
+			}
+			if tt.expectStatus != history.StatusHalted && records[0].Metrics["rows"] != 2 {
+				t.Errorf("Expected the run's row count in its metrics, got %v", records[0].Metrics)
+			}
+		})
+	}
+}
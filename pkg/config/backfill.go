@@ -0,0 +1,142 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/state"
+)
+
+// Backfill steps
+const (
+	BackfillStepDay   = "day"
+	BackfillStepWeek  = "week"
+	BackfillStepMonth = "month"
+)
+
+// BackfillOptions describe a backfill of a report over a date range
+type BackfillOptions struct {
+	// From is in the first window
+	From time.Time
+	// To is in the last window
+	To time.Time
+	// Step is the window size: day, week or month
+	Step string
+	// Restart ignores the progress of an earlier, failed backfill of the same range
+	Restart bool
+}
+
+// BackfillWindow is one period a backfill runs the report for
+type BackfillWindow struct {
+	Start time.Time
+	End   time.Time
+	// Name is the period the report file is named for, e.g. 2024-01-31 or 2024-01
+	Name string
+}
+
+// BackfillWindows splits from..to into calendar days, weeks (starting on
+// Monday) or months, from the one containing from up to and including the
+// one containing to
+func BackfillWindows(from, to time.Time, step string) ([]BackfillWindow, error) {
+	var start, next func(time.Time) time.Time
+	layout := "2006-01-02"
+	day := func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()) }
+	switch step {
+	case BackfillStepDay:
+		start = day
+		next = func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	case BackfillStepWeek:
+		start = func(t time.Time) time.Time { return day(t).AddDate(0, 0, -((int(t.Weekday()) + 6) % 7)) }
+		next = func(t time.Time) time.Time { return t.AddDate(0, 0, 7) }
+	case BackfillStepMonth:
+		start = func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()) }
+		next = func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }
+		layout = "2006-01"
+	default:
+		return nil, fmt.Errorf("invalid step '%s': must be %s, %s or %s", step, BackfillStepDay, BackfillStepWeek, BackfillStepMonth)
+	}
+	if to.Before(from) {
+		return nil, errors.New("the end of the range is before its start")
+	}
+
+	var windows []BackfillWindow
+	for t := start(from); !t.After(to); t = next(t) {
+		windows = append(windows, BackfillWindow{Start: t, End: next(t), Name: t.Format(layout)})
+	}
+	return windows, nil
+}
+
+// Backfill runs a report once for each window in a date range, oldest first.
+// Each run's templates see the window as .PeriodStart and .PeriodEnd, with
+// .ScheduledTime at the end of the window. Progress is saved after every
+// window, so after a failure running the same backfill again resumes from
+// the window that failed
+func (c *Config) Backfill(reportName string, opts BackfillOptions) error {
+	report, err := c.Report(reportName)
+	if err != nil {
+		return err
+	}
+	if report.Incremental != nil {
+		return fmt.Errorf("report '%s' is incremental and extracts from its watermark, not a date range", reportName)
+	}
+
+	windows, err := BackfillWindows(opts.From, opts.To, opts.Step)
+	if err != nil {
+		return err
+	}
+
+	st, err := c.state.Load(reportName)
+	if err != nil {
+		return err
+	}
+	progress := &state.Backfill{From: opts.From, To: opts.To, Step: opts.Step}
+	if p := st.Backfill; p != nil && !opts.Restart {
+		if p.From.Equal(opts.From) && p.To.Equal(opts.To) && p.Step == opts.Step {
+			progress = p
+			slog.Info("Resuming backfill", "report", reportName, "completed", p.Completed)
+		} else {
+			slog.Warn("Replacing unfinished backfill of a different range", "report", reportName, "from", p.From, "to", p.To, "step", p.Step)
+		}
+	}
+
+	for i, w := range windows {
+		if !progress.Completed.IsZero() && !w.Start.After(progress.Completed) {
+			continue
+		}
+
+		slog.Info("Backfilling window", "report", reportName, "window", w.Name, "n", i+1, "of", len(windows))
+		err = report.Execute(RunOptions{
+			ScheduledTime: w.End,
+			PeriodStart:   w.Start,
+			PeriodEnd:     w.End,
+			Trigger:       TriggerBackfill,
+			FileName:      w.Name,
+		})
+		if errors.Is(err, ErrNoResults) {
+			slog.Warn("No results for backfill window", "report", reportName, "window", w.Name)
+		} else if err != nil {
+			return fmt.Errorf("backfill of '%s' stopped at %s, run it again to resume: %w", reportName, w.Name, err)
+		}
+
+		progress.Completed = w.Start
+		err = c.state.Update(reportName, func(st *state.ReportState) error {
+			st.Backfill = progress
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.state.Update(reportName, func(st *state.ReportState) error {
+		st.Backfill = nil
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	slog.Info("Backfill finished", "report", reportName, "windows", len(windows))
+	return nil
+}
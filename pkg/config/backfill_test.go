@@ -0,0 +1,156 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/config"
+	"github.com/lehigh-university-libraries/encode/pkg/history"
+	"github.com/lehigh-university-libraries/encode/pkg/state"
+)
+
+func TestBackfillWindows(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name        string
+		from, to    time.Time
+		step        string
+		expectNames []string
+		expectError bool
+	}{
+		{name: "Days", from: day(2024, 2, 28), to: day(2024, 3, 1), step: "day", expectNames: []string{"2024-02-28", "2024-02-29", "2024-03-01"}},
+		{name: "Weeks", from: day(2024, 1, 1), to: day(2024, 1, 20), step: "week", expectNames: []string{"2024-01-01", "2024-01-08", "2024-01-15"}},
+		{name: "Months", from: day(2023, 11, 1), to: day(2024, 1, 1), step: "month", expectNames: []string{"2023-11", "2023-12", "2024-01"}},
+		{name: "Months from the 31st", from: day(2024, 1, 31), to: day(2024, 3, 15), step: "month", expectNames: []string{"2024-01", "2024-02", "2024-03"}},
+		{name: "Weeks from midweek start on Monday", from: day(2024, 1, 3), to: day(2024, 1, 15), step: "week", expectNames: []string{"2024-01-01", "2024-01-08", "2024-01-15"}},
+		{name: "Days from midday", from: day(2024, 1, 1).Add(12 * time.Hour), to: day(2024, 1, 2), step: "day", expectNames: []string{"2024-01-01", "2024-01-02"}},
+		{name: "Unknown step", from: day(2024, 1, 1), to: day(2024, 1, 2), step: "year", expectError: true},
+		{name: "Reversed range", from: day(2024, 1, 2), to: day(2024, 1, 1), step: "day", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			windows, err := config.BackfillWindows(tt.from, tt.to, tt.step)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var names []string
+			for i, w := range windows {
+				names = append(names, w.Name)
+				if i > 0 && !w.Start.Equal(windows[i-1].End) {
+					t.Errorf("Window %s does not start where %s ends", w.Name, windows[i-1].Name)
+				}
+			}
+			if !reflect.DeepEqual(names, tt.expectNames) {
+				t.Errorf("Expected %v, got %v", tt.expectNames, names)
+			}
+		})
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(2024, 1, 3, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name        string
+		restart     bool
+		expectFiles []string
+	}{
+		{
+			name:        "Resumes after the last completed window",
+			expectFiles: []string{"2024-01-02.csv", "2024-01-03.csv"},
+		},
+		{
+			name:        "Restart runs every window",
+			restart:     true,
+			expectFiles: []string{"2024-01-01.csv", "2024-01-02.csv", "2024-01-03.csv"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			staging := t.TempDir()
+			filename := createTempYAML(t, `
+stagingDirectory: `+staging+`
+
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: users
+    connection: mock
+    schedule: "0 12 * * *"
+    query_params:
+      query: "SELECT id, name FROM users WHERE created >= '{{ isoDate .PeriodStart }}' AND created < '{{ isoDate .PeriodEnd }}'"
+    sinks:
+      - type: Local
+        path: `+filepath.Join(staging, "out")+`
+`)
+			defer os.Remove(filename)
+
+			// An earlier backfill of the same range failed after its first window
+			store := state.NewStore(filepath.Join(staging, ".state"))
+			err := store.Update("users", func(st *state.ReportState) error {
+				st.Backfill = &state.Backfill{From: from, To: to, Step: "day", Completed: from}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := config.LoadConfig(filename)
+			if err != nil {
+				t.Fatalf("LoadConfig() failed: %v", err)
+			}
+			err = cfg.Backfill("users", config.BackfillOptions{From: from, To: to, Step: "day", Restart: tt.restart})
+			if err != nil {
+				t.Fatalf("Backfill() failed: %v", err)
+			}
+
+			entries, err := os.ReadDir(filepath.Join(staging, "out", "users"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var files []string
+			for _, e := range entries {
+				files = append(files, e.Name())
+			}
+			if !reflect.DeepEqual(files, tt.expectFiles) {
+				t.Errorf("Expected files %v, got %v", tt.expectFiles, files)
+			}
+
+			st, err := store.Load("users")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if st.Backfill != nil || !st.LastSuccess.IsZero() {
+				t.Errorf("Expected backfill to clear its progress and leave last success alone, got %+v", st)
+			}
+
+			records, err := cfg.History().List(history.Filter{Report: "users"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != len(tt.expectFiles) {
+				t.Fatalf("Expected %d run records, got %d", len(tt.expectFiles), len(records))
+			}
+			for _, rec := range records {
+				if rec.Trigger != config.TriggerBackfill || rec.Status != history.StatusSucceeded || rec.Rows != 2 || rec.Bytes == 0 || len(rec.Files) != 2 {
+					t.Errorf("Unexpected run record %+v", rec)
+				}
+			}
+		})
+	}
+}
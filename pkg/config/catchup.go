@@ -0,0 +1,113 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/state"
+	cron "github.com/robfig/cron/v3"
+)
+
+// Catch-up policies for ticks missed while encode was not running
+const (
+	// CatchUpNone skips missed ticks
+	CatchUpNone = "none"
+	// CatchUpOnce runs the most recent missed tick
+	CatchUpOnce = "once"
+	// CatchUpAll runs every missed tick, oldest first
+	CatchUpAll = "all"
+)
+
+func validateCatchUp(policy string) error {
+	switch policy {
+	case "", CatchUpNone, CatchUpOnce, CatchUpAll:
+		return nil
+	}
+	return fmt.Errorf("invalid catch_up '%s': must be %s, %s or %s", policy, CatchUpNone, CatchUpOnce, CatchUpAll)
+}
+
+// MissedRuns returns the ticks of the report's schedule after its last run
+// and up to now that its catch_up policy says to run, oldest first. A report
+// that has never run or is paused has nothing to catch up
+func (r ReportConfig) MissedRuns(now time.Time) ([]time.Time, error) {
+	if r.CatchUp == "" || r.CatchUp == CatchUpNone || r.state == nil {
+		return nil, nil
+	}
+	st, err := r.state.Load(r.Name)
+	if err != nil {
+		return nil, err
+	}
+	if st.LastRun.IsZero() || !st.Paused.IsZero() {
+		return nil, nil
+	}
+
+	sched, err := cron.ParseStandard(r.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	var missed []time.Time
+	// The scheduler runs in local time
+	for t := sched.Next(st.LastRun.In(time.Local)); !t.After(now); t = sched.Next(t) {
+		missed = append(missed, t)
+	}
+	if r.CatchUp == CatchUpOnce && len(missed) > 1 {
+		missed = missed[len(missed)-1:]
+	}
+	return missed, nil
+}
+
+// CatchUp finds the ticks each report missed before now and runs them in
+// the background, following each report's catch_up policy. Each run gets the
+// logical time of the tick it missed. The missed ticks are found before
+// CatchUp returns, so the scheduler can be started right after it. The
+// returned channel is closed once every catch-up run has finished
+func (c *Config) CatchUp(now time.Time) <-chan struct{} {
+	type catchUp struct {
+		report ReportConfig
+		missed []time.Time
+	}
+	var runs []catchUp
+	for _, report := range c.Reports {
+		missed, err := report.MissedRuns(now)
+		if err != nil {
+			slog.Error("Unable to check for missed runs", "report", report.Name, "err", err)
+			continue
+		}
+		if len(missed) > 0 {
+			slog.Info("Catching up missed runs", "report", report.Name, "policy", report.CatchUp, "runs", len(missed))
+			runs = append(runs, catchUp{report: report, missed: missed})
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, run := range runs {
+			for _, t := range run.missed {
+				// Named for the missed tick, so several catch-up runs don't share a file
+				err := run.report.Execute(RunOptions{ScheduledTime: t, Trigger: TriggerCatchUp, FileName: t.Format("2006-01-02.15.04.05")})
+				if errors.Is(err, ErrRunning) {
+					slog.Warn("Report already running, skipping catch-up run", "report", run.report.Name, "scheduled", t, "err", err)
+				} else if err != nil {
+					slog.Error("Catch-up run failed", "report", run.report.Name, "scheduled", t, "err", err)
+				}
+			}
+		}
+	}()
+	return done
+}
+
+// recordRun saves the scheduled time of a cron tick the report ran for
+func (r ReportConfig) recordRun(scheduled time.Time) error {
+	if r.state == nil {
+		return nil
+	}
+	return r.state.Update(r.Name, func(st *state.ReportState) error {
+		if scheduled.After(st.LastRun) {
+			st.LastRun = scheduled
+		}
+		return nil
+	})
+}
@@ -0,0 +1,127 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/config"
+	"github.com/lehigh-university-libraries/encode/pkg/state"
+)
+
+func TestCatchUp(t *testing.T) {
+	noon := func(day int) time.Time { return time.Date(2024, 1, day, 12, 0, 0, 0, time.Local) }
+	now := time.Date(2024, 1, 4, 13, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name          string
+		policy        string
+		lastRun       time.Time
+		expectMissed  []time.Time
+		expectFiles   []string
+		expectLastRun time.Time
+	}{
+		{
+			name:          "None",
+			policy:        "none",
+			lastRun:       noon(1),
+			expectLastRun: noon(1),
+		},
+		{
+			name:          "Once runs the latest missed tick",
+			policy:        "once",
+			lastRun:       noon(1),
+			expectMissed:  []time.Time{noon(4)},
+			expectFiles:   []string{"2024-01-04.12.00.00.csv"},
+			expectLastRun: noon(4),
+		},
+		{
+			name:          "All runs every missed tick",
+			policy:        "all",
+			lastRun:       noon(1),
+			expectMissed:  []time.Time{noon(2), noon(3), noon(4)},
+			expectFiles:   []string{"2024-01-02.12.00.00.csv", "2024-01-03.12.00.00.csv", "2024-01-04.12.00.00.csv"},
+			expectLastRun: noon(4),
+		},
+		{
+			name:   "Never run before",
+			policy: "all",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			staging := t.TempDir()
+			filename := createTempYAML(t, `
+stagingDirectory: `+staging+`
+
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: users
+    connection: mock
+    schedule: "0 12 * * *"
+    catch_up: `+tt.policy+`
+    sinks:
+      - type: Local
+        path: `+filepath.Join(staging, "out")+`
+`)
+			defer os.Remove(filename)
+
+			store := state.NewStore(filepath.Join(staging, ".state"))
+			if !tt.lastRun.IsZero() {
+				err := store.Update("users", func(st *state.ReportState) error {
+					st.LastRun = tt.lastRun
+					return nil
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cfg, err := config.LoadConfig(filename)
+			if err != nil {
+				t.Fatalf("LoadConfig() failed: %v", err)
+			}
+			report, err := cfg.Report("users")
+			if err != nil {
+				t.Fatal(err)
+			}
+			missed, err := report.MissedRuns(now)
+			if err != nil {
+				t.Fatalf("MissedRuns() failed: %v", err)
+			}
+			if len(missed) != len(tt.expectMissed) {
+				t.Fatalf("Expected missed runs %v, got %v", tt.expectMissed, missed)
+			}
+			for i := range missed {
+				if !missed[i].Equal(tt.expectMissed[i]) {
+					t.Errorf("Expected missed runs %v, got %v", tt.expectMissed, missed)
+				}
+			}
+
+			<-cfg.CatchUp(now)
+
+			var files []string
+			entries, _ := os.ReadDir(filepath.Join(staging, "out", "users"))
+			for _, e := range entries {
+				files = append(files, e.Name())
+			}
+			if !reflect.DeepEqual(files, tt.expectFiles) {
+				t.Errorf("Expected files %v, got %v", tt.expectFiles, files)
+			}
+
+			st, err := store.Load("users")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !st.LastRun.Equal(tt.expectLastRun) {
+				t.Errorf("Expected last run %v, got %v", tt.expectLastRun, st.LastRun)
+			}
+		})
+	}
+}
@@ -4,8 +4,20 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"text/template"
+	"time"
 
+	"github.com/lehigh-university-libraries/encode/pkg/anomaly"
 	"github.com/lehigh-university-libraries/encode/pkg/connection"
+	"github.com/lehigh-university-libraries/encode/pkg/consolidate"
+	"github.com/lehigh-university-libraries/encode/pkg/format"
+	"github.com/lehigh-university-libraries/encode/pkg/history"
+	"github.com/lehigh-university-libraries/encode/pkg/notify"
+	"github.com/lehigh-university-libraries/encode/pkg/render"
+	"github.com/lehigh-university-libraries/encode/pkg/schema"
+	"github.com/lehigh-university-libraries/encode/pkg/sink"
+	"github.com/lehigh-university-libraries/encode/pkg/state"
 	"github.com/lehigh-university-libraries/encode/pkg/storage"
 	cron "github.com/robfig/cron/v3"
 	yaml "gopkg.in/yaml.v3"
@@ -15,19 +27,66 @@ type Config struct {
 	Connections      []map[string]any `yaml:"connections"`
 	Reports          []ReportConfig   `yaml:"reports"`
 	StagingDirectory string           `yaml:"stagingDirectory"`
-	S3               storage.S3Config `yaml:"s3"`
-	s3Uploader       *storage.S3Uploader
+	// StateDirectory holds per-report state such as watermarks, and the run history (default: {stagingDirectory}/.state)
+	StateDirectory string `yaml:"stateDirectory"`
+	// AcademicCalendar sets the terms used by the query template term helpers
+	AcademicCalendar render.Calendar `yaml:"academicCalendar"`
+	// Owners and Notify are the defaults for reports that do not set their own
+	Owners []string       `yaml:"owners"`
+	Notify []NotifyConfig `yaml:"notify"`
+	// NotifyRepeat is how long a report that keeps failing waits before notifying again (default: 24h, 0 never repeats)
+	NotifyRepeat string           `yaml:"notifyRepeat"`
+	S3           storage.S3Config `yaml:"s3"`
+	s3Uploader   *storage.S3Uploader
+	s3Clients    *storage.ClientCache
+	state        *state.Store
+	history      *history.Store
+	runs         *runTracker
 }
 
 type ReportConfig struct {
-	Name             string            `yaml:"name"`
-	Connection       string            `yaml:"connection"`
-	QueryParams      map[string]string `yaml:"query_params"`
-	TemplatePath     string            `yaml:"template"`
-	Schedule         string            `yaml:"schedule"`
+	Name        string            `yaml:"name"`
+	Connection  string            `yaml:"connection"`
+	QueryParams map[string]string `yaml:"query_params"`
+	// TemplatePath is a query template file, relative to the config file, used as query_params.query
+	TemplatePath string `yaml:"template"`
+	// Params are bind parameters for SQL connections
+	Params   []ParamConfig `yaml:"params"`
+	Schedule string        `yaml:"schedule"`
+	// CatchUp is what to do about ticks missed while encode was down: none (default), once or all
+	CatchUp string `yaml:"catch_up"`
+	// EmptyResult is what a run that returns no rows does: fail, skip or header
+	EmptyResult string       `yaml:"empty_result"`
+	Sinks       []SinkConfig `yaml:"sinks"`
+	// Output is the format the report file is written in
+	Output format.Options `yaml:"output"`
+	// Consolidate merges each run into one file by key instead of writing a new file per run
+	Consolidate *consolidate.Options `yaml:"consolidate"`
+	// Incremental extracts only what changed since the last successful run
+	Incremental *IncrementalConfig `yaml:"incremental"`
+	// Schema is a contract every run's results are checked against before they are published
+	Schema *schema.Contract `yaml:"schema"`
+	// SchemaDrift is what a change from the last successful run's schema does: block, warn (default) or accept
+	SchemaDrift string `yaml:"schema_drift"`
+	// Checks compare the row count and column metrics to bounds and earlier runs; a failing run is quarantined
+	Checks []anomaly.Check `yaml:"checks"`
+	// Owners are told about failures; email channels mail them
+	Owners []string `yaml:"owners"`
+	// Notify lists the channels told about failures, halts and recoveries. Unset uses the global notify list; [] turns notifications off
+	Notify []NotifyConfig `yaml:"notify"`
+	// S3 overrides the global s3 block for this report; unset fields are inherited
+	S3               *storage.S3Override `yaml:"s3"`
 	StagingDirectory string
 	connection       connection.ConnectionProvider
+	sinks            []sink.Sink
+	notifiers        []notify.Notifier
+	notifyRepeat     time.Duration
 	s3Uploader       *storage.S3Uploader
+	state            *state.Store
+	history          *history.Store
+	runs             *runTracker
+	log              *slog.Logger
+	templates        map[string]*template.Template
 }
 
 func LoadConfig(filename string) (*Config, error) {
@@ -42,10 +101,21 @@ func LoadConfig(filename string) (*Config, error) {
 
 	var config Config
 	err = yaml.Unmarshal([]byte(expandedYaml), &config)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.StateDirectory == "" {
+		config.StateDirectory = filepath.Join(config.StagingDirectory, ".state")
+	}
+	config.state = state.NewStore(config.StateDirectory)
+	config.history = history.NewStore(filepath.Join(config.StateDirectory, "history"))
+	config.runs = newRunTracker()
 
 	// Initialize S3 uploader if enabled
+	config.s3Clients = storage.NewClientCache()
 	if config.S3.Enabled {
-		config.s3Uploader, err = storage.NewS3Uploader(config.S3)
+		config.s3Uploader, err = config.s3Clients.Uploader(config.S3)
 		if err != nil {
 			slog.Error("Failed to initialize S3 uploader", "err", err)
 			return nil, fmt.Errorf("failed to initialize S3 uploader: %w", err)
@@ -53,6 +123,15 @@ func LoadConfig(filename string) (*Config, error) {
 		slog.Info("S3 uploader initialized", "bucket", config.S3.Bucket, "region", config.S3.Region)
 	}
 
+	err = config.AcademicCalendar.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid academicCalendar: %w", err)
+	}
+	notifyRepeat, err := parseNotifyRepeat(config.NotifyRepeat)
+	if err != nil {
+		return nil, err
+	}
+
 	// Validate cron expressions
 	for k, report := range config.Reports {
 		slog.Debug("Ensuring cron entry is valid", "schedule", report.Schedule)
@@ -63,12 +142,50 @@ func LoadConfig(filename string) (*Config, error) {
 		if err != nil {
 			return nil, fmt.Errorf("invalid cron schedule '%s' in report '%s': %v", report.Schedule, report.Name, err)
 		}
+		err = validateCatchUp(report.CatchUp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid report '%s': %w", report.Name, err)
+		}
+		err = validateEmptyResult(report.EmptyResult)
+		if err != nil {
+			return nil, fmt.Errorf("invalid report '%s': %w", report.Name, err)
+		}
+		err = report.Output.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("invalid output in report '%s': %w", report.Name, err)
+		}
+		err = validateConsolidate(report)
+		if err != nil {
+			return nil, fmt.Errorf("invalid consolidate in report '%s': %w", report.Name, err)
+		}
+		if report.Schema != nil {
+			err = report.Schema.Validate()
+			if err != nil {
+				return nil, fmt.Errorf("invalid schema in report '%s': %w", report.Name, err)
+			}
+		}
+		err = validateSchemaDrift(report.SchemaDrift)
+		if err != nil {
+			return nil, fmt.Errorf("invalid report '%s': %w", report.Name, err)
+		}
+		for i, check := range report.Checks {
+			err = check.Validate()
+			if err != nil {
+				return nil, fmt.Errorf("invalid check %d in report '%s': %w", i+1, report.Name, err)
+			}
+		}
+		if report.Incremental != nil {
+			err = report.Incremental.validate()
+			if err != nil {
+				return nil, fmt.Errorf("invalid incremental in report '%s': %w", report.Name, err)
+			}
+		}
 		var c connection.ConnectionProvider
 		for _, conn := range config.Connections {
 			if conn["name"].(string) == report.Connection {
 				c, err = InitializeConnection(conn)
 				if err != nil {
-					slog.Error("Unable to fetch connection details", "conn", conn, "report", report.Name, "err", err)
+					slog.Error("Unable to fetch connection details", "connection", report.Connection, "report", report.Name, "err", err)
 					c = nil
 				}
 			}
@@ -76,22 +193,137 @@ func LoadConfig(filename string) (*Config, error) {
 		if c == nil {
 			return nil, fmt.Errorf("invalid connection reference '%s' in report '%s'", report.Connection, report.Name)
 		}
+		report.connection = c
+		err = report.parseTemplates(filepath.Dir(filename), config.AcademicCalendar)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query template in report '%s': %w", report.Name, err)
+		}
+		err = report.parseParams(config.AcademicCalendar)
+		if err != nil {
+			return nil, fmt.Errorf("invalid params in report '%s': %w", report.Name, err)
+		}
+		report.s3Uploader = config.s3Uploader
+		if report.S3 != nil {
+			s3Config := config.S3.Merge(*report.S3)
+			report.s3Uploader = nil
+			if s3Config.Enabled {
+				report.s3Uploader, err = config.s3Clients.Uploader(s3Config)
+				if err != nil {
+					return nil, fmt.Errorf("failed to initialize S3 uploader for report '%s': %w", report.Name, err)
+				}
+				slog.Info("S3 uploader initialized", "report", report.Name, "bucket", s3Config.Bucket, "region", s3Config.Region, "prefix", s3Config.Prefix)
+			}
+		}
+		sinks, err := InitializeSinks(report, &config)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sinks in report '%s': %w", report.Name, err)
+		}
+		if report.Owners == nil {
+			report.Owners = config.Owners
+		}
+		if report.Notify == nil {
+			report.Notify = config.Notify
+		}
+		notifiers, err := InitializeNotifiers(report.Notify)
+		if err != nil {
+			return nil, fmt.Errorf("invalid notify in report '%s': %w", report.Name, err)
+		}
 		config.Reports[k].StagingDirectory = config.StagingDirectory
 		config.Reports[k].connection = c
-		config.Reports[k].s3Uploader = config.s3Uploader
+		config.Reports[k].sinks = sinks
+		config.Reports[k].Owners = report.Owners
+		config.Reports[k].notifiers = notifiers
+		config.Reports[k].notifyRepeat = notifyRepeat
+		config.Reports[k].state = config.state
+		config.Reports[k].history = config.history
+		config.Reports[k].runs = config.runs
+		config.Reports[k].QueryParams = report.QueryParams
+		config.Reports[k].templates = report.templates
+		config.Reports[k].Params = report.Params
+		config.Reports[k].s3Uploader = report.s3Uploader
 	}
 
 	return &config, err
 }
 
+// LogValue summarizes the config for logging. Connection and notification
+// settings hold passwords and tokens, so only their names and types are logged
+func (c *Config) LogValue() slog.Value {
+	connections := make([]string, len(c.Connections))
+	for i, conn := range c.Connections {
+		connections[i] = fmt.Sprintf("%v (%v)", conn["name"], conn["type"])
+	}
+	reports := make([]string, len(c.Reports))
+	for i, report := range c.Reports {
+		reports[i] = report.Name
+	}
+	channels := make([]string, len(c.Notify))
+	for i, nc := range c.Notify {
+		channels[i] = nc.Type
+		if nc.Name != "" {
+			channels[i] = fmt.Sprintf("%s (%s)", nc.Name, nc.Type)
+		}
+	}
+	return slog.GroupValue(
+		slog.String("stagingDirectory", c.StagingDirectory),
+		slog.String("stateDirectory", c.StateDirectory),
+		slog.Any("connections", connections),
+		slog.Any("reports", reports),
+		slog.Any("notify", channels),
+	)
+}
+
 // RunReportOnce executes a single report by name and returns immediately
 func (c *Config) RunReportOnce(reportName string) error {
 	for _, report := range c.Reports {
 		if report.Name == reportName {
 			slog.Info("Running report once", "report", reportName)
-			report.Run()
+			err := report.Execute(RunOptions{Trigger: TriggerManual})
+			if err != nil {
+				slog.Error("Report run failed", "report", reportName, "err", err)
+			}
 			return nil
 		}
 	}
 	return fmt.Errorf("report '%s' not found in configuration", reportName)
 }
+
+// Report returns the report with the given name
+func (c *Config) Report(reportName string) (*ReportConfig, error) {
+	for k := range c.Reports {
+		if c.Reports[k].Name == reportName {
+			return &c.Reports[k], nil
+		}
+	}
+	return nil, fmt.Errorf("report '%s' not found in configuration", reportName)
+}
+
+// History is the store every report run is recorded in
+func (c *Config) History() *history.Store {
+	return c.history
+}
+
+// ManifestUploader returns the S3 uploader that maintains a report's
+// manifest: that of its first S3 sink, or else the report's S3 settings
+func (c *Config) ManifestUploader(reportName string) (*storage.S3Uploader, error) {
+	report, err := c.Report(reportName)
+	if err != nil {
+		return nil, err
+	}
+	uploader := report.uploader()
+	if uploader == nil {
+		return nil, fmt.Errorf("S3 is not enabled for report '%s'", reportName)
+	}
+	return uploader, nil
+}
+
+// uploader is the S3 uploader the report's files go through: that of its
+// first S3 sink, or else the report's S3 settings. It is nil without S3
+func (r ReportConfig) uploader() *storage.S3Uploader {
+	for _, s := range r.sinks {
+		if s3, ok := sink.Unwrap(s).(*sink.S3); ok {
+			return s3.Uploader()
+		}
+	}
+	return r.s3Uploader
+}
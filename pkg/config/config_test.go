@@ -1,7 +1,10 @@
 package config_test
 
 import (
+	"bytes"
+	"log/slog"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/lehigh-university-libraries/encode/pkg/config"
@@ -50,7 +53,6 @@ reports:
       spreadsheet_id: "spreadsheet-id"
       range: "Sheet1!A1:C3"
     schedule: "0 0 1 * *"
-    template: "templates/sales.tmpl"
 `,
 			expectError: false,
 			validateFunc: func(t *testing.T, cfg *config.Config) {
@@ -97,7 +99,6 @@ reports:
       spreadsheet_id: "${SPREADSHEET_ID}"
       range: "Sheet1!A1:C3"
     schedule: "0 12 * * *"
-    template: "templates/env_report.tmpl"
 `,
 			expectError: false,
 			validateFunc: func(t *testing.T, cfg *config.Config) {
@@ -121,6 +122,395 @@ reports:
 `, // Syntax error: missing closing quote
 			expectError: true,
 		},
+		{
+			name: "Multiple Sinks",
+			yamlContent: `
+connections:
+  - name: google_sheets
+    type: GoogleSheets
+    credentials_file: "path/to/google-service-account.json"
+  - name: mock
+    type: Mock
+
+reports:
+  - name: Gate Counts
+    connection: mock
+    schedule: "0 12 * * *"
+    sinks:
+      - type: Local
+        path: /mnt/dlshare/reports
+      - type: GoogleSheets
+        name: sheet
+        connection: google_sheets
+        spreadsheet_id: "spreadsheet-id"
+        tab: "Counts"
+        export: true
+        halt_on_failure: true
+`,
+			expectError: false,
+			validateFunc: func(t *testing.T, cfg *config.Config) {
+				sinks := cfg.Reports[0].Sinks
+				if len(sinks) != 2 {
+					t.Fatalf("Expected 2 sinks, got %d", len(sinks))
+				}
+				if sinks[0].Settings["path"] != "/mnt/dlshare/reports" {
+					t.Errorf("Expected local sink path to be parsed, got %v", sinks[0].Settings)
+				}
+				if sinks[1].Name != "sheet" || !sinks[1].HaltOnFailure {
+					t.Errorf("Expected sheet sink to halt on failure, got %+v", sinks[1])
+				}
+			},
+		},
+		{
+			name: "Google Sheets Sink With Wrong Connection Type",
+			yamlContent: `
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: Gate Counts
+    connection: mock
+    schedule: "0 12 * * *"
+    sinks:
+      - type: GoogleSheets
+        connection: mock
+        spreadsheet_id: "spreadsheet-id"
+        tab: "Counts"
+`,
+			expectError: true,
+		},
+		{
+			name: "Unknown Sink Type",
+			yamlContent: `
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: Gate Counts
+    connection: mock
+    schedule: "0 12 * * *"
+    sinks:
+      - type: Carrier Pigeon
+`,
+			expectError: true,
+		},
+		{
+			name: "Per-report S3 Override",
+			yamlContent: `
+connections:
+  - name: mock
+    type: Mock
+
+s3:
+  enabled: false
+  region: us-east-1
+  prefix: encode
+
+reports:
+  - name: Special Collections Visits
+    connection: mock
+    schedule: "0 12 * * *"
+    s3:
+      bucket: special-collections-reports
+      prefix: sc
+      storage_class: STANDARD_IA
+`,
+			expectError: false,
+			validateFunc: func(t *testing.T, cfg *config.Config) {
+				override := cfg.Reports[0].S3
+				if override == nil || override.Bucket != "special-collections-reports" || override.StorageClass != "STANDARD_IA" {
+					t.Errorf("Expected report s3 block to be parsed, got %+v", override)
+				}
+			},
+		},
+		{
+			name: "Per-report S3 Opt Out",
+			yamlContent: `
+connections:
+  - name: mock
+    type: Mock
+
+s3:
+  enabled: true
+  bucket: library-reports
+  region: us-east-1
+
+reports:
+  - name: Gate Counts
+    connection: mock
+    schedule: "0 12 * * *"
+    s3:
+      enabled: false
+`,
+			expectError: false,
+			validateFunc: func(t *testing.T, cfg *config.Config) {
+				u, err := cfg.ManifestUploader("Gate Counts")
+				if err == nil {
+					t.Errorf("Expected S3 to be disabled for the report, got bucket %q", u.Config().Bucket)
+				}
+			},
+		},
+		{
+			name: "Per-report S3 Path Style Off",
+			yamlContent: `
+connections:
+  - name: mock
+    type: Mock
+
+s3:
+  enabled: true
+  bucket: library-reports
+  region: us-east-1
+  endpoint: https://minio.lib.lehigh.edu
+  use_path_style: true
+
+reports:
+  - name: Gate Counts
+    connection: mock
+    schedule: "0 12 * * *"
+    s3:
+      endpoint: https://s3.us-east-1.amazonaws.com
+      use_path_style: false
+`,
+			expectError: false,
+			validateFunc: func(t *testing.T, cfg *config.Config) {
+				u, err := cfg.ManifestUploader("Gate Counts")
+				if err != nil || u.Config().UsePathStyle {
+					t.Errorf("Expected the report to turn off path-style addressing, got %v %v", u, err)
+				}
+			},
+		},
+		{
+			name: "Manifest From S3 Sink",
+			yamlContent: `
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: Special Collections Visits
+    connection: mock
+    schedule: "0 12 * * *"
+    sinks:
+      - type: Local
+        path: /tmp/encode
+      - type: S3
+        bucket: special-collections-reports
+        region: us-east-1
+        halt_on_failure: true
+`,
+			expectError: false,
+			validateFunc: func(t *testing.T, cfg *config.Config) {
+				u, err := cfg.ManifestUploader("Special Collections Visits")
+				if err != nil || u.Config().Bucket != "special-collections-reports" {
+					t.Errorf("Expected the S3 sink's uploader, got %v %v", u, err)
+				}
+			},
+		},
+		{
+			name: "Consolidate Without Key",
+			yamlContent: `
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: Gate Counts
+    connection: mock
+    schedule: "0 12 * * *"
+    consolidate:
+      delete_missing: true
+`,
+			expectError: true,
+		},
+		{
+			name: "Consolidate Delete Missing With Incremental",
+			yamlContent: `
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: Gate Counts
+    connection: mock
+    schedule: "0 12 * * *"
+    consolidate:
+      key: [id]
+      delete_missing: true
+    incremental:
+      watermark: run_time
+`,
+			expectError: true,
+		},
+		{
+			name: "Consolidate Into Database Without Truncate",
+			yamlContent: `
+connections:
+  - name: mock
+    type: Mock
+  - name: postgres_db
+    type: PostgreSQL
+    dsn: postgres://localhost/reporting
+
+reports:
+  - name: Gate Counts
+    connection: mock
+    schedule: "0 12 * * *"
+    consolidate:
+      key: [id]
+    sinks:
+      - type: Database
+        connection: postgres_db
+        table: reporting.gate_counts
+`,
+			expectError: true,
+		},
+		{
+			name: "Consolidate Into Truncated Database",
+			yamlContent: `
+connections:
+  - name: mock
+    type: Mock
+  - name: postgres_db
+    type: PostgreSQL
+    dsn: postgres://localhost/reporting
+
+reports:
+  - name: Gate Counts
+    connection: mock
+    schedule: "0 12 * * *"
+    consolidate:
+      key: [id]
+    sinks:
+      - type: Database
+        connection: postgres_db
+        table: reporting.gate_counts
+        truncate: true
+`,
+			expectError: false,
+		},
+		{
+			name: "Unknown Output Format",
+			yamlContent: `
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: Gate Counts
+    connection: mock
+    schedule: "0 12 * * *"
+    output:
+      format: xlsx
+`,
+			expectError: true,
+		},
+		{
+			name: "Incremental Max Without Column",
+			yamlContent: `
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: Gate Counts
+    connection: mock
+    schedule: "0 12 * * *"
+    incremental:
+      watermark: max
+`,
+			expectError: true,
+		},
+		{
+			name: "Unknown Catch Up Policy",
+			yamlContent: `
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: Gate Counts
+    connection: mock
+    schedule: "0 12 * * *"
+    catch_up: always
+`,
+			expectError: true,
+		},
+		{
+			name: "Unknown Schema Drift Policy",
+			yamlContent: `
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: Gate Counts
+    connection: mock
+    schedule: "0 12 * * *"
+    schema_drift: ignore
+`,
+			expectError: true,
+		},
+		{
+			name: "Check Without A Rule",
+			yamlContent: `
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: Gate Counts
+    connection: mock
+    schedule: "0 12 * * *"
+    checks:
+      - metric: sum
+        column: count
+`,
+			expectError: true,
+		},
+		{
+			name: "Unknown Empty Result Policy",
+			yamlContent: `
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: Gate Counts
+    connection: mock
+    schedule: "0 12 * * *"
+    empty_result: ignore
+`,
+			expectError: true,
+		},
+		{
+			name: "Unknown Notify Event",
+			yamlContent: `
+connections:
+  - name: mock
+    type: Mock
+
+notify:
+  - type: Slack
+    url: https://hooks.slack.com/services/T000/B000/XXX
+    events: [failure, success]
+
+reports:
+  - name: Gate Counts
+    connection: mock
+    schedule: "0 12 * * *"
+`,
+			expectError: true,
+		},
+		{
+			name: "Invalid Notify Repeat",
+			yamlContent: `
+notifyRepeat: daily
+`,
+			expectError: true,
+		},
 		{
 			name:        "Empty YAML File",
 			yamlContent: "",
@@ -169,3 +559,48 @@ reports:
 		})
 	}
 }
+
+func TestConfig_LogValue(t *testing.T) {
+	filename := createTempYAML(t, `
+stagingDirectory: `+t.TempDir()+`
+
+connections:
+  - name: folio
+    type: FOLIO
+    base_url: https://folio.example.edu
+    tenant: diku
+    username: encode
+    password: folio-secret
+
+notify:
+  - type: Email
+    host: smtp.example.edu
+    from: encode@example.edu
+    password: smtp-secret
+  - type: Webhook
+    url: https://hooks.example.edu
+    headers:
+      Authorization: "Bearer hook-secret"
+
+reports:
+  - name: loans
+    connection: folio
+    schedule: "0 12 * * *"
+`)
+	defer os.Remove(filename)
+
+	cfg, err := config.LoadConfig(filename)
+	if err != nil {
+		t.Fatalf("LoadConfig() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("Got config", "config", cfg)
+	if strings.Contains(buf.String(), "secret") {
+		t.Errorf("Expected no credentials in the log, got %s", buf.String())
+	}
+	for _, want := range []string{"folio (FOLIO)", "loans", "Webhook"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected the log to contain %q, got %s", want, buf.String())
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 
 	"github.com/lehigh-university-libraries/encode/pkg/connection"
+	"github.com/lehigh-university-libraries/encode/pkg/render"
 )
 
 func InitializeConnections(config *Config) map[string]connection.ConnectionProvider {
@@ -58,3 +59,11 @@ func InitializeConnection(connData map[string]any) (connection.ConnectionProvide
 		return nil, fmt.Errorf("unknown connection type: %s", connType)
 	}
 }
+
+// sqlDialect is the dialect a connection's query templates quote literals for
+func sqlDialect(c connection.ConnectionProvider) render.Dialect {
+	if _, ok := c.(*connection.MariaDBAuth); ok {
+		return render.DialectMySQL
+	}
+	return render.DialectStandard
+}
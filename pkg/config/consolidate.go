@@ -0,0 +1,75 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/lehigh-university-libraries/encode/pkg/connection"
+	"github.com/lehigh-university-libraries/encode/pkg/consolidate"
+	"github.com/lehigh-university-libraries/encode/pkg/format"
+)
+
+// validateConsolidate checks a report's consolidate block against its output
+func validateConsolidate(report ReportConfig) error {
+	if report.Consolidate == nil {
+		return nil
+	}
+	if report.Output.NoHeader {
+		return errors.New("consolidate requires a header row to read the consolidated file back")
+	}
+	if report.Consolidate.DeleteMissing && report.Incremental != nil {
+		// An incremental run only returns what changed, so every other row would be deleted
+		return errors.New("delete_missing can't be used with incremental extraction")
+	}
+	return report.Consolidate.Validate()
+}
+
+// consolidate merges result into the report's consolidated file,
+// {report_name}.{ext} in the report's staging directory, and returns the
+// file's path with the merged dataset. When the file is missing locally it is
+// first downloaded from where the report uploads it, so the S3 object is never
+// replaced by a master that lost its history
+func (r ReportConfig) consolidate(reportDir string, result *connection.Result) (string, *connection.Result, error) {
+	filename := filepath.Join(reportDir, r.Name+"."+r.Output.Extension())
+
+	_, err := os.Stat(filename)
+	if uploader := r.uploader(); errors.Is(err, os.ErrNotExist) && uploader != nil {
+		_, err = uploader.WithLogger(r.log).DownloadReportFile(r.Name, filepath.Base(filename), filename)
+		if err != nil {
+			os.Remove(filename)
+			return "", nil, err
+		}
+	}
+
+	var masterColumns []string
+	var master []map[string]string
+	_, err = os.Stat(filename)
+	if err == nil {
+		masterColumns, master, err = format.ReadFile(filename, r.Output, result.Columns)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read consolidated file %s: %w", filename, err)
+		}
+	}
+
+	columns, rows, stats, err := consolidate.Merge(*r.Consolidate, masterColumns, master, result.Columns, result.Rows)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Replace the file in one step so a failed write keeps the previous dataset
+	tmp := filename + ".tmp"
+	err = format.WriteFile(tmp, r.Output, columns, rows)
+	if err != nil {
+		os.Remove(tmp)
+		return "", nil, err
+	}
+	err = os.Rename(tmp, filename)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to replace consolidated file: %w", err)
+	}
+
+	r.logger().Info("Consolidated report", "file", filename, "rows", len(rows), "inserted", stats.Inserted, "updated", stats.Updated, "deleted", stats.Deleted, "unchanged", stats.Unchanged)
+	return filename, &connection.Result{Columns: columns, Rows: rows}, nil
+}
@@ -0,0 +1,172 @@
+package config_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lehigh-university-libraries/encode/pkg/config"
+	"github.com/lehigh-university-libraries/encode/pkg/format"
+	"github.com/lehigh-university-libraries/encode/pkg/history"
+)
+
+func TestRunReportOnce_Consolidate(t *testing.T) {
+	staging := t.TempDir()
+	filename := createTempYAML(t, `
+stagingDirectory: `+staging+`
+
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: users
+    connection: mock
+    schedule: "0 12 * * *"
+    consolidate:
+      key: [id]
+    sinks:
+      - type: Local
+        path: `+filepath.Join(staging, "out")+`
+`)
+	defer os.Remove(filename)
+
+	// An earlier run left a row the mock no longer returns and a stale name
+	reportDir := filepath.Join(staging, "users")
+	err := os.MkdirAll(reportDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = format.WriteFile(filepath.Join(reportDir, "users.csv"), format.Options{}, []string{"id", "name"}, []map[string]string{
+		{"id": "0", "name": "Old User"},
+		{"id": "1", "name": "Renamed"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.LoadConfig(filename)
+	if err != nil {
+		t.Fatalf("LoadConfig() failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		err = cfg.RunReportOnce("users")
+		if err != nil {
+			t.Fatalf("RunReportOnce() failed: %v", err)
+		}
+	}
+
+	runs, err := cfg.History().List(history.Filter{Report: "users", Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].Rows != 2 || runs[0].TotalRows != 3 {
+		t.Errorf("Expected a run of 2 rows extracted into 3, got %+v", runs)
+	}
+
+	expected := "id,name\n0,Old User\n1,Test User 1\n2,Test User 2\n"
+	for _, f := range []string{filepath.Join(reportDir, "users.csv"), filepath.Join(staging, "out", "users", "users.csv")} {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatalf("Expected consolidated file %s: %v", f, err)
+		}
+		if string(data) != expected {
+			t.Errorf("Expected %s to contain:\n%s\nGot:\n%s", f, expected, data)
+		}
+	}
+}
+
+// fakeS3 serves GET and PUT of whole objects for path-style requests
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, string) {
+	t.Helper()
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_CONFIG_FILE", "/dev/null")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/dev/null")
+
+	f := &fakeS3{objects: make(map[string][]byte)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		id := strings.TrimPrefix(r.URL.Path, "/")
+		switch r.Method {
+		case http.MethodPut:
+			f.objects[id], _ = io.ReadAll(r.Body)
+		case http.MethodGet, http.MethodHead:
+			data, ok := f.objects[id]
+			if !ok {
+				w.Header().Set("Content-Type", "application/xml")
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, "<Error><Code>NoSuchKey</Code><Message>NoSuchKey</Message></Error>")
+				return
+			}
+			_, _ = w.Write(data)
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return f, server.URL
+}
+
+func TestRunReportOnce_ConsolidateFromS3Sink(t *testing.T) {
+	fake, endpoint := newFakeS3(t)
+	// The master is only in the sink's bucket; the global s3 block is off
+	fake.objects["sc-reports/sc/users/users.csv"] = []byte("id,name\n0,Old User\n")
+
+	staging := t.TempDir()
+	filename := createTempYAML(t, `
+stagingDirectory: `+staging+`
+
+connections:
+  - name: mock
+    type: Mock
+
+s3:
+  enabled: false
+  bucket: library-reports
+  region: us-east-1
+
+reports:
+  - name: users
+    connection: mock
+    schedule: "0 12 * * *"
+    consolidate:
+      key: [id]
+    sinks:
+      - type: S3
+        bucket: sc-reports
+        prefix: sc
+        endpoint: `+endpoint+`
+        use_path_style: true
+        manifest_store: s3
+`)
+	defer os.Remove(filename)
+
+	cfg, err := config.LoadConfig(filename)
+	if err != nil {
+		t.Fatalf("LoadConfig() failed: %v", err)
+	}
+	err = cfg.RunReportOnce("users")
+	if err != nil {
+		t.Fatalf("RunReportOnce() failed: %v", err)
+	}
+
+	expected := "id,name\n0,Old User\n1,Test User 1\n2,Test User 2\n"
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if got := string(fake.objects["sc-reports/sc/users/users.csv"]); got != expected {
+		t.Errorf("Expected the uploaded master to keep its history:\n%s\nGot:\n%s", expected, got)
+	}
+}
@@ -1,12 +1,20 @@
 package config
 
 import (
-	"encoding/csv"
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/lehigh-university-libraries/encode/pkg/anomaly"
+	"github.com/lehigh-university-libraries/encode/pkg/connection"
+	"github.com/lehigh-university-libraries/encode/pkg/format"
+	"github.com/lehigh-university-libraries/encode/pkg/history"
+	"github.com/lehigh-university-libraries/encode/pkg/schema"
+	"github.com/lehigh-university-libraries/encode/pkg/sink"
 	cron "github.com/robfig/cron/v3"
 )
 
@@ -25,106 +33,316 @@ func (c *Config) StartCron() *cron.Cron {
 	return cron
 }
 
+// NextRuns returns when the scheduler next runs each report
+func NextRuns(scheduler *cron.Cron) map[string]time.Time {
+	next := make(map[string]time.Time)
+	for _, e := range scheduler.Entries() {
+		report, ok := e.Job.(ReportConfig)
+		if !ok {
+			continue
+		}
+		// Entries only know their next run once the scheduler has started
+		t := e.Next
+		if t.IsZero() {
+			t = e.Schedule.Next(time.Now())
+		}
+		next[report.Name] = t
+	}
+	return next
+}
+
+// ErrNoResults is returned by runs whose query returned no rows
+var ErrNoResults = errors.New("no results returned")
+
+// ErrValidationFailed is wrapped by the errors of runs halted because their
+// results failed a check, before anything was published
+var ErrValidationFailed = errors.New("validation failed")
+
+// ErrFetchFailed is wrapped by the errors of runs whose query failed
+var ErrFetchFailed = errors.New("unable to fetch report")
+
+// ErrSinksFailed is wrapped by the errors of runs where a sink failed
+var ErrSinksFailed = errors.New("sinks failed")
+
+// ErrQuarantined is wrapped by the errors of runs whose results failed an
+// anomaly check and were set aside in the quarantine directory
+var ErrQuarantined = fmt.Errorf("%w: quarantined", ErrValidationFailed)
+
+// Run triggers
+const (
+	TriggerCron     = "cron"
+	TriggerManual   = "manual"
+	TriggerBackfill = "backfill"
+	TriggerCatchUp  = "catch-up"
+)
+
+// RunOptions describe one run of a report
+type RunOptions struct {
+	// ScheduledTime is the run's logical time, which query templates see as .ScheduledTime
+	ScheduledTime time.Time
+	// PeriodStart and PeriodEnd bound the data the run covers. They default
+	// to the schedule's previous tick and ScheduledTime
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	// Trigger is what started the run: cron, catch-up, manual or backfill
+	Trigger string
+	// FileName replaces the timestamp the report file is named with
+	FileName string
+	// RunID identifies the run in the history store; one is generated if empty
+	RunID string
+	// AcceptDrift accepts any schema drift, even when the report blocks it
+	AcceptDrift bool
+	// Params override query params and named params by name for this run
+	Params map[string]string
+}
+
 // https://pkg.go.dev/github.com/robfig/cron#FuncJob.Run
+// The run is scheduled for the current minute, the cron tick that fired it
 func (r ReportConfig) Run() {
-	slog.Debug("Running", "report", r.Name)
-
-	results, err := r.connection.FetchReport(r.QueryParams)
+	scheduled := time.Now().Truncate(time.Minute)
+	paused, err := r.Paused()
 	if err != nil {
-		slog.Error("Unable to fetch report", "report", r.Name, "err", err)
-		return
+		slog.Error("Unable to check whether report is paused", "report", r.Name, "err", err)
 	}
-
-	if len(results) == 0 {
-		slog.Error("NO results returned", "report", r.Name, "err", err)
+	if !paused.IsZero() {
+		slog.Info("Report paused, skipping scheduled run", "report", r.Name, "paused", paused)
+		// Ticks skipped while paused are not missed, so catch-up leaves them be
+		err = r.recordRun(scheduled)
+		if err != nil {
+			slog.Error("Unable to save report state", "report", r.Name, "err", err)
+		}
 		return
 	}
-
-	reportDir := filepath.Join(r.StagingDirectory, r.Name)
-	err = os.MkdirAll(reportDir, 0755)
-	if err != nil {
-		slog.Error("Error creating report directory", "dir", reportDir, "err", err)
-		return
+	err = r.Execute(RunOptions{ScheduledTime: scheduled, Trigger: TriggerCron})
+	if errors.Is(err, ErrRunning) {
+		slog.Warn("Report still running, skipping scheduled run", "report", r.Name, "err", err)
+	} else if err != nil {
+		slog.Error("Report run failed", "report", r.Name, "err", err)
 	}
+}
 
-	filename := filepath.Join(reportDir, time.Now().Format("2006-01-02.15.04.05.csv"))
-	file, err := os.Create(filename)
-	if err != nil {
-		slog.Error("Error creating file", "filename", filename, "err", err)
-		return
+// Execute runs the report once and records the outcome in the run history.
+// It fails with ErrRunning, without running or recording anything, while
+// another run of the report is in progress
+func (r ReportConfig) Execute(opts RunOptions) error {
+	opts = r.runOptions(opts)
+	rec := history.Record{
+		ID:            opts.RunID,
+		Report:        r.Name,
+		Trigger:       opts.Trigger,
+		ScheduledTime: opts.ScheduledTime,
+		Start:         time.Now(),
 	}
-	defer file.Close()
-	err = writeToCSV(results, file)
+	log, err := r.runs.start(rec)
 	if err != nil {
-		slog.Error("Error writing CSV", "filename", filename, "err", err)
-		return
+		return err
 	}
+	r.log = log
+	err = r.execute(opts, &rec)
+	rec.Finish(time.Now(), err)
+	switch {
+	case errors.Is(err, ErrQuarantined):
+		rec.Status = history.StatusQuarantined
+	case errors.Is(err, ErrValidationFailed):
+		rec.Status = history.StatusHalted
+	}
+	if r.history != nil {
+		herr := r.history.Append(rec)
+		if herr != nil {
+			r.logger().Error("Unable to save run history", "err", herr)
+		}
+	}
+	r.runs.finish(rec.ID)
+	recordMetrics(rec, err)
+	r.notifyRun(rec)
+	r.saveLog(rec)
+	return err
+}
 
-	slog.Info("Saved report", "filename", filename)
-
-	// Upload to S3 if configured
-	if r.s3Uploader != nil {
-		s3URI, err := r.s3Uploader.UploadFile(filename, r.Name)
+func (r ReportConfig) execute(opts RunOptions, rec *history.Record) error {
+	r.logger().Debug("Running", "scheduled", opts.ScheduledTime, "trigger", opts.Trigger)
+	if opts.Trigger == TriggerCron || opts.Trigger == TriggerCatchUp {
+		err := r.recordRun(opts.ScheduledTime)
 		if err != nil {
-			slog.Error("Failed to upload file to S3", "filename", filename, "err", err)
-			return
+			r.logger().Error("Unable to save report state", "err", err)
 		}
+	}
+	startedAt := time.Now()
 
-		// Generate and upload manifest if URI was returned
-		if s3URI != "" {
-			err = r.s3Uploader.GenerateManifest(r.Name, s3URI)
-			if err != nil {
-				slog.Error("Failed to generate manifest", "report", r.Name, "err", err)
-				return
-			}
+	params, binds, err := r.RenderQuery(opts)
+	if err != nil {
+		return fmt.Errorf("unable to prepare query: %w", err)
+	}
 
-			// Upload the updated manifest to S3 for QuickSight
-			manifestURL, err := r.s3Uploader.UploadManifestForReport(r.Name)
-			if err != nil {
-				slog.Error("Failed to upload manifest to S3", "report", r.Name, "err", err)
-				return
+	result, err := connection.FetchBound(connection.WithLogger(r.connection, r.log), params, binds)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrFetchFailed, err)
+	}
+
+	if len(result.Rows) == 0 {
+		switch r.emptyResult() {
+		case EmptyResultSkip:
+			if r.Incremental != nil {
+				r.logger().Info("No new rows since watermark", "watermark", params[connection.WatermarkParam])
+			} else {
+				r.logger().Info("No results, skipping run")
 			}
-			if manifestURL != "" {
-				slog.Info("Manifest available for QuickSight", "report", r.Name, "url", manifestURL)
+			return nil
+		case EmptyResultHeader:
+			result.Columns, err = r.emptyColumns(result.Columns)
+			if err != nil {
+				return err
 			}
+			r.logger().Info("No results, writing header only", "columns", len(result.Columns))
+		default:
+			return ErrNoResults
 		}
 	}
-}
-
-// writeToCSV writes a slice of maps to a CSV file.
-// The first map's keys are used as the header row.
-func writeToCSV(data []map[string]string, f *os.File) error {
-	// Create a new CSV writer
-	file := csv.NewWriter(f)
-	defer file.Flush()
+	fetched := result
 
-	if len(data) == 0 {
-		return nil
+	if r.Schema != nil {
+		err = r.Schema.Check(result.Columns, result.Rows)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrValidationFailed, err)
+		}
+	}
+	inferred := schema.Infer(result.Columns, result.Rows)
+	err = r.checkDrift(inferred, opts, rec)
+	if err != nil {
+		return err
 	}
 
-	// Get the keys from the first map to use as the header row
-	var header []string
-	for key := range data[0] {
-		header = append(header, key)
+	reportDir := filepath.Join(r.StagingDirectory, r.Name)
+	err = os.MkdirAll(reportDir, 0755)
+	if err != nil {
+		return fmt.Errorf("error creating report directory %s: %w", reportDir, err)
 	}
 
-	// Write the header row
-	err := file.Write(header)
+	name := opts.FileName
+	if name == "" {
+		name = time.Now().Format("2006-01-02.15.04.05")
+	}
+	err = r.checkAnomalies(result, rec)
+	var aerr *anomaly.Error
+	if errors.As(err, &aerr) {
+		return r.quarantine(reportDir, name, result, rec, aerr)
+	}
 	if err != nil {
 		return err
 	}
 
-	// Write the data rows
-	for _, row := range data {
-		var record []string
-		for _, key := range header {
-			record = append(record, row[key])
+	var filename string
+	if r.Consolidate != nil {
+		filename, result, err = r.consolidate(reportDir, result)
+		if err != nil {
+			return fmt.Errorf("error consolidating report: %w", err)
 		}
-		err = file.Write(record)
+	} else {
+		filename = filepath.Join(reportDir, name+"."+r.Output.Extension())
+		err = format.WriteFile(filename, r.Output, result.Columns, result.Rows)
 		if err != nil {
-			return err
+			return fmt.Errorf("error writing report file %s: %w", filename, err)
 		}
 	}
 
+	r.logger().Info("Saved report", "filename", filename)
+	rec.Rows = len(fetched.Rows)
+	if r.Consolidate != nil {
+		rec.TotalRows = len(result.Rows)
+	}
+	rec.Files = append(rec.Files, filename)
+	if info, err := os.Stat(filename); err == nil {
+		rec.Bytes = info.Size()
+	}
+
+	out := &sink.Output{
+		Report:  r.Name,
+		File:    filename,
+		Format:  r.Output,
+		Columns: result.Columns,
+		Rows:    result.Rows,
+		Log:     r.log,
+	}
+	statuses := sink.WriteAll(r.sinks, out)
+
+	summary := make([]string, len(statuses))
+	for i, status := range statuses {
+		summary[i] = status.String()
+		switch {
+		case !status.OK() || status.Location == "":
+		case strings.HasPrefix(status.Location, "s3://"):
+			rec.URIs = append(rec.URIs, status.Location)
+		default:
+			rec.Files = append(rec.Files, status.Location)
+		}
+	}
+	failed := sink.Failed(statuses)
+	if len(failed) > 0 {
+		r.logger().Error("Report run finished with failed sinks", "rows", len(result.Rows), "sinks", strings.Join(summary, " "), "failed", len(failed))
+		return fmt.Errorf("%d of %d %w", len(failed), len(statuses), ErrSinksFailed)
+	}
+	r.logger().Info("Report run finished", "rows", len(result.Rows), "sinks", strings.Join(summary, " "))
+
+	// A backfilled window is history; it says nothing about the report's current state
+	if opts.Trigger == TriggerBackfill {
+		return nil
+	}
+
+	// An empty run has no values to infer types from, so the baseline is kept
+	if len(fetched.Rows) == 0 {
+		inferred = nil
+	}
+	err = r.recordSuccess(opts.ScheduledTime, inferred)
+	if err != nil {
+		r.logger().Error("Unable to save report state", "err", err)
+	}
+
+	// Only move the watermark once every destination has the data
+	if r.Incremental != nil {
+		err = r.advanceWatermark(params[connection.WatermarkParam], fetched, startedAt)
+		if err != nil {
+			return fmt.Errorf("unable to advance watermark: %w", err)
+		}
+	}
 	return nil
 }
+
+// runOptions fills in the defaults for a run
+func (r ReportConfig) runOptions(opts RunOptions) RunOptions {
+	if opts.ScheduledTime.IsZero() {
+		opts.ScheduledTime = time.Now().Truncate(time.Minute)
+	}
+	if opts.Trigger == "" {
+		opts.Trigger = TriggerManual
+	}
+	if opts.RunID == "" {
+		opts.RunID = history.NewID(time.Now())
+	}
+	if opts.PeriodEnd.IsZero() {
+		opts.PeriodEnd = opts.ScheduledTime
+	}
+	if opts.PeriodStart.IsZero() {
+		opts.PeriodStart = previousTick(r.Schedule, opts.PeriodEnd)
+	}
+	return opts
+}
+
+// previousTick is the last time before t that schedule fires
+func previousTick(schedule string, t time.Time) time.Time {
+	sched, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return time.Time{}
+	}
+	// Look back far enough to find a tick, starting close to keep the walk short
+	for _, span := range []time.Duration{time.Hour, 24 * time.Hour, 8 * 24 * time.Hour, 32 * 24 * time.Hour, 367 * 24 * time.Hour, 5 * 367 * 24 * time.Hour} {
+		tick := sched.Next(t.Add(-span))
+		if !tick.Before(t) {
+			continue
+		}
+		for next := sched.Next(tick); next.Before(t); next = sched.Next(tick) {
+			tick = next
+		}
+		return tick
+	}
+	return time.Time{}
+}
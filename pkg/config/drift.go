@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/lehigh-university-libraries/encode/pkg/history"
+	"github.com/lehigh-university-libraries/encode/pkg/schema"
+)
+
+// Schema drift policies
+const (
+	// SchemaDriftBlock halts runs with breaking changes: removed columns or changed types
+	SchemaDriftBlock = "block"
+	// SchemaDriftWarn logs every change and publishes the run
+	SchemaDriftWarn = "warn"
+	// SchemaDriftAccept publishes the run and takes its schema as the new baseline without warning
+	SchemaDriftAccept = "accept"
+)
+
+func validateSchemaDrift(policy string) error {
+	switch policy {
+	case "", SchemaDriftBlock, SchemaDriftWarn, SchemaDriftAccept:
+		return nil
+	}
+	return fmt.Errorf("invalid schema_drift '%s': must be %s, %s or %s", policy, SchemaDriftBlock, SchemaDriftWarn, SchemaDriftAccept)
+}
+
+// checkDrift compares a run's schema to the last successful run's and
+// applies the report's schema_drift policy. Warnings are added to rec
+func (r ReportConfig) checkDrift(inferred []schema.InferredColumn, opts RunOptions, rec *history.Record) error {
+	if r.state == nil {
+		return nil
+	}
+	st, err := r.state.Load(r.Name)
+	if err != nil {
+		return err
+	}
+	if len(st.Schema) == 0 {
+		return nil
+	}
+	drift := schema.Diff(st.Schema, inferred)
+	if len(drift) == 0 {
+		return nil
+	}
+
+	policy := r.SchemaDrift
+	if opts.AcceptDrift {
+		policy = SchemaDriftAccept
+	}
+	switch policy {
+	case SchemaDriftAccept:
+		r.logger().Info("Schema changed since the last successful run", "changes", drift.String())
+		return nil
+	case SchemaDriftBlock:
+		if breaking := drift.Breaking(); len(breaking) > 0 {
+			r.logger().Error("Breaking schema drift", "changes", drift.String())
+			return fmt.Errorf("%w: breaking schema drift: %s", ErrValidationFailed, breaking)
+		}
+	}
+
+	r.logger().Warn("Schema drift", "changes", drift.String(), "breaking", len(drift.Breaking()))
+	for _, c := range drift {
+		rec.Warnings = append(rec.Warnings, "schema drift: "+c.String())
+	}
+	return nil
+}
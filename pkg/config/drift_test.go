@@ -0,0 +1,200 @@
+package config_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lehigh-university-libraries/encode/pkg/config"
+	"github.com/lehigh-university-libraries/encode/pkg/history"
+	"github.com/lehigh-university-libraries/encode/pkg/notify"
+	"github.com/lehigh-university-libraries/encode/pkg/schema"
+	"github.com/lehigh-university-libraries/encode/pkg/state"
+)
+
+func TestExecute_SchemaDrift(t *testing.T) {
+	tests := []struct {
+		name           string
+		policy         string
+		previous       []schema.InferredColumn
+		acceptDrift    bool
+		expectStatus   string
+		expectWarnings int
+		expectBaseline []schema.InferredColumn
+	}{
+		{
+			name:           "No previous schema",
+			policy:         "block",
+			expectStatus:   history.StatusSucceeded,
+			expectBaseline: []schema.InferredColumn{{Name: "id", Type: "integer"}, {Name: "name", Type: "string"}},
+		},
+		{
+			name:           "Added column warns",
+			policy:         "block",
+			previous:       []schema.InferredColumn{{Name: "id", Type: "integer"}},
+			expectStatus:   history.StatusSucceeded,
+			expectWarnings: 1,
+			expectBaseline: []schema.InferredColumn{{Name: "id", Type: "integer"}, {Name: "name", Type: "string"}},
+		},
+		{
+			name:           "Removed column blocks",
+			policy:         "block",
+			previous:       []schema.InferredColumn{{Name: "id", Type: "integer"}, {Name: "name", Type: "string"}, {Name: "email", Type: "string"}},
+			expectStatus:   history.StatusHalted,
+			expectBaseline: []schema.InferredColumn{{Name: "id", Type: "integer"}, {Name: "name", Type: "string"}, {Name: "email", Type: "string"}},
+		},
+		{
+			name:           "Type change warns by default",
+			previous:       []schema.InferredColumn{{Name: "id", Type: "date"}, {Name: "name", Type: "string"}},
+			expectStatus:   history.StatusSucceeded,
+			expectWarnings: 1,
+			expectBaseline: []schema.InferredColumn{{Name: "id", Type: "integer"}, {Name: "name", Type: "string"}},
+		},
+		{
+			name:           "Accept is silent",
+			policy:         "accept",
+			previous:       []schema.InferredColumn{{Name: "name", Type: "string"}, {Name: "id", Type: "date"}},
+			expectStatus:   history.StatusSucceeded,
+			expectBaseline: []schema.InferredColumn{{Name: "id", Type: "integer"}, {Name: "name", Type: "string"}},
+		},
+		{
+			name:           "Accepting a blocked change",
+			policy:         "block",
+			previous:       []schema.InferredColumn{{Name: "id", Type: "date"}, {Name: "name", Type: "string"}},
+			acceptDrift:    true,
+			expectStatus:   history.StatusSucceeded,
+			expectBaseline: []schema.InferredColumn{{Name: "id", Type: "integer"}, {Name: "name", Type: "string"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			staging := t.TempDir()
+			filename := createTempYAML(t, `
+stagingDirectory: `+staging+`
+
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: users
+    connection: mock
+    schedule: "0 12 * * *"
+    schema_drift: "`+tt.policy+`"
+`)
+			defer os.Remove(filename)
+
+			cfg, err := config.LoadConfig(filename)
+			if err != nil {
+				t.Fatalf("LoadConfig() failed: %v", err)
+			}
+			if tt.previous != nil {
+				data, _ := json.Marshal(state.ReportState{Schema: tt.previous})
+				stateDir := filepath.Join(staging, ".state")
+				if err := os.MkdirAll(stateDir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(stateDir, "users.json"), data, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			report, err := cfg.Report("users")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = report.Execute(config.RunOptions{AcceptDrift: tt.acceptDrift})
+
+			records, err := cfg.History().List(history.Filter{Report: "users"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 1 || records[0].Status != tt.expectStatus {
+				t.Fatalf("Expected one %s run, got %+v", tt.expectStatus, records)
+			}
+			if len(records[0].Warnings) != tt.expectWarnings {
+				t.Errorf("Expected %d warnings, got %v", tt.expectWarnings, records[0].Warnings)
+			}
+
+			st, err := state.NewStore(filepath.Join(staging, ".state")).Load("users")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(st.Schema) != len(tt.expectBaseline) {
+				t.Fatalf("Expected baseline %v, got %v", tt.expectBaseline, st.Schema)
+			}
+			for i := range st.Schema {
+				if st.Schema[i] != tt.expectBaseline[i] {
+					t.Errorf("Expected baseline %v, got %v", tt.expectBaseline, st.Schema)
+				}
+			}
+		})
+	}
+}
+
+func TestExecute_SchemaDriftNotifies(t *testing.T) {
+	var mu sync.Mutex
+	var events []notify.Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e notify.Event
+		_ = json.NewDecoder(r.Body).Decode(&e)
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	staging := t.TempDir()
+	filename := createTempYAML(t, `
+stagingDirectory: `+staging+`
+notify:
+  - type: Webhook
+    url: `+server.URL+`
+
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: users
+    connection: mock
+    schedule: "0 12 * * *"
+    schema_drift: warn
+`)
+	defer os.Remove(filename)
+
+	cfg, err := config.LoadConfig(filename)
+	if err != nil {
+		t.Fatalf("LoadConfig() failed: %v", err)
+	}
+	data, _ := json.Marshal(state.ReportState{Schema: []schema.InferredColumn{{Name: "id", Type: "integer"}}})
+	stateDir := filepath.Join(staging, ".state")
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(stateDir, "users.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second run matches the new baseline, so only the first notifies
+	for i := 0; i < 2; i++ {
+		if err := cfg.RunReportOnce("users"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 1 || events[0].Kind != notify.EventWarning {
+		t.Fatalf("Expected one warning, got %+v", events)
+	}
+	if len(events[0].Warnings) != 1 || !strings.Contains(events[0].Warnings[0], "name") {
+		t.Errorf("Expected the warning to carry the added column, got %v", events[0].Warnings)
+	}
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Empty result policies
+const (
+	// EmptyResultFail fails the run with ErrNoResults. The default for full reports
+	EmptyResultFail = "fail"
+	// EmptyResultSkip ends the run quietly without writing anything. The default for incremental reports
+	EmptyResultSkip = "skip"
+	// EmptyResultHeader writes and publishes a file with only the header row
+	EmptyResultHeader = "header"
+)
+
+func validateEmptyResult(policy string) error {
+	switch policy {
+	case "", EmptyResultFail, EmptyResultSkip, EmptyResultHeader:
+		return nil
+	}
+	return fmt.Errorf("invalid empty_result '%s': must be %s, %s or %s", policy, EmptyResultFail, EmptyResultSkip, EmptyResultHeader)
+}
+
+// emptyResult is the report's empty result policy with its default filled in
+func (r ReportConfig) emptyResult() string {
+	if r.EmptyResult != "" {
+		return r.EmptyResult
+	}
+	// No new rows is the normal answer for an incremental report
+	if r.Incremental != nil {
+		return EmptyResultSkip
+	}
+	return EmptyResultFail
+}
+
+// emptyColumns is the header for a run that returned no rows. Connectors
+// that cannot describe an empty result, such as FOLIO, leave columns empty;
+// then the schema contract's columns are used, or the last successful run's
+func (r ReportConfig) emptyColumns(columns []string) ([]string, error) {
+	if len(columns) > 0 {
+		return columns, nil
+	}
+	if r.Schema != nil && len(r.Schema.Columns) > 0 {
+		columns = make([]string, len(r.Schema.Columns))
+		for i, c := range r.Schema.Columns {
+			columns[i] = c.Name
+		}
+		return columns, nil
+	}
+	if r.state != nil {
+		st, err := r.state.Load(r.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range st.Schema {
+			columns = append(columns, c.Name)
+		}
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("no results returned, and no columns are known to write a header")
+	}
+	return columns, nil
+}
@@ -0,0 +1,95 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lehigh-university-libraries/encode/pkg/config"
+	"github.com/lehigh-university-libraries/encode/pkg/history"
+)
+
+func TestRunReportOnce_EmptyResult(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        string
+		expectStatus  string
+		expectPublish string
+	}{
+		{
+			name:         "Fail by default",
+			expectStatus: history.StatusFailed,
+		},
+		{
+			name:         "Skip",
+			policy:       "skip",
+			expectStatus: history.StatusSucceeded,
+		},
+		{
+			name:          "Header only",
+			policy:        "header",
+			expectStatus:  history.StatusSucceeded,
+			expectPublish: "id,name\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			staging := t.TempDir()
+			filename := createTempYAML(t, `
+stagingDirectory: `+staging+`
+
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: overdue
+    connection: mock
+    query_params:
+      rows: "0"
+    schedule: "0 12 * * *"
+    empty_result: "`+tt.policy+`"
+    sinks:
+      - type: Local
+        path: `+filepath.Join(staging, "out")+`
+`)
+			defer os.Remove(filename)
+
+			cfg, err := config.LoadConfig(filename)
+			if err != nil {
+				t.Fatalf("LoadConfig() failed: %v", err)
+			}
+			err = cfg.RunReportOnce("overdue")
+			if err != nil {
+				t.Fatalf("RunReportOnce() failed: %v", err)
+			}
+
+			published, _ := filepath.Glob(filepath.Join(staging, "out", "overdue", "*.csv"))
+			if tt.expectPublish == "" {
+				if len(published) != 0 {
+					t.Errorf("Expected nothing published, got %v", published)
+				}
+			} else {
+				if len(published) != 1 {
+					t.Fatalf("Expected one published file, got %v", published)
+				}
+				data, err := os.ReadFile(published[0])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != tt.expectPublish {
+					t.Errorf("Expected %q, got %q", tt.expectPublish, string(data))
+				}
+			}
+
+			records, err := cfg.History().List(history.Filter{Report: "overdue"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 1 || records[0].Status != tt.expectStatus || records[0].Rows != 0 {
+				t.Fatalf("Expected one %s run with no rows, got %+v", tt.expectStatus, records)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/connection"
+	"github.com/lehigh-university-libraries/encode/pkg/state"
+)
+
+const (
+	// WatermarkMax advances to the largest value of a column in the results
+	WatermarkMax = "max"
+	// WatermarkRunTime advances to the time the last successful run started
+	WatermarkRunTime = "run_time"
+
+	WatermarkTypeTimestamp = "timestamp"
+	WatermarkTypeNumber    = "number"
+	WatermarkTypeString    = "string"
+
+	defaultWatermarkTimeFormat = "2006-01-02 15:04:05"
+)
+
+// watermarkTimeLayouts are the timestamp formats a max watermark column is read in
+var watermarkTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+// IncrementalConfig makes a report extract only what changed since its last
+// successful run. The watermark is passed to the query as the "watermark"
+// query param, which SQL queries reference as :watermark
+type IncrementalConfig struct {
+	// Watermark is "max" (the largest value of Column in the results) or "run_time"
+	Watermark string `yaml:"watermark"`
+	// Column holds the values a max watermark is taken from
+	Column string `yaml:"column"`
+	// Type is how max compares values: timestamp (default), number or string
+	Type string `yaml:"type"`
+	// Initial is the watermark before the first successful run
+	Initial string `yaml:"initial"`
+	// TimeFormat is the Go layout run_time watermarks are written in, in UTC (default "2006-01-02 15:04:05")
+	TimeFormat string `yaml:"time_format"`
+}
+
+func (i *IncrementalConfig) validate() error {
+	switch i.Watermark {
+	case WatermarkMax:
+		if i.Column == "" {
+			return errors.New("incremental.column is required for a max watermark")
+		}
+		switch i.valueType() {
+		case WatermarkTypeTimestamp, WatermarkTypeNumber, WatermarkTypeString:
+		default:
+			return fmt.Errorf("invalid incremental.type '%s': must be %s, %s or %s", i.Type, WatermarkTypeTimestamp, WatermarkTypeNumber, WatermarkTypeString)
+		}
+	case WatermarkRunTime:
+	default:
+		return fmt.Errorf("invalid incremental.watermark '%s': must be %s or %s", i.Watermark, WatermarkMax, WatermarkRunTime)
+	}
+	return nil
+}
+
+func (i *IncrementalConfig) valueType() string {
+	if i.Watermark == WatermarkRunTime {
+		return WatermarkTypeTimestamp
+	}
+	if i.Type == "" {
+		return WatermarkTypeTimestamp
+	}
+	return i.Type
+}
+
+// initial is the watermark before the first successful run
+func (i *IncrementalConfig) initial() string {
+	if i.Initial != "" {
+		return i.Initial
+	}
+	switch i.valueType() {
+	case WatermarkTypeNumber:
+		return "0"
+	case WatermarkTypeString:
+		return ""
+	}
+	return time.Unix(0, 0).UTC().Format(i.timeFormat())
+}
+
+func (i *IncrementalConfig) timeFormat() string {
+	if i.TimeFormat == "" {
+		return defaultWatermarkTimeFormat
+	}
+	return i.TimeFormat
+}
+
+// next is the watermark after a successful run that started at startedAt.
+// A run_time watermark is in UTC, like initial. A max watermark never moves backwards
+func (i *IncrementalConfig) next(current string, result *connection.Result, startedAt time.Time) (string, error) {
+	if i.Watermark == WatermarkRunTime {
+		return startedAt.UTC().Format(i.timeFormat()), nil
+	}
+
+	best := current
+	for _, row := range result.Rows {
+		v, ok := row[i.Column]
+		if !ok {
+			return "", fmt.Errorf("watermark column '%s' is missing from the results", i.Column)
+		}
+		if v == "" {
+			continue
+		}
+		greater, err := i.greater(v, best)
+		if err != nil {
+			return "", err
+		}
+		if greater {
+			best = v
+		}
+	}
+	return best, nil
+}
+
+// greater reports whether a sorts after b. An empty b sorts first
+func (i *IncrementalConfig) greater(a, b string) (bool, error) {
+	if b == "" {
+		return true, nil
+	}
+	switch i.valueType() {
+	case WatermarkTypeNumber:
+		x, err := strconv.ParseFloat(a, 64)
+		if err != nil {
+			return false, fmt.Errorf("invalid number watermark '%s'", a)
+		}
+		y, err := strconv.ParseFloat(b, 64)
+		if err != nil {
+			return false, fmt.Errorf("invalid number watermark '%s'", b)
+		}
+		return x > y, nil
+	case WatermarkTypeTimestamp:
+		x, err := parseWatermarkTime(a)
+		if err != nil {
+			return false, err
+		}
+		y, err := parseWatermarkTime(b)
+		if err != nil {
+			return false, err
+		}
+		return x.After(y), nil
+	}
+	return a > b, nil
+}
+
+func parseWatermarkTime(s string) (time.Time, error) {
+	for _, layout := range watermarkTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp watermark '%s'", s)
+}
+
+// advanceWatermark saves the watermark after a run whose sinks all succeeded
+func (r ReportConfig) advanceWatermark(current string, result *connection.Result, startedAt time.Time) error {
+	next, err := r.Incremental.next(current, result, startedAt)
+	if err != nil {
+		return err
+	}
+	err = r.state.Update(r.Name, func(st *state.ReportState) error {
+		st.Watermark = next
+		st.WatermarkUpdated = time.Now()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	r.logger().Info("Advanced watermark", "from", current, "to", next)
+	return nil
+}
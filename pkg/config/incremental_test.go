@@ -0,0 +1,125 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/config"
+	"github.com/lehigh-university-libraries/encode/pkg/state"
+)
+
+func TestRunReportOnce_Incremental(t *testing.T) {
+	tests := []struct {
+		name            string
+		sinkPath        func(staging string) string
+		expectWatermark string
+	}{
+		{
+			name:            "Watermark advances after every sink succeeds",
+			sinkPath:        func(staging string) string { return filepath.Join(staging, "out") },
+			expectWatermark: "2",
+		},
+		{
+			name: "Watermark stays put when a sink fails",
+			sinkPath: func(staging string) string {
+				// A file where the sink expects a directory
+				blocker := filepath.Join(staging, "blocker")
+				_ = os.WriteFile(blocker, nil, 0644)
+				return blocker
+			},
+			expectWatermark: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			staging := t.TempDir()
+			filename := createTempYAML(t, `
+stagingDirectory: `+staging+`
+
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: users
+    connection: mock
+    schedule: "0 12 * * *"
+    query_params:
+      query: "SELECT id, name FROM users WHERE id > :watermark"
+    incremental:
+      watermark: max
+      column: id
+      type: number
+    sinks:
+      - type: Local
+        path: `+tt.sinkPath(staging)+`
+`)
+			defer os.Remove(filename)
+
+			cfg, err := config.LoadConfig(filename)
+			if err != nil {
+				t.Fatalf("LoadConfig() failed: %v", err)
+			}
+			err = cfg.RunReportOnce("users")
+			if err != nil {
+				t.Fatalf("RunReportOnce() failed: %v", err)
+			}
+
+			st, err := state.NewStore(filepath.Join(staging, ".state")).Load("users")
+			if err != nil {
+				t.Fatalf("Load() failed: %v", err)
+			}
+			if st.Watermark != tt.expectWatermark {
+				t.Errorf("Expected watermark %q, got %q", tt.expectWatermark, st.Watermark)
+			}
+		})
+	}
+}
+
+func TestRunReportOnce_RunTimeWatermark(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("EST", -5*60*60)
+	defer func() { time.Local = local }()
+
+	staging := t.TempDir()
+	filename := createTempYAML(t, `
+stagingDirectory: `+staging+`
+
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: users
+    connection: mock
+    schedule: "0 12 * * *"
+    incremental:
+      watermark: run_time
+`)
+	defer os.Remove(filename)
+
+	cfg, err := config.LoadConfig(filename)
+	if err != nil {
+		t.Fatalf("LoadConfig() failed: %v", err)
+	}
+	before := time.Now().UTC().Truncate(time.Second)
+	err = cfg.RunReportOnce("users")
+	if err != nil {
+		t.Fatalf("RunReportOnce() failed: %v", err)
+	}
+
+	st, err := state.NewStore(filepath.Join(staging, ".state")).Load("users")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	watermark, err := time.Parse("2006-01-02 15:04:05", st.Watermark)
+	if err != nil {
+		t.Fatalf("Unable to parse watermark %q: %v", st.Watermark, err)
+	}
+	if watermark.Before(before) || watermark.After(time.Now().UTC()) {
+		t.Errorf("Expected a UTC watermark at or after %s, got %q", before, st.Watermark)
+	}
+}
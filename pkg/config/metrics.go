@@ -0,0 +1,90 @@
+package config
+
+import (
+	"errors"
+
+	"github.com/lehigh-university-libraries/encode/pkg/history"
+	"github.com/lehigh-university-libraries/encode/pkg/metrics"
+	cron "github.com/robfig/cron/v3"
+)
+
+// Failure reasons in encode_report_failures_total
+const (
+	reasonNoResults   = "no_results"
+	reasonFetch       = "fetch"
+	reasonHalted      = "halted"
+	reasonQuarantined = "quarantined"
+	reasonSinks       = "sinks"
+	reasonOther       = "other"
+)
+
+var (
+	reportRuns = metrics.Default.Counter("encode_report_runs_total",
+		"Report runs by report, trigger and status", "report", "trigger", "status")
+	reportFailures = metrics.Default.Counter("encode_report_failures_total",
+		"Report runs that did not succeed, by report and reason", "report", "reason")
+	reportLastSuccess = metrics.Default.Gauge("encode_report_last_success_timestamp_seconds",
+		"When the report's last successful run finished", "report")
+	reportDuration = metrics.Default.Gauge("encode_report_last_run_duration_seconds",
+		"How long the report's last run took", "report")
+	reportRows = metrics.Default.Gauge("encode_report_last_run_rows",
+		"Rows the report's last run extracted", "report")
+	reportRowsTotal = metrics.Default.Counter("encode_report_rows_extracted_total",
+		"Rows extracted across all of a report's runs", "report")
+	reportBytes = metrics.Default.Counter("encode_report_bytes_uploaded_total",
+		"Bytes of report files uploaded to S3, by report", "report")
+	reportNextRun = metrics.Default.Gauge("encode_report_next_run_timestamp_seconds",
+		"When the report is next scheduled to run", "report")
+)
+
+// recordMetrics updates the report series for a finished run
+func recordMetrics(rec history.Record, err error) {
+	reportRuns.Inc(rec.Report, rec.Trigger, rec.Status)
+	reportDuration.Set(rec.Duration.Seconds(), rec.Report)
+	reportRows.Set(float64(rec.Rows), rec.Report)
+	reportRowsTotal.Add(float64(rec.Rows), rec.Report)
+	if len(rec.URIs) > 0 {
+		reportBytes.Add(float64(rec.Bytes), rec.Report)
+	}
+	if err != nil {
+		reportFailures.Inc(rec.Report, failureReason(err))
+		return
+	}
+	reportLastSuccess.Set(float64(rec.End.Unix()), rec.Report)
+}
+
+func failureReason(err error) string {
+	switch {
+	case errors.Is(err, ErrNoResults):
+		return reasonNoResults
+	case errors.Is(err, ErrFetchFailed):
+		return reasonFetch
+	case errors.Is(err, ErrQuarantined):
+		return reasonQuarantined
+	case errors.Is(err, ErrValidationFailed):
+		return reasonHalted
+	case errors.Is(err, ErrSinksFailed):
+		return reasonSinks
+	}
+	return reasonOther
+}
+
+// ObserveSchedule keeps encode_report_next_run_timestamp_seconds up to date
+// with the scheduler's entries. Each report's last success is loaded from
+// the run history so the series survives a restart
+func (c *Config) ObserveSchedule(scheduler *cron.Cron) {
+	for _, report := range c.Reports {
+		if c.history == nil {
+			break
+		}
+		last, err := c.history.List(history.Filter{Report: report.Name, Status: history.StatusSucceeded, Limit: 1})
+		if err == nil && len(last) == 1 {
+			reportLastSuccess.Set(float64(last[0].End.Unix()), report.Name)
+		}
+	}
+	metrics.Default.OnScrape(func() {
+		for name, next := range NextRuns(scheduler) {
+			reportNextRun.Set(float64(next.Unix()), name)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package config_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lehigh-university-libraries/encode/pkg/config"
+	"github.com/lehigh-university-libraries/encode/pkg/metrics"
+)
+
+func TestExecute_Metrics(t *testing.T) {
+	staging := t.TempDir()
+	filename := createTempYAML(t, `
+stagingDirectory: `+staging+`
+
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: metrics_ok
+    connection: mock
+    schedule: "0 12 * * *"
+  - name: metrics_empty
+    connection: mock
+    query_params:
+      rows: "0"
+    schedule: "0 12 * * *"
+`)
+	defer os.Remove(filename)
+
+	cfg, err := config.LoadConfig(filename)
+	if err != nil {
+		t.Fatalf("LoadConfig() failed: %v", err)
+	}
+	for _, name := range []string{"metrics_ok", "metrics_empty"} {
+		if err := cfg.RunReportOnce(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scheduler := cfg.StartCron()
+	cfg.ObserveSchedule(scheduler)
+
+	var b strings.Builder
+	if err := metrics.Default.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	scrape := b.String()
+	for _, want := range []string{
+		`encode_report_runs_total{report="metrics_ok",trigger="manual",status="succeeded"} 1`,
+		`encode_report_last_run_rows{report="metrics_ok"} 2`,
+		`encode_report_last_success_timestamp_seconds{report="metrics_ok"}`,
+		`encode_report_failures_total{report="metrics_empty",reason="no_results"} 1`,
+		`encode_report_next_run_timestamp_seconds{report="metrics_empty"}`,
+		`encode_connector_request_duration_seconds_count{connector="mock",operation="fetch"}`,
+	} {
+		if !strings.Contains(scrape, want) {
+			t.Errorf("Expected scrape to contain %s", want)
+		}
+	}
+	if strings.Contains(scrape, `encode_report_last_success_timestamp_seconds{report="metrics_empty"}`) {
+		t.Errorf("Expected no last success for a report that never succeeded")
+	}
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/history"
+	"github.com/lehigh-university-libraries/encode/pkg/notify"
+	"github.com/lehigh-university-libraries/encode/pkg/state"
+)
+
+// defaultNotifyRepeat is how often a report that keeps failing is renotified
+const defaultNotifyRepeat = 24 * time.Hour
+
+// NotifyConfig is one channel in a notify list.
+// Settings holds the type-specific fields
+type NotifyConfig struct {
+	Type string `yaml:"type"`
+	Name string `yaml:"name"`
+	// Events limits the channel to failure, halt, recovery or warning events (default: all)
+	Events   []string       `yaml:"events"`
+	Settings map[string]any `yaml:",inline"`
+}
+
+// InitializeNotifiers builds the channels in a notify list
+func InitializeNotifiers(channels []NotifyConfig) ([]notify.Notifier, error) {
+	notifiers := make([]notify.Notifier, 0, len(channels))
+	names := make(map[string]bool)
+	for i, nc := range channels {
+		if nc.Name == "" {
+			nc.Name = fmt.Sprintf("%s-%d", strings.ToLower(nc.Type), i+1)
+		}
+		if names[nc.Name] {
+			return nil, fmt.Errorf("duplicate channel name '%s'", nc.Name)
+		}
+		names[nc.Name] = true
+
+		for _, e := range nc.Events {
+			err := notify.ValidateKind(e)
+			if err != nil {
+				return nil, fmt.Errorf("channel '%s': %w", nc.Name, err)
+			}
+		}
+		n, err := InitializeNotifier(nc)
+		if err != nil {
+			return nil, fmt.Errorf("channel '%s': %w", nc.Name, err)
+		}
+		notifiers = append(notifiers, notify.Filter(n, nc.Events))
+	}
+	return notifiers, nil
+}
+
+func InitializeNotifier(nc NotifyConfig) (notify.Notifier, error) {
+	switch nc.Type {
+	case "Email":
+		var ec notify.EmailConfig
+		if err := decodeSettings(nc.Settings, &ec); err != nil {
+			return nil, err
+		}
+		return notify.NewEmail(nc.Name, ec)
+	case "Webhook", "Slack", "Teams":
+		var wc notify.WebhookConfig
+		if err := decodeSettings(nc.Settings, &wc); err != nil {
+			return nil, err
+		}
+		switch nc.Type {
+		case "Slack":
+			return notify.NewSlack(nc.Name, wc)
+		case "Teams":
+			return notify.NewTeams(nc.Name, wc)
+		}
+		return notify.NewWebhook(nc.Name, wc)
+	default:
+		return nil, fmt.Errorf("unknown notify type: %s", nc.Type)
+	}
+}
+
+func parseNotifyRepeat(s string) (time.Duration, error) {
+	if s == "" {
+		return defaultNotifyRepeat, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid notifyRepeat '%s': must be a duration like 24h", s)
+	}
+	return d, nil
+}
+
+// notifyRun tells the report's channels about a finished run. A failure or
+// halt is sent when it starts and then at most once per notifyRepeat while
+// it lasts; the first successful run after it sends a recovery. Any other
+// successful run with warnings sends a warning
+func (r ReportConfig) notifyRun(rec history.Record) {
+	if len(r.notifiers) == 0 || r.state == nil || rec.Trigger == TriggerBackfill {
+		return
+	}
+
+	var event *notify.Event
+	err := r.state.Update(r.Name, func(st *state.ReportState) error {
+		kind := notify.EventFailure
+		switch rec.Status {
+		case history.StatusSucceeded:
+			if st.Alert == nil {
+				if len(rec.Warnings) > 0 {
+					event = r.event(notify.EventWarning, rec, st)
+				}
+				return nil
+			}
+			st.Alert = nil
+			event = r.event(notify.EventRecovery, rec, st)
+			return nil
+		case history.StatusHalted, history.StatusQuarantined:
+			kind = notify.EventHalt
+		}
+
+		if st.Alert == nil || st.Alert.Event != kind {
+			st.Alert = &state.Alert{Event: kind, Since: rec.End}
+		} else if r.notifyRepeat == 0 || rec.End.Sub(st.Alert.Sent) < r.notifyRepeat {
+			st.Alert.Suppressed++
+			r.logger().Debug("Notification suppressed", "event", kind, "since", st.Alert.Since)
+			return nil
+		}
+		event = r.event(kind, rec, st)
+		event.Repeated = st.Alert.Notified
+		st.Alert.Notified++
+		st.Alert.Sent = rec.End
+		st.Alert.Suppressed = 0
+		return nil
+	})
+	if err != nil {
+		r.logger().Error("Unable to save notification state", "err", err)
+		return
+	}
+	if event != nil {
+		notify.NotifyAll(r.notifiers, *event)
+	}
+}
+
+func (r ReportConfig) event(kind string, rec history.Record, st *state.ReportState) *notify.Event {
+	return &notify.Event{
+		Kind:          kind,
+		Report:        r.Name,
+		RunID:         rec.ID,
+		Status:        rec.Status,
+		Trigger:       rec.Trigger,
+		ScheduledTime: rec.ScheduledTime,
+		Error:         rec.Error,
+		Warnings:      rec.Warnings,
+		LastSuccess:   st.LastSuccess,
+		Owners:        r.Owners,
+	}
+}
@@ -0,0 +1,100 @@
+package config_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/lehigh-university-libraries/encode/pkg/config"
+	"github.com/lehigh-university-libraries/encode/pkg/notify"
+)
+
+func TestRunReportOnce_Notify(t *testing.T) {
+	var mu sync.Mutex
+	var events []notify.Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e notify.Event
+		_ = json.NewDecoder(r.Body).Decode(&e)
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	staging := t.TempDir()
+	load := func(rows, repeat string) *config.Config {
+		t.Helper()
+		filename := createTempYAML(t, `
+stagingDirectory: `+staging+`
+owners: [default@example.edu]
+notifyRepeat: "`+repeat+`"
+notify:
+  - type: Webhook
+    url: `+server.URL+`
+
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: users
+    connection: mock
+    owners: [owner@example.edu]
+    query_params:
+      rows: "`+rows+`"
+    schedule: "0 12 * * *"
+  - name: quiet
+    connection: mock
+    query_params:
+      rows: "0"
+    schedule: "0 12 * * *"
+    notify: []
+`)
+		t.Cleanup(func() { os.Remove(filename) })
+		cfg, err := config.LoadConfig(filename)
+		if err != nil {
+			t.Fatalf("LoadConfig() failed: %v", err)
+		}
+		return cfg
+	}
+	run := func(cfg *config.Config, report string) {
+		t.Helper()
+		if err := cfg.RunReportOnce(report); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A failing report notifies once, then stays quiet until it recovers
+	failing := load("0", "")
+	run(failing, "users")
+	run(failing, "users")
+	run(failing, "quiet")
+	passing := load("2", "")
+	run(passing, "users")
+	run(passing, "users")
+
+	if len(events) != 2 {
+		t.Fatalf("Expected a failure and a recovery, got %+v", events)
+	}
+	if events[0].Kind != notify.EventFailure || events[0].Error != "no results returned" || events[0].RunID == "" || !events[0].LastSuccess.IsZero() {
+		t.Errorf("Unexpected failure event %+v", events[0])
+	}
+	if len(events[0].Owners) != 1 || events[0].Owners[0] != "owner@example.edu" {
+		t.Errorf("Expected the report's owners, got %v", events[0].Owners)
+	}
+	if events[1].Kind != notify.EventRecovery || events[1].LastSuccess.IsZero() {
+		t.Errorf("Unexpected recovery event %+v", events[1])
+	}
+
+	// A short repeat period notifies every failing run, counting the earlier ones
+	events = nil
+	repeating := load("0", "1ns")
+	run(repeating, "users")
+	run(repeating, "users")
+	if len(events) != 2 || events[1].Repeated != 1 {
+		t.Errorf("Expected the failure to be repeated, got %+v", events)
+	}
+}
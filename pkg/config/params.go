@@ -0,0 +1,129 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/lehigh-university-libraries/encode/pkg/connection"
+	"github.com/lehigh-university-libraries/encode/pkg/render"
+)
+
+// ParamConfig declares a bind parameter sent to a SQL report's query
+type ParamConfig struct {
+	// Name is referenced in the query as :name. Leave it out on every param
+	// to bind them in order to $1, $2, ... (PostgreSQL) or ? (MariaDB)
+	Name string `yaml:"name"`
+	// Type is string (default), int, float, bool, date or timestamp
+	Type string `yaml:"type"`
+	// Value is a query template rendered at run time
+	Value string `yaml:"value"`
+	// Default is used when value is unset or renders empty
+	Default string `yaml:"default"`
+	value   *template.Template
+}
+
+// bind is the parameter as sent to the connection, with value already rendered
+func (p *ParamConfig) bind(value string) connection.BindParam {
+	if value == "" {
+		value = p.Default
+	}
+	return connection.BindParam{Name: p.Name, Type: p.Type, Value: value}
+}
+
+// parseParams validates the report's params and parses their values as templates
+func (r *ReportConfig) parseParams(cal render.Calendar) error {
+	if len(r.Params) == 0 {
+		return nil
+	}
+	if _, ok := r.connection.(connection.BindFetcher); !ok {
+		return fmt.Errorf("connection '%s' does not support params", r.Connection)
+	}
+
+	binds := make([]connection.BindParam, len(r.Params))
+	seen := make(map[string]bool)
+	for i := range r.Params {
+		p := &r.Params[i]
+		label := p.Name
+		if label == "" {
+			label = strconv.Itoa(i + 1)
+		}
+		if p.Name != "" {
+			if seen[p.Name] {
+				return fmt.Errorf("param '%s' is declared twice", p.Name)
+			}
+			seen[p.Name] = true
+		}
+		err := connection.ValidateParamType(p.Type)
+		if err != nil {
+			return fmt.Errorf("param '%s': %w", label, err)
+		}
+		if p.Default != "" {
+			_, err = p.bind("").Arg()
+			if err != nil {
+				return fmt.Errorf("invalid default: %w", err)
+			}
+		}
+		if strings.Contains(p.Value, "{{") {
+			p.value, err = render.Parse("params."+label, p.Value, cal, sqlDialect(r.connection))
+			if err != nil {
+				return err
+			}
+		}
+		binds[i] = p.bind(p.Value)
+	}
+
+	positional, err := connection.Positional(binds)
+	if err != nil {
+		return err
+	}
+	if positional && r.Incremental != nil {
+		return errors.New("incremental reports bind :watermark by name, so their params need names too")
+	}
+	return nil
+}
+
+// validateOverrides checks that each override replaces one of the report's
+// query params or named params with a value of the right type
+func (r ReportConfig) validateOverrides(overrides map[string]string) error {
+	for k, v := range overrides {
+		if k == "query" || k == connection.WatermarkParam {
+			return fmt.Errorf("param '%s' cannot be overridden", k)
+		}
+		if _, ok := r.QueryParams[k]; ok {
+			continue
+		}
+		i := slices.IndexFunc(r.Params, func(p ParamConfig) bool { return p.Name == k })
+		if i < 0 {
+			return fmt.Errorf("report '%s' has no param '%s'", r.Name, k)
+		}
+		_, err := r.Params[i].bind(v).Arg()
+		if err != nil {
+			return fmt.Errorf("param '%s': %w", k, err)
+		}
+	}
+	return nil
+}
+
+// bindParams renders the report's params for a run. An overridden param is
+// sent as given instead of rendering its value
+func (r ReportConfig) bindParams(data render.Data, overrides map[string]string) ([]connection.BindParam, error) {
+	binds := make([]connection.BindParam, len(r.Params))
+	for i, p := range r.Params {
+		value := p.Value
+		if v, ok := overrides[p.Name]; ok && p.Name != "" {
+			value = v
+		} else if p.value != nil {
+			var err error
+			value, err = render.Execute(p.value, data)
+			if err != nil {
+				return nil, err
+			}
+		}
+		binds[i] = p.bind(value)
+	}
+	return binds, nil
+}
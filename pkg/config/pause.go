@@ -0,0 +1,47 @@
+package config
+
+import (
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/state"
+)
+
+// Pause stops a report's scheduled runs until it is resumed. Manual runs and
+// backfills still run
+func (c *Config) Pause(reportName string) error {
+	report, err := c.Report(reportName)
+	if err != nil {
+		return err
+	}
+	return c.state.Update(report.Name, func(st *state.ReportState) error {
+		if st.Paused.IsZero() {
+			st.Paused = time.Now()
+		}
+		return nil
+	})
+}
+
+// Resume puts a paused report back on its schedule. Ticks skipped while it
+// was paused are not caught up
+func (c *Config) Resume(reportName string) error {
+	report, err := c.Report(reportName)
+	if err != nil {
+		return err
+	}
+	return c.state.Update(report.Name, func(st *state.ReportState) error {
+		st.Paused = time.Time{}
+		return nil
+	})
+}
+
+// Paused returns when the report's schedule was paused, or zero if it is not
+func (r ReportConfig) Paused() (time.Time, error) {
+	if r.state == nil {
+		return time.Time{}, nil
+	}
+	st, err := r.state.Load(r.Name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return st.Paused, nil
+}
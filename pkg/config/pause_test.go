@@ -0,0 +1,82 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/config"
+	"github.com/lehigh-university-libraries/encode/pkg/history"
+)
+
+func TestRun_Paused(t *testing.T) {
+	staging := t.TempDir()
+	filename := createTempYAML(t, `
+stagingDirectory: `+staging+`
+
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: users
+    connection: mock
+    schedule: "0 12 * * *"
+    catch_up: all
+    sinks:
+      - type: Local
+        path: `+filepath.Join(staging, "out")+`
+`)
+	defer os.Remove(filename)
+
+	cfg, err := config.LoadConfig(filename)
+	if err != nil {
+		t.Fatalf("LoadConfig() failed: %v", err)
+	}
+	err = cfg.Pause("users")
+	if err != nil {
+		t.Fatalf("Pause() failed: %v", err)
+	}
+	report, err := cfg.Report("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paused, err := report.Paused()
+	if err != nil || paused.IsZero() {
+		t.Fatalf("Expected report to be paused, got %v, %v", paused, err)
+	}
+
+	report.Run()
+	records, err := cfg.History().List(history.Filter{Report: "users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Errorf("Expected a paused report not to run, got %+v", records)
+	}
+	missed, err := report.MissedRuns(time.Now().Add(72 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missed) != 0 {
+		t.Errorf("Expected a paused report to have nothing to catch up, got %v", missed)
+	}
+
+	err = cfg.Resume("users")
+	if err != nil {
+		t.Fatalf("Resume() failed: %v", err)
+	}
+	report.Run()
+	records, err = cfg.History().List(history.Filter{Report: "users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Status != history.StatusSucceeded {
+		t.Errorf("Expected one succeeded run after resuming, got %+v", records)
+	}
+
+	if cfg.Pause("missing") == nil {
+		t.Error("Expected pausing an unknown report to fail")
+	}
+}
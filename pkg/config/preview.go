@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lehigh-university-libraries/encode/pkg/format"
+	"github.com/lehigh-university-libraries/encode/pkg/history"
+)
+
+// Preview is the start of a report's latest output
+type Preview struct {
+	// Run is the run that wrote the file
+	Run     history.Record
+	File    string
+	Columns []string
+	Rows    []map[string]string
+}
+
+// Preview reads the first n rows of the newest file a successful run left in
+// the staging directory. It returns nil if no such file is left
+func (r ReportConfig) Preview(n int) (*Preview, error) {
+	if r.history == nil {
+		return nil, nil
+	}
+	records, err := r.history.List(history.Filter{Report: r.Name, Status: history.StatusSucceeded})
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range records {
+		// The staged file is always the first; sinks' locations follow it
+		if len(rec.Files) == 0 {
+			continue
+		}
+		if _, err := os.Stat(rec.Files[0]); err != nil {
+			continue
+		}
+
+		var columns []string
+		if r.Output.NoHeader {
+			columns, err = r.emptyColumns(nil)
+			if err != nil {
+				return nil, fmt.Errorf("unable to name the columns of %s: %w", rec.Files[0], err)
+			}
+		}
+		columns, rows, err := format.ReadFileHead(rec.Files[0], r.Output, columns, n)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %w", rec.Files[0], err)
+		}
+		return &Preview{Run: rec, File: rec.Files[0], Columns: columns, Rows: rows}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,269 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/history"
+)
+
+// maxRunLogs is how many finished runs keep their logs in memory
+const maxRunLogs = 200
+
+// ErrRunning is returned when starting a report that already has a run in progress
+var ErrRunning = errors.New("report is already running")
+
+// ErrInvalidParams is wrapped by the errors of runs started with overrides
+// the report has no params for
+var ErrInvalidParams = errors.New("invalid params")
+
+// runTracker knows which runs are in progress and keeps the logs of recent runs
+type runTracker struct {
+	mu       sync.Mutex
+	active   map[string]history.Record
+	logs     map[string]*runLog
+	finished []string
+}
+
+func newRunTracker() *runTracker {
+	return &runTracker{
+		active: make(map[string]history.Record),
+		logs:   make(map[string]*runLog),
+	}
+}
+
+// start claims the report for rec and returns the logger for the run. Every
+// line it logs carries the report and run ID and is kept in the run's log
+func (t *runTracker) start(rec history.Record) (*slog.Logger, error) {
+	if t == nil {
+		return slog.Default().With("report", rec.Report, "run", rec.ID), nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l, err := t.claimLocked(rec)
+	if err != nil {
+		return nil, err
+	}
+	handler := teeHandler{slog.Default().Handler(), slog.NewTextHandler(l, &slog.HandlerOptions{Level: slog.LevelDebug})}
+	return slog.New(handler).With("report", rec.Report, "run", rec.ID), nil
+}
+
+// claim marks rec as running unless its report already has another run in
+// progress. A run claimed ahead of time can then start with the same ID
+func (t *runTracker) claim(rec history.Record) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, err := t.claimLocked(rec)
+	return err
+}
+
+func (t *runTracker) claimLocked(rec history.Record) (*runLog, error) {
+	for _, active := range t.active {
+		if active.Report == rec.Report && active.ID != rec.ID {
+			return nil, fmt.Errorf("%w: run %s", ErrRunning, active.ID)
+		}
+	}
+	return t.track(rec), nil
+}
+
+func (t *runTracker) track(rec history.Record) *runLog {
+	rec.Status = history.StatusRunning
+	t.active[rec.ID] = rec
+	l, ok := t.logs[rec.ID]
+	if !ok {
+		l = &runLog{}
+		t.logs[rec.ID] = l
+	}
+	return l
+}
+
+// finish stops tracking a run, keeping its log until maxRunLogs newer runs finish
+func (t *runTracker) finish(id string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.active, id)
+	t.finished = append(t.finished, id)
+	if len(t.finished) > maxRunLogs {
+		delete(t.logs, t.finished[0])
+		t.finished = t.finished[1:]
+	}
+}
+
+// running returns the report's run in progress
+func (t *runTracker) running(reportName string) (history.Record, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, rec := range t.active {
+		if rec.Report == reportName {
+			return rec, true
+		}
+	}
+	return history.Record{}, false
+}
+
+func (t *runTracker) get(id string) (history.Record, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rec, ok := t.active[id]
+	return rec, ok
+}
+
+func (t *runTracker) log(id string) ([]byte, bool) {
+	t.mu.Lock()
+	l, ok := t.logs[id]
+	t.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	return l.Bytes(), true
+}
+
+// runLog is the text log of one run
+type runLog struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (l *runLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.Write(p)
+}
+
+// Bytes returns a copy of the log so far
+func (l *runLog) Bytes() []byte {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return bytes.Clone(l.buf.Bytes())
+}
+
+// teeHandler sends each record to every handler that is enabled for its level
+type teeHandler []slog.Handler
+
+func (h teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, handler := range h {
+		if handler.Enabled(ctx, r.Level) {
+			errs = append(errs, handler.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make(teeHandler, len(h))
+	for i, handler := range h {
+		out[i] = handler.WithAttrs(attrs)
+	}
+	return out
+}
+
+func (h teeHandler) WithGroup(name string) slog.Handler {
+	out := make(teeHandler, len(h))
+	for i, handler := range h {
+		out[i] = handler.WithGroup(name)
+	}
+	return out
+}
+
+// logger is the run's logger inside Execute, and otherwise one that tags
+// lines with the report
+func (r ReportConfig) logger() *slog.Logger {
+	if r.log != nil {
+		return r.log
+	}
+	return slog.Default().With("report", r.Name)
+}
+
+// Start runs a report in the background and returns the run's ID. It fails
+// with ErrRunning while the report has a run in progress, and with
+// ErrInvalidParams if opts overrides params the report does not have
+func (c *Config) Start(reportName string, opts RunOptions) (string, error) {
+	report, err := c.Report(reportName)
+	if err != nil {
+		return "", err
+	}
+	opts = report.runOptions(opts)
+	err = report.validateOverrides(opts.Params)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidParams, err)
+	}
+	err = c.runs.claim(history.Record{
+		ID:            opts.RunID,
+		Report:        report.Name,
+		Trigger:       opts.Trigger,
+		ScheduledTime: opts.ScheduledTime,
+		Start:         time.Now(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	run := *report
+	go func() {
+		err := run.Execute(opts)
+		if err != nil {
+			slog.Error("Report run failed", "report", run.Name, "run", opts.RunID, "err", err)
+		}
+	}()
+	return opts.RunID, nil
+}
+
+// Running returns the report's run in progress
+func (c *Config) Running(reportName string) (*history.Record, bool) {
+	rec, ok := c.runs.running(reportName)
+	return &rec, ok
+}
+
+// RunRecord returns a run in progress or from the history by ID
+func (c *Config) RunRecord(id string) (*history.Record, error) {
+	if rec, ok := c.runs.get(id); ok {
+		return &rec, nil
+	}
+	return c.history.Get(id)
+}
+
+// RunLog returns the log of a run in progress or one of the last maxRunLogs
+// runs to finish, or else the log attached to the run in the history
+func (c *Config) RunLog(id string) ([]byte, error) {
+	if log, ok := c.runs.log(id); ok {
+		return log, nil
+	}
+	rec, err := c.history.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return c.history.Log(*rec)
+}
+
+// saveLog attaches the run's log to its record in the history
+func (r ReportConfig) saveLog(rec history.Record) {
+	if r.history == nil || r.runs == nil {
+		return
+	}
+	log, ok := r.runs.log(rec.ID)
+	if !ok {
+		return
+	}
+	err := r.history.SaveLog(rec, log)
+	if err != nil {
+		slog.Error("Unable to save run log", "report", r.Name, "run", rec.ID, "err", err)
+	}
+}
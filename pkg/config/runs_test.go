@@ -0,0 +1,164 @@
+package config_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/config"
+	"github.com/lehigh-university-libraries/encode/pkg/history"
+)
+
+func TestExecute_RunLog(t *testing.T) {
+	staging := t.TempDir()
+	filename := createTempYAML(t, `
+stagingDirectory: `+staging+`
+
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: users
+    connection: mock
+    schedule: "0 12 * * *"
+    sinks:
+      - type: Local
+        path: `+filepath.Join(staging, "out")+`
+`)
+	defer os.Remove(filename)
+
+	cfg, err := config.LoadConfig(filename)
+	if err != nil {
+		t.Fatalf("LoadConfig() failed: %v", err)
+	}
+	report, err := cfg.Report("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	err = report.Execute(config.RunOptions{RunID: "run-1"})
+	if err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+
+	var messages []string
+	for line := range strings.Lines(buf.String()) {
+		var entry map[string]any
+		err = json.Unmarshal([]byte(line), &entry)
+		if err != nil {
+			t.Fatalf("Expected a JSON log line, got %q", line)
+		}
+		if entry["report"] != "users" || entry["run"] != "run-1" {
+			t.Errorf("Expected the line to carry the report and run, got %q", line)
+		}
+		messages = append(messages, entry["msg"].(string))
+	}
+	for _, msg := range []string{"Saved report", "Sink succeeded", "Report run finished"} {
+		if !strings.Contains(strings.Join(messages, "\n"), msg) {
+			t.Errorf("Expected a %q line, got %v", msg, messages)
+		}
+	}
+
+	log, err := cfg.RunLog("run-1")
+	if err != nil {
+		t.Fatalf("RunLog() failed: %v", err)
+	}
+	rec, err := cfg.History().Get("run-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := cfg.History().Log(*rec)
+	if err != nil {
+		t.Fatalf("Expected the log attached to the run's history: %v", err)
+	}
+	if !bytes.Equal(log, saved) || !strings.Contains(string(saved), "Sink succeeded") {
+		t.Errorf("Expected the saved log to match the run's log, got %q and %q", saved, log)
+	}
+
+	// After a restart the log comes from the history
+	restarted, err := config.LoadConfig(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log, err = restarted.RunLog("run-1")
+	if err != nil || !bytes.Equal(log, saved) {
+		t.Errorf("Expected the saved log after a restart, got %q, %v", log, err)
+	}
+
+	_, err = cfg.RunLog("missing")
+	if err == nil {
+		t.Error("Expected an error for an unknown run")
+	}
+	_, err = cfg.History().Log(history.Record{ID: "missing", Report: "users"})
+	if err == nil {
+		t.Error("Expected an error for a run without a log")
+	}
+}
+
+func TestExecute_AlreadyRunning(t *testing.T) {
+	staging := t.TempDir()
+	filename := createTempYAML(t, `
+stagingDirectory: `+staging+`
+
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: users
+    connection: mock
+    schedule: "0 12 * * *"
+    query_params:
+      delay: 0s
+    sinks:
+      - type: Local
+        path: `+filepath.Join(staging, "out")+`
+`)
+	defer os.Remove(filename)
+
+	cfg, err := config.LoadConfig(filename)
+	if err != nil {
+		t.Fatalf("LoadConfig() failed: %v", err)
+	}
+	report, err := cfg.Report("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := cfg.Start("users", config.RunOptions{Params: map[string]string{"delay": "300ms"}})
+	if err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	err = report.Execute(config.RunOptions{Trigger: config.TriggerCron})
+	if !errors.Is(err, config.ErrRunning) {
+		t.Errorf("Expected ErrRunning while the started run is in progress, got %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for _, running := cfg.Running("users"); running && time.Now().Before(deadline); _, running = cfg.Running("users") {
+		time.Sleep(10 * time.Millisecond)
+	}
+	runs, err := cfg.History().List(history.Filter{Report: "users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].ID != id || runs[0].Status != history.StatusSucceeded {
+		t.Errorf("Expected only the started run in the history, got %+v", runs)
+	}
+
+	err = report.Execute(config.RunOptions{Trigger: config.TriggerCron})
+	if err != nil {
+		t.Errorf("Expected a run once the report is free, got %v", err)
+	}
+}
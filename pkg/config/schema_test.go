@@ -0,0 +1,87 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lehigh-university-libraries/encode/pkg/config"
+	"github.com/lehigh-university-libraries/encode/pkg/history"
+)
+
+func TestRunReportOnce_Schema(t *testing.T) {
+	tests := []struct {
+		name          string
+		schema        string
+		expectStatus  string
+		expectPublish bool
+	}{
+		{
+			name: "Passing contract publishes",
+			schema: `
+      columns:
+        - name: id
+          type: integer
+          min: 1
+        - name: name
+          nullable: false`,
+			expectStatus:  history.StatusSucceeded,
+			expectPublish: true,
+		},
+		{
+			name: "Failing contract halts before the sinks",
+			schema: `
+      columns:
+        - name: id
+          type: integer
+          max: 1
+        - name: name`,
+			expectStatus: history.StatusHalted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			staging := t.TempDir()
+			filename := createTempYAML(t, `
+stagingDirectory: `+staging+`
+
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: users
+    connection: mock
+    schedule: "0 12 * * *"
+    schema:`+tt.schema+`
+    sinks:
+      - type: Local
+        path: `+filepath.Join(staging, "out")+`
+`)
+			defer os.Remove(filename)
+
+			cfg, err := config.LoadConfig(filename)
+			if err != nil {
+				t.Fatalf("LoadConfig() failed: %v", err)
+			}
+			err = cfg.RunReportOnce("users")
+			if err != nil {
+				t.Fatalf("RunReportOnce() failed: %v", err)
+			}
+
+			_, err = os.Stat(filepath.Join(staging, "out", "users"))
+			if published := err == nil; published != tt.expectPublish {
+				t.Errorf("Expected published %v, got %v", tt.expectPublish, published)
+			}
+
+			records, err := cfg.History().List(history.Filter{Report: "users"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 1 || records[0].Status != tt.expectStatus {
+				t.Fatalf("Expected one %s run, got %+v", tt.expectStatus, records)
+			}
+		})
+	}
+}
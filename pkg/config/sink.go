@@ -0,0 +1,155 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lehigh-university-libraries/encode/pkg/connection"
+	"github.com/lehigh-university-libraries/encode/pkg/sink"
+	"github.com/lehigh-university-libraries/encode/pkg/storage"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// SinkConfig is one destination in a report's sinks list.
+// Settings holds the type-specific fields
+type SinkConfig struct {
+	Type string `yaml:"type"`
+	Name string `yaml:"name"`
+	// Connection names the connection used by GoogleSheets and Database sinks
+	Connection string `yaml:"connection"`
+	// HaltOnFailure skips the sinks listed after this one if it fails
+	HaltOnFailure bool           `yaml:"halt_on_failure"`
+	Settings      map[string]any `yaml:",inline"`
+}
+
+// InitializeSinks builds the sinks for a report. Reports without a sinks
+// list get an S3 sink when S3 is enabled for the report
+func InitializeSinks(report ReportConfig, config *Config) ([]sink.Sink, error) {
+	if len(report.Sinks) == 0 {
+		if report.s3Uploader == nil {
+			return nil, nil
+		}
+		return []sink.Sink{sink.NewS3("s3", report.s3Uploader)}, nil
+	}
+
+	sinks := make([]sink.Sink, 0, len(report.Sinks))
+	names := make(map[string]bool)
+	for i, sc := range report.Sinks {
+		if sc.Name == "" {
+			sc.Name = fmt.Sprintf("%s-%d", strings.ToLower(sc.Type), i+1)
+		}
+		if names[sc.Name] {
+			return nil, fmt.Errorf("duplicate sink name '%s'", sc.Name)
+		}
+		names[sc.Name] = true
+
+		s, err := InitializeSink(sc, report, config)
+		if err != nil {
+			return nil, fmt.Errorf("sink '%s': %w", sc.Name, err)
+		}
+		if sc.HaltOnFailure {
+			s = sink.WithHalt(s)
+		}
+		sinks = append(sinks, s)
+	}
+
+	return sinks, nil
+}
+
+func InitializeSink(sc SinkConfig, report ReportConfig, config *Config) (sink.Sink, error) {
+	switch sc.Type {
+	case "Local":
+		var lc sink.LocalConfig
+		if err := decodeSettings(sc.Settings, &lc); err != nil {
+			return nil, err
+		}
+		return sink.NewLocal(sc.Name, lc)
+	case "S3":
+		var override storage.S3Override
+		if err := decodeSettings(sc.Settings, &override); err != nil {
+			return nil, err
+		}
+		if override == (storage.S3Override{}) && report.s3Uploader != nil {
+			return sink.NewS3(sc.Name, report.s3Uploader), nil
+		}
+		base := config.S3
+		if report.s3Uploader != nil {
+			base = report.s3Uploader.Config()
+		}
+		s3c := base.Merge(override)
+		s3c.Enabled = true
+		if s3c.Bucket == "" {
+			return nil, fmt.Errorf("S3 sink requires bucket")
+		}
+		uploader, err := config.s3Clients.Uploader(s3c)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize S3 uploader: %w", err)
+		}
+		return sink.NewS3(sc.Name, uploader), nil
+	case "SFTP":
+		var sftpc sink.SFTPConfig
+		if err := decodeSettings(sc.Settings, &sftpc); err != nil {
+			return nil, err
+		}
+		return sink.NewSFTP(sc.Name, sftpc)
+	case "GoogleSheets":
+		var gsc sink.GoogleSheetsConfig
+		if err := decodeSettings(sc.Settings, &gsc); err != nil {
+			return nil, err
+		}
+		c, err := findConnection(config.Connections, sc.Connection)
+		if err != nil {
+			return nil, err
+		}
+		auth, ok := c.(*connection.GoogleSheetsAuth)
+		if !ok {
+			return nil, fmt.Errorf("connection '%s' is not a GoogleSheets connection", sc.Connection)
+		}
+		return sink.NewGoogleSheets(sc.Name, auth, gsc)
+	case "Database":
+		var dc sink.DatabaseConfig
+		if err := decodeSettings(sc.Settings, &dc); err != nil {
+			return nil, err
+		}
+		if report.Consolidate != nil && !dc.Truncate {
+			// A consolidated run hands sinks the whole merged dataset, which would be inserted again every run
+			return nil, fmt.Errorf("database sink requires truncate: true on a consolidated report")
+		}
+		c, err := findConnection(config.Connections, sc.Connection)
+		if err != nil {
+			return nil, err
+		}
+		writer, ok := c.(connection.RowWriter)
+		if !ok {
+			return nil, fmt.Errorf("connection '%s' cannot be written to", sc.Connection)
+		}
+		return sink.NewDatabase(sc.Name, writer, dc)
+	default:
+		return nil, fmt.Errorf("unknown sink type: %s", sc.Type)
+	}
+}
+
+// decodeSettings converts a sink's inline settings into its typed config
+func decodeSettings(settings map[string]any, v any) error {
+	data, err := yaml.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	err = yaml.Unmarshal(data, v)
+	if err != nil {
+		return fmt.Errorf("invalid settings: %w", err)
+	}
+	return nil
+}
+
+func findConnection(connections []map[string]any, name string) (connection.ConnectionProvider, error) {
+	if name == "" {
+		return nil, fmt.Errorf("connection is required")
+	}
+	for _, conn := range connections {
+		if conn["name"].(string) == name {
+			return InitializeConnection(conn)
+		}
+	}
+	return nil, fmt.Errorf("invalid connection reference '%s'", name)
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/connection"
+	"github.com/lehigh-university-libraries/encode/pkg/render"
+	"github.com/lehigh-university-libraries/encode/pkg/schema"
+	"github.com/lehigh-university-libraries/encode/pkg/state"
+)
+
+// parseTemplates loads the report's template file into query_params.query and
+// parses every query param that uses template actions
+func (r *ReportConfig) parseTemplates(configDir string, cal render.Calendar) error {
+	if r.TemplatePath != "" {
+		if _, ok := r.QueryParams["query"]; ok {
+			return fmt.Errorf("set either template or query_params.query, not both")
+		}
+		path := r.TemplatePath
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(configDir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("unable to read template: %w", err)
+		}
+		if r.QueryParams == nil {
+			r.QueryParams = make(map[string]string)
+		}
+		r.QueryParams["query"] = string(data)
+	}
+
+	r.templates = make(map[string]*template.Template)
+	for k, v := range r.QueryParams {
+		if !strings.Contains(v, "{{") {
+			continue
+		}
+		t, err := render.Parse(k, v, cal, sqlDialect(r.connection))
+		if err != nil {
+			return err
+		}
+		r.templates[k] = t
+	}
+	return nil
+}
+
+// RenderQuery returns the query params and bind params a run sends, with
+// templates rendered and the current watermark added
+func (r ReportConfig) RenderQuery(opts RunOptions) (map[string]string, []connection.BindParam, error) {
+	opts = r.runOptions(opts)
+	err := r.validateOverrides(opts.Params)
+	if err != nil {
+		return nil, nil, err
+	}
+	params := maps.Clone(r.QueryParams)
+	if params == nil {
+		params = make(map[string]string)
+	}
+	for k, v := range opts.Params {
+		if _, ok := params[k]; ok {
+			params[k] = v
+		}
+	}
+	raw := maps.Clone(params)
+
+	st := &state.ReportState{}
+	if r.state != nil {
+		st, err = r.state.Load(r.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if r.Incremental != nil {
+		watermark := st.Watermark
+		if watermark == "" {
+			watermark = r.Incremental.initial()
+		}
+		params[connection.WatermarkParam] = watermark
+		r.logger().Info("Extracting incrementally", "watermark", watermark)
+	}
+
+	data := render.Data{
+		ReportName:    r.Name,
+		ScheduledTime: opts.ScheduledTime,
+		PeriodStart:   opts.PeriodStart,
+		PeriodEnd:     opts.PeriodEnd,
+		LastSuccess:   st.LastSuccess,
+		Watermark:     params[connection.WatermarkParam],
+		Params:        raw,
+	}
+	for k, t := range r.templates {
+		if _, ok := opts.Params[k]; ok {
+			continue
+		}
+		v, err := render.Execute(t, data)
+		if err != nil {
+			return nil, nil, err
+		}
+		params[k] = v
+		r.logger().Debug("Rendered query param", "param", k, "value", v)
+	}
+
+	binds, err := r.bindParams(data, opts.Params)
+	if err != nil {
+		return nil, nil, err
+	}
+	return params, binds, nil
+}
+
+// recordSuccess saves the scheduled time and schema of a run whose sinks all succeeded
+func (r ReportConfig) recordSuccess(scheduled time.Time, inferred []schema.InferredColumn) error {
+	if r.state == nil {
+		return nil
+	}
+	return r.state.Update(r.Name, func(st *state.ReportState) error {
+		st.LastSuccess = scheduled
+		if inferred != nil {
+			st.Schema = inferred
+		}
+		return nil
+	})
+}
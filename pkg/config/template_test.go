@@ -0,0 +1,274 @@
+package config_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/config"
+	"github.com/lehigh-university-libraries/encode/pkg/connection"
+)
+
+func TestRenderQuery(t *testing.T) {
+	scheduled := time.Date(2024, 10, 2, 6, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		report      string
+		sql         string
+		params      map[string]string
+		expectError bool
+		expect      map[string]string
+		expectBinds []connection.BindParam
+	}{
+		{
+			name: "Template file",
+			report: `
+    template: queries/loans.sql
+    query_params:
+      branch: Linderman`,
+			sql: `SELECT * FROM loans
+WHERE loaned_at >= {{ .ScheduledTime | termStart | isoDate | sqlQuote }}
+  AND branch = {{ sqlQuote .Params.branch }} -- {{ .ReportName }}`,
+			expect: map[string]string{
+				"branch": "Linderman",
+				"query": `SELECT * FROM loans
+WHERE loaned_at >= '2024-09-01'
+  AND branch = 'Linderman' -- loans`,
+			},
+		},
+		{
+			name: "Inline query params",
+			report: `
+    query_params:
+      query: "SELECT * FROM loans WHERE day = {{ .ScheduledTime | addDays -1 | isoDate | sqlQuote }}"
+      term: "{{ termName .ScheduledTime }}"
+      period: "{{ .PeriodStart | isoDateTime }} to {{ .PeriodEnd | isoDateTime }}"`,
+			expect: map[string]string{
+				"query":  "SELECT * FROM loans WHERE day = '2024-10-01'",
+				"term":   "Fall",
+				"period": "2024-10-01 06:00:00 to 2024-10-02 06:00:00",
+			},
+		},
+		{
+			name: "Bind params",
+			report: `
+    connection: postgres
+    query_params:
+      query: SELECT * FROM loans WHERE loaned_at >= :since AND branch = :branch AND renewals > :renewals
+    params:
+      - name: since
+        type: date
+        value: "{{ .ScheduledTime | termStart | isoDate }}"
+      - name: branch
+        default: Linderman
+      - name: renewals
+        type: int
+        value: "2"`,
+			expect: map[string]string{
+				"query": "SELECT * FROM loans WHERE loaned_at >= :since AND branch = :branch AND renewals > :renewals",
+			},
+			expectBinds: []connection.BindParam{
+				{Name: "since", Type: "date", Value: "2024-09-01"},
+				{Name: "branch", Value: "Linderman"},
+				{Name: "renewals", Type: "int", Value: "2"},
+			},
+		},
+		{
+			name: "Overridden params",
+			report: `
+    connection: postgres
+    query_params:
+      branch: Linderman
+      query: "SELECT * FROM loans WHERE loaned_at >= :since AND branch = {{ sqlQuote .Params.branch }}"
+    params:
+      - name: since
+        type: date
+        value: "{{ .ScheduledTime | termStart | isoDate }}"`,
+			params: map[string]string{"branch": "Fairchild", "since": "2024-01-01"},
+			expect: map[string]string{
+				"branch": "Fairchild",
+				"query":  "SELECT * FROM loans WHERE loaned_at >= :since AND branch = 'Fairchild'",
+			},
+			expectBinds: []connection.BindParam{
+				{Name: "since", Type: "date", Value: "2024-01-01"},
+			},
+		},
+		{
+			name: "Overrides quoted for MariaDB",
+			report: `
+    connection: mariadb
+    query_params:
+      branch: Linderman
+      query: "SELECT * FROM loans WHERE branch = {{ sqlQuote .Params.branch }}"`,
+			params: map[string]string{"branch": `x\' OR 1=1 -- `},
+			expect: map[string]string{
+				"query": `SELECT * FROM loans WHERE branch = 'x\\'' OR 1=1 -- '`,
+			},
+		},
+		{
+			name: "Override of a param the report does not have",
+			report: `
+    query_params:
+      query: SELECT 1`,
+			params:      map[string]string{"branch": "Fairchild"},
+			expectError: true,
+		},
+		{
+			name: "Override of the query",
+			report: `
+    query_params:
+      query: SELECT 1`,
+			params:      map[string]string{"query": "SELECT 2"},
+			expectError: true,
+		},
+		{
+			name: "Override with the wrong type",
+			report: `
+    connection: postgres
+    params:
+      - name: since
+        type: date`,
+			params:      map[string]string{"since": "yesterday"},
+			expectError: true,
+		},
+		{
+			name: "Params on a connection without bind support",
+			report: `
+    connection: mock
+    params:
+      - name: branch
+        value: Linderman`,
+			expectError: true,
+		},
+		{
+			name: "Invalid param type",
+			report: `
+    connection: postgres
+    params:
+      - name: branch
+        type: text`,
+			expectError: true,
+		},
+		{
+			name: "Invalid param default",
+			report: `
+    connection: postgres
+    params:
+      - name: since
+        type: date
+        default: yesterday`,
+			expectError: true,
+		},
+		{
+			name: "Named and positional params mixed",
+			report: `
+    connection: postgres
+    params:
+      - name: branch
+        value: Linderman
+      - value: "2"`,
+			expectError: true,
+		},
+		{
+			name: "Template and query both set",
+			report: `
+    template: queries/loans.sql
+    query_params:
+      query: SELECT 1`,
+			sql:         "SELECT 2",
+			expectError: true,
+		},
+		{
+			name: "Missing template file",
+			report: `
+    template: queries/missing.sql`,
+			expectError: true,
+		},
+		{
+			name: "Invalid template",
+			report: `
+    query_params:
+      query: "SELECT {{ .ScheduledTime"`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.sql != "" {
+				err := os.MkdirAll(filepath.Join(dir, "queries"), 0755)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = os.WriteFile(filepath.Join(dir, "queries", "loans.sql"), []byte(tt.sql), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			filename := filepath.Join(dir, "encode.yaml")
+			err := os.WriteFile(filename, []byte(`
+stagingDirectory: `+dir+`
+
+connections:
+  - name: mock
+    type: Mock
+  - name: postgres
+    type: PostgreSQL
+    dsn: postgres://localhost/encode
+  - name: mariadb
+    type: MariaDB
+    dsn: encode@tcp(localhost)/encode
+
+reports:
+  - name: loans
+    schedule: "0 6 * * *"`+connectionLine(tt.report)+tt.report+"\n"), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := config.LoadConfig(filename)
+			if tt.expectError && err != nil {
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() failed: %v", err)
+			}
+
+			report, err := cfg.Report("loans")
+			if err != nil {
+				t.Fatal(err)
+			}
+			params, binds, err := report.RenderQuery(config.RunOptions{ScheduledTime: scheduled, Params: tt.params})
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderQuery() failed: %v", err)
+			}
+			for k, v := range tt.expect {
+				if params[k] != v {
+					t.Errorf("Expected %s %q, got %q", k, v, params[k])
+				}
+			}
+			if fmt.Sprint(binds) != fmt.Sprint(tt.expectBinds) {
+				t.Errorf("Expected binds %v, got %v", tt.expectBinds, binds)
+			}
+		})
+	}
+}
+
+// connectionLine defaults a test report to the mock connection
+func connectionLine(report string) string {
+	if strings.Contains(report, "connection:") {
+		return ""
+	}
+	return "\n    connection: mock"
+}
@@ -0,0 +1,247 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WatermarkParam is the query param holding an incremental report's watermark.
+// SQL queries reference it as :watermark
+const WatermarkParam = "watermark"
+
+// placeholderStyle is how a driver numbers bind parameters
+type placeholderStyle int
+
+const (
+	// placeholderDollar is PostgreSQL's $1, $2, ...; a repeated name reuses its number
+	placeholderDollar placeholderStyle = iota
+	// placeholderQuestion is MariaDB's ?; a repeated name is bound again
+	placeholderQuestion
+)
+
+// Bind parameter types
+const (
+	ParamTypeString    = "string"
+	ParamTypeInt       = "int"
+	ParamTypeFloat     = "float"
+	ParamTypeBool      = "bool"
+	ParamTypeDate      = "date"
+	ParamTypeTimestamp = "timestamp"
+)
+
+// paramTimeLayouts are the formats date and timestamp params are parsed from
+var paramTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// BindParam is a typed value sent to a SQL query as a driver bind parameter.
+// A named param is referenced in the query as :name; params without a name
+// are bound in order to $1, $2, ... (PostgreSQL) or ? (MariaDB)
+type BindParam struct {
+	Name  string
+	Type  string
+	Value string
+}
+
+// ValidateParamType checks that t is a known bind parameter type
+func ValidateParamType(t string) error {
+	switch t {
+	case "", ParamTypeString, ParamTypeInt, ParamTypeFloat, ParamTypeBool, ParamTypeDate, ParamTypeTimestamp:
+		return nil
+	}
+	return fmt.Errorf("invalid param type '%s': must be %s, %s, %s, %s, %s or %s", t, ParamTypeString, ParamTypeInt, ParamTypeFloat, ParamTypeBool, ParamTypeDate, ParamTypeTimestamp)
+}
+
+// Arg converts the param's value to the Go type the driver binds. An empty
+// value of any type but string is bound as NULL
+func (p BindParam) Arg() (any, error) {
+	if p.Type == "" || p.Type == ParamTypeString {
+		return p.Value, nil
+	}
+	if p.Value == "" {
+		return nil, nil
+	}
+
+	var arg any
+	var err error
+	switch p.Type {
+	case ParamTypeInt:
+		arg, err = strconv.ParseInt(p.Value, 10, 64)
+	case ParamTypeFloat:
+		arg, err = strconv.ParseFloat(p.Value, 64)
+	case ParamTypeBool:
+		arg, err = strconv.ParseBool(p.Value)
+	case ParamTypeDate, ParamTypeTimestamp:
+		arg, err = parseParamTime(p.Value)
+	default:
+		err = ValidateParamType(p.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value '%s' for param '%s'", p.Type, p.Value, p.Name)
+	}
+	return arg, nil
+}
+
+func parseParamTime(s string) (time.Time, error) {
+	for _, layout := range paramTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s'", s)
+}
+
+// Positional reports whether binds are bound by position rather than by name.
+// It errors when named and unnamed params are mixed
+func Positional(binds []BindParam) (bool, error) {
+	named := 0
+	for _, b := range binds {
+		if b.Name != "" {
+			named++
+		}
+	}
+	if named > 0 && named < len(binds) {
+		return false, errors.New("params must either all have a name or all be positional")
+	}
+	return len(binds) > 0 && named == 0, nil
+}
+
+// bindQuery rewrites query for the driver and returns its bind args. Named
+// params and the watermark query param replace their :name references;
+// positional params are passed in order and the query is left as is
+func bindQuery(query string, params map[string]string, binds []BindParam, style placeholderStyle) (string, []any, error) {
+	positional, err := Positional(binds)
+	if err != nil {
+		return "", nil, err
+	}
+
+	args := make([]any, 0, len(binds))
+	values := make(map[string]any)
+	for _, b := range binds {
+		arg, err := b.Arg()
+		if err != nil {
+			return "", nil, err
+		}
+		if positional {
+			args = append(args, arg)
+			continue
+		}
+		values[b.Name] = arg
+	}
+	if positional {
+		return query, args, nil
+	}
+
+	if v, ok := params[WatermarkParam]; ok {
+		if _, declared := values[WatermarkParam]; !declared {
+			values[WatermarkParam] = v
+		}
+	}
+	query, args = bindNamed(query, values, style)
+	return query, args, nil
+}
+
+// bindNamed replaces each :name in query whose name is in values with a
+// driver placeholder and returns the rewritten query with its bind args.
+// Names inside string literals, quoted identifiers and comments, and
+// PostgreSQL :: casts, are left alone
+func bindNamed(query string, values map[string]any, style placeholderStyle) (string, []any) {
+	if len(values) == 0 {
+		return query, nil
+	}
+
+	var b strings.Builder
+	var args []any
+	numbers := make(map[string]int)
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			// MariaDB strings also escape a quote with a backslash
+			end := closingQuote(query, i, c, style == placeholderQuestion && c != '`')
+			b.WriteString(query[i:end])
+			i = end - 1
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			b.WriteString(query[i : i+end])
+			i += end - 1
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i
+			} else {
+				end += 4
+			}
+			b.WriteString(query[i : i+end])
+			i += end - 1
+		case c == ':' && i+1 < len(query) && query[i+1] == ':':
+			b.WriteString("::")
+			i++
+		case c == ':' && (i == 0 || query[i-1] != ':'):
+			end := i + 1
+			for end < len(query) && isIdentByte(query[end], end == i+1) {
+				end++
+			}
+			name := query[i+1 : end]
+			v, ok := values[name]
+			if name == "" || !ok {
+				b.WriteByte(c)
+				continue
+			}
+			if style == placeholderDollar {
+				n, seen := numbers[name]
+				if !seen {
+					args = append(args, v)
+					n = len(args)
+					numbers[name] = n
+				}
+				b.WriteString("$" + strconv.Itoa(n))
+			} else {
+				args = append(args, v)
+				b.WriteString("?")
+			}
+			i = end - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String(), args
+}
+
+// closingQuote returns the index just past the quoted section starting at
+// start, treating a doubled quote, and with backslash any character after a
+// backslash, as escaped
+func closingQuote(query string, start int, quote byte, backslash bool) int {
+	for i := start + 1; i < len(query); i++ {
+		if backslash && query[i] == '\\' {
+			i++
+			continue
+		}
+		if query[i] != quote {
+			continue
+		}
+		if i+1 < len(query) && query[i+1] == quote {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return len(query)
+}
+
+func isIdentByte(c byte, first bool) bool {
+	if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+		return true
+	}
+	return !first && c >= '0' && c <= '9'
+}
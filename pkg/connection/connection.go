@@ -1,12 +1,97 @@
 package connection
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"time"
+)
 
 type ConnectionProvider interface {
 	Authenticate() error
 	FetchReport(params map[string]string) ([]map[string]string, error)
 }
 
+// Result is a fetched report with its column order preserved
+type Result struct {
+	Columns []string
+	Rows    []map[string]string
+}
+
+// ResultFetcher is implemented by connections that know the column order
+// of the data they return
+type ResultFetcher interface {
+	FetchResult(params map[string]string) (*Result, error)
+}
+
+// Fetch runs a report against conn. Connections that implement ResultFetcher
+// supply their own column order; for the rest the keys of the first row are
+// sorted so output files have a stable header
+func Fetch(conn ConnectionProvider, params map[string]string) (*Result, error) {
+	start := time.Now()
+	result, err := fetch(conn, params)
+	Observe(Type(conn), "fetch", start, err)
+	return result, err
+}
+
+func fetch(conn ConnectionProvider, params map[string]string) (*Result, error) {
+	if rf, ok := conn.(ResultFetcher); ok {
+		return rf.FetchResult(params)
+	}
+
+	rows, err := conn.FetchReport(params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Rows: rows}
+	if len(rows) > 0 {
+		for key := range rows[0] {
+			result.Columns = append(result.Columns, key)
+		}
+		sort.Strings(result.Columns)
+	}
+
+	return result, nil
+}
+
+// BindFetcher is implemented by SQL connections that send bind parameters
+// to the driver along with the query
+type BindFetcher interface {
+	FetchBound(params map[string]string, binds []BindParam) (*Result, error)
+}
+
+// FetchBound runs a report against conn with bind parameters. Without binds
+// it is the same as Fetch
+func FetchBound(conn ConnectionProvider, params map[string]string, binds []BindParam) (*Result, error) {
+	if len(binds) == 0 {
+		return Fetch(conn, params)
+	}
+	bf, ok := conn.(BindFetcher)
+	if !ok {
+		return nil, fmt.Errorf("connection %T does not support bind params", conn)
+	}
+	start := time.Now()
+	result, err := bf.FetchBound(params, binds)
+	Observe(Type(conn), "fetch", start, err)
+	return result, err
+}
+
+// RowWriter is implemented by connections that can load rows into a table
+type RowWriter interface {
+	InsertRows(table string, columns []string, rows []map[string]string, truncate bool) error
+}
+
+// insertBatchSize caps how many rows go into a single INSERT statement
+const insertBatchSize = 500
+
+// nullIfEmpty turns blank cells into NULL so they can load into typed columns
+func nullIfEmpty(v string) any {
+	if v == "" {
+		return nil
+	}
+	return v
+}
+
 type AuthService[T ConnectionProvider] struct {
 	Provider T
 	Name     string `yaml:"name"`
@@ -9,6 +9,7 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -20,6 +21,53 @@ type FolioAuth struct {
 	Password string
 	Token    string
 	Client   *http.Client
+	log      *slog.Logger
+	// shared is the connection a WithLogger copy uses the session of
+	shared *FolioAuth
+	mu     sync.Mutex
+}
+
+// WithLogger returns a copy of the connection that logs to log and uses the
+// original's client and token
+func (f *FolioAuth) WithLogger(log *slog.Logger) ConnectionProvider {
+	return &FolioAuth{
+		BaseURL:  f.BaseURL,
+		Tenant:   f.Tenant,
+		Username: f.Username,
+		Password: f.Password,
+		log:      log,
+		shared:   f.owner(),
+	}
+}
+
+func (f *FolioAuth) owner() *FolioAuth {
+	if f.shared != nil {
+		return f.shared
+	}
+	return f
+}
+
+// session returns the client and token, logging in on first use. Runs share
+// one session however many copies of the connection they log through
+func (f *FolioAuth) session() (*http.Client, string, error) {
+	o := f.owner()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	// Initialize client if not set
+	if o.Client == nil {
+		o.Client = &http.Client{
+			Timeout: 60 * time.Second,
+		}
+	}
+	// Authenticate if token is not set
+	if o.Token == "" {
+		err := o.Authenticate()
+		if err != nil {
+			logger(f.log).Warn("Unable to authenticate to FOLIO")
+			return nil, "", err
+		}
+	}
+	return o.Client, o.Token, nil
 }
 
 // loginRequest represents the FOLIO login payload
@@ -97,26 +145,15 @@ func (f *FolioAuth) Authenticate() error {
 	}
 
 	f.Token = token
-	slog.Debug("FOLIO authentication successful", "tenant", f.Tenant)
+	logger(f.log).Debug("FOLIO authentication successful", "tenant", f.Tenant)
 	return nil
 }
 
 // FetchReport executes a SQL query from a GitHub URL and returns results as CSV data
 func (f *FolioAuth) FetchReport(params map[string]string) ([]map[string]string, error) {
-	// Initialize client if not set
-	if f.Client == nil {
-		f.Client = &http.Client{
-			Timeout: 60 * time.Second,
-		}
-	}
-
-	// Authenticate if token is not set
-	if f.Token == "" {
-		err := f.Authenticate()
-		if err != nil {
-			slog.Warn("Unable to authenticate to FOLIO")
-			return nil, err
-		}
+	client, token, err := f.session()
+	if err != nil {
+		return nil, err
 	}
 
 	queryURL, ok := params["query_url"]
@@ -150,9 +187,9 @@ func (f *FolioAuth) FetchReport(params map[string]string) ([]map[string]string,
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("x-okapi-tenant", f.Tenant)
-	req.Header.Set("x-okapi-token", f.Token)
+	req.Header.Set("x-okapi-token", token)
 
-	resp, err := f.Client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute report request: %w", err)
 	}
@@ -190,6 +227,6 @@ func (f *FolioAuth) FetchReport(params map[string]string) ([]map[string]string,
 		results[i] = rowMap
 	}
 
-	slog.Debug("FOLIO report fetched successfully", "rows", len(results))
+	logger(f.log).Debug("FOLIO report fetched successfully", "rows", len(results))
 	return results, nil
 }
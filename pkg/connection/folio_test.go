@@ -2,9 +2,12 @@ package connection_test
 
 import (
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/lehigh-university-libraries/encode/pkg/connection"
@@ -256,3 +259,40 @@ func TestFolioAuth_FetchReport(t *testing.T) {
 		})
 	}
 }
+
+func TestFolioAuth_WithLogger(t *testing.T) {
+	var logins atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/authn/login" {
+			logins.Add(1)
+			w.Header().Set("x-okapi-token", "test-token-123")
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		if r.Header.Get("x-okapi-token") != "test-token-123" {
+			t.Errorf("Expected token header, got %s", r.Header.Get("x-okapi-token"))
+		}
+		_, _ = w.Write([]byte(`{"totalRecords":0,"records":[]}`))
+	}))
+	defer server.Close()
+
+	auth := &connection.FolioAuth{BaseURL: server.URL, Tenant: "t", Username: "u", Password: "p"}
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			conn := connection.WithLogger[connection.ConnectionProvider](auth, slog.Default())
+			_, err := conn.FetchReport(map[string]string{"query_url": "https://example.com/query.sql"})
+			if err != nil {
+				t.Errorf("FetchReport() failed: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	if n := logins.Load(); n != 1 {
+		t.Errorf("Expected copies to share one login, got %d", n)
+	}
+	if auth.Token != "test-token-123" {
+		t.Errorf("Expected the original to keep the token, got %q", auth.Token)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"strconv"
 	"strings"
+	"sync"
 
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
@@ -15,6 +16,39 @@ import (
 type GoogleSheetsAuth struct {
 	CredentialsFile string
 	Service         *sheets.Service
+	log             *slog.Logger
+	// shared is the connection a WithLogger copy uses the client of
+	shared *GoogleSheetsAuth
+	mu     sync.Mutex
+}
+
+// WithLogger returns a copy of the connection that logs to log and uses the
+// original's client
+func (g *GoogleSheetsAuth) WithLogger(log *slog.Logger) ConnectionProvider {
+	return &GoogleSheetsAuth{CredentialsFile: g.CredentialsFile, log: log, shared: g.owner()}
+}
+
+func (g *GoogleSheetsAuth) owner() *GoogleSheetsAuth {
+	if g.shared != nil {
+		return g.shared
+	}
+	return g
+}
+
+// service returns the Sheets client, creating it on first use. Runs share
+// one client however many copies of the connection they log through
+func (g *GoogleSheetsAuth) service() (*sheets.Service, error) {
+	o := g.owner()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	// Only authenticate if Service is not already set (e.g., for testing with mocks)
+	if o.Service == nil {
+		err := o.Authenticate()
+		if err != nil {
+			return nil, fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+	return o.Service, nil
 }
 
 func (g *GoogleSheetsAuth) Authenticate() error {
@@ -33,12 +67,18 @@ func (g *GoogleSheetsAuth) Authenticate() error {
 }
 
 func (g *GoogleSheetsAuth) FetchReport(params map[string]string) ([]map[string]string, error) {
-	// Only authenticate if Service is not already set (e.g., for testing with mocks)
-	if g.Service == nil {
-		err := g.Authenticate()
-		if err != nil {
-			return nil, fmt.Errorf("failed to authenticate: %w", err)
-		}
+	result, err := g.FetchResult(params)
+	if err != nil {
+		return nil, err
+	}
+	return result.Rows, nil
+}
+
+// FetchResult reads and merges the requested tabs, keeping the merged column order
+func (g *GoogleSheetsAuth) FetchResult(params map[string]string) (*Result, error) {
+	svc, err := g.service()
+	if err != nil {
+		return nil, err
 	}
 
 	spreadsheetID, ok := params["spreadsheet_id"]
@@ -70,11 +110,27 @@ func (g *GoogleSheetsAuth) FetchReport(params map[string]string) ([]map[string]s
 		headerRow = parsed
 	}
 
+	opts := SheetMergeOptions{
+		SheetColumn: DefaultSheetColumn,
+		HeaderMode:  HeaderModeUnion,
+	}
+	if sc, ok := params["sheet_column"]; ok {
+		opts.SheetColumn = sc
+	}
+	if hm, ok := params["header_mode"]; ok {
+		switch hm {
+		case HeaderModeUnion, HeaderModeStrict:
+			opts.HeaderMode = hm
+		default:
+			return nil, fmt.Errorf("invalid header_mode value '%s': must be %q or %q", hm, HeaderModeUnion, HeaderModeStrict)
+		}
+	}
+
 	// Data starts at the row after the header
 	dataStartRow := headerRow + 1
 
 	// Get spreadsheet metadata to map GIDs to sheet names
-	spreadsheet, err := g.Service.Spreadsheets.Get(spreadsheetID).Do()
+	spreadsheet, err := svc.Spreadsheets.Get(spreadsheetID).Do()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get spreadsheet: %w", err)
 	}
@@ -87,7 +143,7 @@ func (g *GoogleSheetsAuth) FetchReport(params map[string]string) ([]map[string]s
 
 	// Fetch data from each sheet
 	var sheetDataList []SheetData
-	for i, gidStr := range gidStrings {
+	for _, gidStr := range gidStrings {
 		gidStr = strings.TrimSpace(gidStr)
 		gidInt, err := strconv.ParseInt(gidStr, 10, 64)
 		if err != nil {
@@ -99,28 +155,26 @@ func (g *GoogleSheetsAuth) FetchReport(params map[string]string) ([]map[string]s
 			return nil, fmt.Errorf("sheet with gid %s not found", gidStr)
 		}
 
-		slog.Debug("Fetching data from sheet", "gid", gidStr, "name", sheetName)
+		logger(g.log).Debug("Fetching data from sheet", "gid", gidStr, "name", sheetName)
 
 		sheetData := SheetData{Name: sheetName}
 
-		// For the first GID (index 0), read the header row
-		if i == 0 {
-			headerRange := fmt.Sprintf("%s!A%d:ZZ%d", sheetName, headerRow, headerRow)
-			headerResp, err := g.Service.Spreadsheets.Values.Get(spreadsheetID, headerRange).Do()
-			if err != nil {
-				return nil, fmt.Errorf("failed to read header row from sheet '%s': %w", sheetName, err)
-			}
-
-			if len(headerResp.Values) == 0 || len(headerResp.Values[0]) == 0 {
-				return nil, fmt.Errorf("header row is empty in sheet '%s'", sheetName)
-			}
+		// Every tab has its own header row so columns can be aligned by name
+		headerRange := fmt.Sprintf("%s!A%d:ZZ%d", sheetName, headerRow, headerRow)
+		headerResp, err := svc.Spreadsheets.Values.Get(spreadsheetID, headerRange).Do()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read header row from sheet '%s': %w", sheetName, err)
+		}
 
-			sheetData.Header = headerResp.Values[0]
+		if len(headerResp.Values) == 0 || len(headerResp.Values[0]) == 0 {
+			return nil, fmt.Errorf("header row is empty in sheet '%s'", sheetName)
 		}
 
+		sheetData.Header = headerResp.Values[0]
+
 		// Read data from this sheet
 		dataRange := fmt.Sprintf("%s!A%d:ZZ10000", sheetName, dataStartRow)
-		dataResp, err := g.Service.Spreadsheets.Values.Get(spreadsheetID, dataRange).Do()
+		dataResp, err := svc.Spreadsheets.Values.Get(spreadsheetID, dataRange).Do()
 		if err != nil {
 			return nil, fmt.Errorf("failed to read data from sheet '%s': %w", sheetName, err)
 		}
@@ -130,7 +184,13 @@ func (g *GoogleSheetsAuth) FetchReport(params map[string]string) ([]map[string]s
 	}
 
 	// Parse the fetched data
-	return ParseSheetData(sheetDataList, headerRow)
+	opts.Log = g.log
+	columns, results, err := MergeSheetData(sheetDataList, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{Columns: columns, Rows: results}, nil
 }
 
 // SheetData represents data fetched from a single sheet
@@ -140,86 +200,240 @@ type SheetData struct {
 	Rows   [][]interface{}
 }
 
+const (
+	// DefaultSheetColumn is the column that records which tab a row came from
+	DefaultSheetColumn = "sheet"
+
+	// HeaderModeUnion aligns tabs by header name and keeps every column seen in any tab
+	HeaderModeUnion = "union"
+	// HeaderModeStrict aligns tabs by header name and fails if any tab has a different set of columns
+	HeaderModeStrict = "strict"
+)
+
+// SheetMergeOptions controls how multiple tabs are combined into one result
+type SheetMergeOptions struct {
+	// SheetColumn is the name of the column holding the tab name. Empty disables the column.
+	SheetColumn string
+	// HeaderMode is HeaderModeUnion or HeaderModeStrict
+	HeaderMode string
+	// AllRows reads every row, skipping only empty ones, instead of stopping
+	// at the first row whose column A is blank
+	AllRows bool
+	// Log is where merge progress is logged; nil uses the default logger
+	Log *slog.Logger
+}
+
 // ParseSheetData converts raw Google Sheets API responses into structured data
-// This function is separated for easier testing
+// using the default merge options. This function is separated for easier testing
 func ParseSheetData(sheets []SheetData, headerRow int) ([]map[string]string, error) {
+	_, results, err := MergeSheetData(sheets, SheetMergeOptions{
+		SheetColumn: DefaultSheetColumn,
+		HeaderMode:  HeaderModeUnion,
+	})
+	return results, err
+}
+
+// MergeSheetData combines the rows of several tabs, aligning each tab's columns
+// by header name. It returns the merged column order along with the rows.
+// Columns appear in the order they are first seen, followed by the sheet column.
+// A tab without its own header reuses the header of the tab before it.
+func MergeSheetData(sheets []SheetData, opts SheetMergeOptions) ([]string, []map[string]string, error) {
 	if len(sheets) == 0 {
-		return nil, errors.New("no sheets provided")
+		return nil, nil, errors.New("no sheets provided")
 	}
 
+	var columns []string
+	seen := make(map[string]bool)
+	var firstTab []string
 	var headers []string
 	var allResults []map[string]string
+	// Tabs are aligned by header name when there are several. A single tab
+	// keeps its header row as written, as it always has
+	byName := len(sheets) > 1
 
 	// Process each sheet
 	for i, sheet := range sheets {
-		slog.Debug("Processing sheet", "name", sheet.Name, "index", i)
+		logger(opts.Log).Debug("Processing sheet", "name", sheet.Name, "index", i)
 
-		// For the first sheet, extract headers
-		if i == 0 {
+		if i == 0 || sheet.Header != nil {
 			if len(sheet.Header) == 0 {
-				return nil, fmt.Errorf("header row is empty in sheet '%s'", sheet.Name)
+				return nil, nil, fmt.Errorf("header row is empty in sheet '%s'", sheet.Name)
 			}
 
-			headers = make([]string, len(sheet.Header))
-			for j, v := range sheet.Header {
-				if str, ok := v.(string); ok {
-					headers[j] = str
-				} else {
-					headers[j] = fmt.Sprintf("%v", v)
-				}
+			var err error
+			headers, err = sheetHeaders(sheet, opts.SheetColumn, byName, opts.Log)
+			if err != nil {
+				return nil, nil, err
 			}
 
-			// Add "sheet" column to headers
-			headers = append(headers, "sheet")
+			logger(opts.Log).Debug("Extracted headers from sheet", "name", sheet.Name, "headers", headers)
+		}
 
-			slog.Debug("Extracted headers from first sheet", "headers", headers)
+		var named []string
+		if byName {
+			named = nonEmpty(headers)
+		} else {
+			named = distinct(headers, opts.SheetColumn)
+		}
+		if i == 0 {
+			firstTab = named
+		} else if opts.HeaderMode == HeaderModeStrict && !sameColumns(firstTab, named) {
+			return nil, nil, fmt.Errorf("columns in sheet '%s' %v do not match columns in sheet '%s' %v", sheet.Name, named, sheets[0].Name, firstTab)
+		}
+		for _, h := range named {
+			if !seen[h] {
+				seen[h] = true
+				columns = append(columns, h)
+			}
 		}
 
 		// Process rows
 		for _, row := range sheet.Rows {
-			// Check if column A (index 0) is blank or empty
-			if len(row) == 0 {
-				break
-			}
-
-			firstCell := ""
-			if row[0] != nil {
-				if str, ok := row[0].(string); ok {
-					firstCell = str
-				} else {
-					firstCell = fmt.Sprintf("%v", row[0])
+			if opts.AllRows {
+				if emptyRow(row) {
+					continue
 				}
-			}
-
-			// Stop when column A is blank
-			if firstCell == "" {
+			} else if len(row) == 0 || cellString(row[0]) == "" {
+				// Stop when column A is blank
 				break
 			}
 
-			// Map row to headers (excluding the "sheet" column)
 			rowMap := make(map[string]string)
-			for j, header := range headers[:len(headers)-1] { // Skip last "sheet" header
-				if j < len(row) && row[j] != nil {
-					if str, ok := row[j].(string); ok {
-						rowMap[header] = str
-					} else {
-						rowMap[header] = fmt.Sprintf("%v", row[j])
-					}
+			for j, header := range headers {
+				if header == "" && byName {
+					continue
+				}
+				if j < len(row) {
+					rowMap[header] = cellString(row[j])
 				} else {
 					rowMap[header] = ""
 				}
 			}
 
-			// Add sheet name column
-			rowMap["sheet"] = sheet.Name
+			if opts.SheetColumn != "" {
+				rowMap[opts.SheetColumn] = sheet.Name
+			}
 
 			allResults = append(allResults, rowMap)
 		}
 
-		slog.Debug("Processed sheet", "name", sheet.Name, "rows", len(sheet.Rows))
+		logger(opts.Log).Debug("Processed sheet", "name", sheet.Name, "rows", len(sheet.Rows))
+	}
+
+	// Backfill columns a row's tab did not have so every row has the same keys
+	for _, rowMap := range allResults {
+		for _, c := range columns {
+			if _, ok := rowMap[c]; !ok {
+				rowMap[c] = ""
+			}
+		}
+	}
+
+	if opts.SheetColumn != "" {
+		columns = append(columns, opts.SheetColumn)
+	}
+
+	logger(opts.Log).Info("Merged data from multiple sheets", "total_sheets", len(sheets), "total_rows", len(allResults), "columns", len(columns))
+
+	return columns, allResults, nil
+}
+
+func emptyRow(row []any) bool {
+	for _, v := range row {
+		if cellString(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// sheetHeaders converts a tab's header row to strings. With byName it
+// rejects names that would make aligning columns by name ambiguous; otherwise
+// they are only logged, and a duplicate column keeps its last value
+func sheetHeaders(sheet SheetData, sheetColumn string, byName bool, log *slog.Logger) ([]string, error) {
+	headers := make([]string, len(sheet.Header))
+	if !byName {
+		seen := make(map[string]bool)
+		for j, v := range sheet.Header {
+			h := cellString(v)
+			switch {
+			case seen[h]:
+				logger(log).Warn("Duplicate column in header; the last one's values are kept", "sheet", sheet.Name, "column", h)
+			case h != "" && h == sheetColumn:
+				logger(log).Warn("Column is replaced by the sheet column; set sheet_column to keep it", "sheet", sheet.Name, "column", h)
+			}
+			seen[h] = true
+			headers[j] = h
+		}
+		return headers, nil
+	}
+
+	dupes := make(map[string]bool)
+	for j, v := range sheet.Header {
+		h := strings.TrimSpace(cellString(v))
+		if h == "" {
+			logger(log).Debug("Skipping column with blank header", "sheet", sheet.Name, "column", j+1)
+			continue
+		}
+		if dupes[h] {
+			return nil, fmt.Errorf("duplicate column '%s' in header of sheet '%s'", h, sheet.Name)
+		}
+		if h == sheetColumn {
+			return nil, fmt.Errorf("column '%s' in sheet '%s' conflicts with the sheet column; set sheet_column to another name", h, sheet.Name)
+		}
+		dupes[h] = true
+		headers[j] = h
 	}
 
-	slog.Info("Merged data from multiple sheets", "total_sheets", len(sheets), "total_rows", len(allResults))
+	return headers, nil
+}
+
+func nonEmpty(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
 
-	return allResults, nil
+// distinct returns values without repeats, leaving out the sheet column
+func distinct(values []string, sheetColumn string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, v := range values {
+		if seen[v] || (sheetColumn != "" && v == sheetColumn) {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	return out
+}
+
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]bool, len(a))
+	for _, v := range a {
+		set[v] = true
+	}
+	for _, v := range b {
+		if !set[v] {
+			return false
+		}
+	}
+	return true
+}
+
+func cellString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	if str, ok := v.(string); ok {
+		return str
+	}
+	return fmt.Sprintf("%v", v)
 }
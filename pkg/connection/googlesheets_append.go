@@ -0,0 +1,205 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// SheetTarget identifies the tab a report is appended to
+type SheetTarget struct {
+	SpreadsheetID string
+	// GID selects the tab by grid ID; Tab selects it by title. One is required.
+	GID string
+	Tab string
+	// HeaderRow is the 1-indexed row holding column headers (default: 1)
+	HeaderRow int
+	// ValueInputOption is passed to the Sheets API (default: RAW)
+	ValueInputOption string
+}
+
+// AppendRows appends rows to the target tab. When the tab is empty the header
+// row is written first. Otherwise every existing header column must still be
+// present in columns and in the same order; columns the tab does not have yet
+// are added to the end of the header row
+func (g *GoogleSheetsAuth) AppendRows(target SheetTarget, columns []string, rows []map[string]string) error {
+	svc, err := g.service()
+	if err != nil {
+		return err
+	}
+
+	tab, headerRow, err := resolveTarget(svc, target)
+	if err != nil {
+		return err
+	}
+
+	headerRange := fmt.Sprintf("%s!A%d:ZZ%d", tab, headerRow, headerRow)
+	headerResp, err := svc.Spreadsheets.Values.Get(target.SpreadsheetID, headerRange).Do()
+	if err != nil {
+		return fmt.Errorf("failed to read header row from sheet '%s': %w", tab, err)
+	}
+
+	var existing []string
+	if len(headerResp.Values) > 0 {
+		for _, v := range headerResp.Values[0] {
+			existing = append(existing, cellString(v))
+		}
+	}
+
+	header, err := ReconcileHeader(existing, columns)
+	if err != nil {
+		return fmt.Errorf("sheet '%s': %w", tab, err)
+	}
+
+	valueInput := target.ValueInputOption
+	if valueInput == "" {
+		valueInput = "RAW"
+	}
+
+	if len(header) != len(existing) {
+		logger(g.log).Info("Updating sheet header", "sheet", tab, "old", existing, "new", header)
+		row := make([]interface{}, len(header))
+		for i, h := range header {
+			row[i] = h
+		}
+		_, err = svc.Spreadsheets.Values.Update(target.SpreadsheetID, fmt.Sprintf("%s!A%d", tab, headerRow), &sheets.ValueRange{
+			Values: [][]interface{}{row},
+		}).ValueInputOption("RAW").Do()
+		if err != nil {
+			return fmt.Errorf("failed to write header row to sheet '%s': %w", tab, err)
+		}
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+
+	values := make([][]interface{}, len(rows))
+	for i, r := range rows {
+		record := make([]interface{}, len(header))
+		for j, h := range header {
+			record[j] = r[h]
+		}
+		values[i] = record
+	}
+
+	_, err = svc.Spreadsheets.Values.Append(target.SpreadsheetID, fmt.Sprintf("%s!A%d", tab, headerRow), &sheets.ValueRange{
+		Values: values,
+	}).ValueInputOption(valueInput).InsertDataOption("INSERT_ROWS").Do()
+	if err != nil {
+		return fmt.Errorf("failed to append rows to sheet '%s': %w", tab, err)
+	}
+
+	logger(g.log).Info("Appended rows to Google Sheet", "spreadsheet", target.SpreadsheetID, "sheet", tab, "rows", len(rows))
+	return nil
+}
+
+// ExportSheet reads every row of the target tab, skipping only empty rows
+func (g *GoogleSheetsAuth) ExportSheet(target SheetTarget) (*Result, error) {
+	svc, err := g.service()
+	if err != nil {
+		return nil, err
+	}
+
+	tab, headerRow, err := resolveTarget(svc, target)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := svc.Spreadsheets.Values.Get(target.SpreadsheetID, fmt.Sprintf("%s!A%d:ZZ", tab, headerRow)).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sheet '%s': %w", tab, err)
+	}
+	if len(resp.Values) == 0 {
+		return nil, fmt.Errorf("header row is empty in sheet '%s'", tab)
+	}
+
+	columns, rows, err := MergeSheetData([]SheetData{
+		{Name: tab, Header: resp.Values[0], Rows: resp.Values[1:]},
+	}, SheetMergeOptions{HeaderMode: HeaderModeStrict, AllRows: true, Log: g.log})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{Columns: columns, Rows: rows}, nil
+}
+
+// ReconcileHeader checks incoming columns against the header already in a
+// sheet. Every existing column must still be present and in the same relative
+// order. The returned header is the existing one with any new columns appended
+func ReconcileHeader(existing, incoming []string) ([]string, error) {
+	if len(existing) == 0 {
+		return incoming, nil
+	}
+
+	position := make(map[string]int, len(incoming))
+	for i, c := range incoming {
+		position[c] = i
+	}
+
+	last := -1
+	known := make(map[string]bool, len(existing))
+	for _, c := range existing {
+		known[c] = true
+		if c == "" {
+			continue
+		}
+		p, ok := position[c]
+		if !ok {
+			return nil, fmt.Errorf("existing column '%s' is missing from the new data", c)
+		}
+		if p < last {
+			return nil, fmt.Errorf("existing column '%s' is out of order in the new data", c)
+		}
+		last = p
+	}
+
+	header := append([]string{}, existing...)
+	for _, c := range incoming {
+		if !known[c] {
+			header = append(header, c)
+		}
+	}
+
+	return header, nil
+}
+
+func resolveTarget(svc *sheets.Service, target SheetTarget) (string, int, error) {
+	if target.SpreadsheetID == "" {
+		return "", 0, errors.New("missing spreadsheet_id")
+	}
+
+	headerRow := target.HeaderRow
+	if headerRow == 0 {
+		headerRow = 1
+	}
+	if headerRow < 1 {
+		return "", 0, errors.New("header_row must be >= 1")
+	}
+
+	if target.Tab != "" {
+		return target.Tab, headerRow, nil
+	}
+	if target.GID == "" {
+		return "", 0, errors.New("missing gid or tab")
+	}
+
+	gid, err := strconv.ParseInt(target.GID, 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid gid format '%s': %w", target.GID, err)
+	}
+
+	spreadsheet, err := svc.Spreadsheets.Get(target.SpreadsheetID).Do()
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get spreadsheet: %w", err)
+	}
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties.SheetId == gid {
+			return sheet.Properties.Title, headerRow, nil
+		}
+	}
+
+	return "", 0, fmt.Errorf("sheet with gid %s not found", target.GID)
+}
@@ -0,0 +1,215 @@
+package connection_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/lehigh-university-libraries/encode/pkg/connection"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+// fakeSheet is an in-memory stand-in for the Sheets values API
+type fakeSheet struct {
+	title string
+	gid   int64
+	cells [][]string
+}
+
+var rangePattern = regexp.MustCompile(`^(.+)!A(\d+)(?::ZZ(\d*))?$`)
+
+func (f *fakeSheet) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, rest, ok := strings.Cut(r.URL.Path, "/values/")
+		if !ok {
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"sheets": []any{map[string]any{"properties": map[string]any{"sheetId": f.gid, "title": f.title}}},
+			})
+			return
+		}
+
+		rng := strings.TrimSuffix(rest, ":append")
+		m := rangePattern.FindStringSubmatch(rng)
+		if m == nil || m[1] != f.title {
+			t.Errorf("unexpected range %q", rng)
+			http.Error(w, "bad range", http.StatusBadRequest)
+			return
+		}
+		start, _ := strconv.Atoi(m[2])
+
+		switch {
+		case r.Method == http.MethodGet:
+			end := len(f.cells)
+			if m[3] != "" {
+				end, _ = strconv.Atoi(m[3])
+			}
+			var values [][]string
+			for i := start - 1; i < end && i < len(f.cells); i++ {
+				values = append(values, f.cells[i])
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"range": rng, "values": values})
+		case strings.HasSuffix(rest, ":append"):
+			var vr sheets.ValueRange
+			_ = json.NewDecoder(r.Body).Decode(&vr)
+			for _, row := range vr.Values {
+				f.cells = append(f.cells, toStrings(row))
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{})
+		case r.Method == http.MethodPut:
+			var vr sheets.ValueRange
+			_ = json.NewDecoder(r.Body).Decode(&vr)
+			for len(f.cells) < start {
+				f.cells = append(f.cells, nil)
+			}
+			f.cells[start-1] = toStrings(vr.Values[0])
+			_ = json.NewEncoder(w).Encode(map[string]any{})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}
+}
+
+func toStrings(row []interface{}) []string {
+	out := make([]string, len(row))
+	for i, v := range row {
+		out[i], _ = v.(string)
+	}
+	return out
+}
+
+func newFakeSheetsAuth(t *testing.T, f *fakeSheet) *connection.GoogleSheetsAuth {
+	t.Helper()
+	server := httptest.NewServer(f.handler(t))
+	t.Cleanup(server.Close)
+
+	svc, err := sheets.NewService(context.Background(),
+		option.WithEndpoint(server.URL+"/"),
+		option.WithHTTPClient(server.Client()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create sheets service: %v", err)
+	}
+	return &connection.GoogleSheetsAuth{Service: svc}
+}
+
+func TestGoogleSheetsAuth_AppendRows(t *testing.T) {
+	tests := []struct {
+		name          string
+		cells         [][]string
+		columns       []string
+		rows          []map[string]string
+		expectError   bool
+		expectedCells [][]string
+	}{
+		{
+			name:    "Empty tab gets a header",
+			columns: []string{"date", "count"},
+			rows:    []map[string]string{{"date": "2024-01-01", "count": "5"}},
+			expectedCells: [][]string{
+				{"date", "count"},
+				{"2024-01-01", "5"},
+			},
+		},
+		{
+			name:    "Rows are aligned to the existing header",
+			cells:   [][]string{{"date", "count"}, {"2024-01-01", "5"}},
+			columns: []string{"date", "count"},
+			rows:    []map[string]string{{"date": "2024-01-02", "count": "7"}},
+			expectedCells: [][]string{
+				{"date", "count"},
+				{"2024-01-01", "5"},
+				{"2024-01-02", "7"},
+			},
+		},
+		{
+			name:    "New columns are added to the end of the header",
+			cells:   [][]string{{"date", "count"}, {"2024-01-01", "5"}},
+			columns: []string{"date", "branch", "count"},
+			rows:    []map[string]string{{"date": "2024-01-02", "branch": "Fairchild", "count": "7"}},
+			expectedCells: [][]string{
+				{"date", "count", "branch"},
+				{"2024-01-01", "5"},
+				{"2024-01-02", "7", "Fairchild"},
+			},
+		},
+		{
+			name:        "Missing existing column halts the append",
+			cells:       [][]string{{"date", "count"}, {"2024-01-01", "5"}},
+			columns:     []string{"date"},
+			rows:        []map[string]string{{"date": "2024-01-02"}},
+			expectError: true,
+			expectedCells: [][]string{
+				{"date", "count"},
+				{"2024-01-01", "5"},
+			},
+		},
+		{
+			name:        "Reordered existing columns halt the append",
+			cells:       [][]string{{"date", "count"}, {"2024-01-01", "5"}},
+			columns:     []string{"count", "date"},
+			rows:        []map[string]string{{"date": "2024-01-02", "count": "7"}},
+			expectError: true,
+			expectedCells: [][]string{
+				{"date", "count"},
+				{"2024-01-01", "5"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeSheet{title: "Data", gid: 42, cells: tt.cells}
+			auth := newFakeSheetsAuth(t, f)
+
+			err := auth.AppendRows(connection.SheetTarget{SpreadsheetID: "abc", GID: "42"}, tt.columns, tt.rows)
+			if tt.expectError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Fatalf("AppendRows() failed: %v", err)
+			}
+
+			if !reflect.DeepEqual(tt.expectedCells, f.cells) {
+				t.Errorf("Expected cells:\n%v\nGot:\n%v", tt.expectedCells, f.cells)
+			}
+		})
+	}
+}
+
+func TestGoogleSheetsAuth_ExportSheet(t *testing.T) {
+	f := &fakeSheet{title: "Data", gid: 0, cells: [][]string{
+		{"date", "count"},
+		{"2024-01-01", "5"},
+		{"", "6"},
+		{},
+		{"", ""},
+		{"2024-01-02", "7"},
+	}}
+	auth := newFakeSheetsAuth(t, f)
+
+	result, err := auth.ExportSheet(connection.SheetTarget{SpreadsheetID: "abc", Tab: "Data"})
+	if err != nil {
+		t.Fatalf("ExportSheet() failed: %v", err)
+	}
+
+	expected := &connection.Result{
+		Columns: []string{"date", "count"},
+		Rows: []map[string]string{
+			{"date": "2024-01-01", "count": "5"},
+			{"date": "", "count": "6"},
+			{"date": "2024-01-02", "count": "7"},
+		},
+	}
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
@@ -289,6 +289,200 @@ func TestParseSheetData_ErrorCases(t *testing.T) {
 	}
 }
 
+func TestMergeSheetData_DifferingHeaders(t *testing.T) {
+	tests := []struct {
+		name            string
+		sheets          []connection.SheetData
+		opts            connection.SheetMergeOptions
+		expectedColumns []string
+		expectedResults []map[string]string
+		expectError     bool
+	}{
+		{
+			name: "Reordered columns are aligned by name",
+			sheets: []connection.SheetData{
+				{
+					Name:   "Fall",
+					Header: []interface{}{"Date", "Type", "Count"},
+					Rows:   [][]interface{}{{"2024-09-01", "Class", "10"}},
+				},
+				{
+					Name:   "Spring",
+					Header: []interface{}{"Count", "Date", "Type"},
+					Rows:   [][]interface{}{{"20", "2025-02-01", "Tour"}},
+				},
+			},
+			opts:            connection.SheetMergeOptions{SheetColumn: "sheet", HeaderMode: connection.HeaderModeUnion},
+			expectedColumns: []string{"Date", "Type", "Count", "sheet"},
+			expectedResults: []map[string]string{
+				{"Date": "2024-09-01", "Type": "Class", "Count": "10", "sheet": "Fall"},
+				{"Date": "2025-02-01", "Type": "Tour", "Count": "20", "sheet": "Spring"},
+			},
+		},
+		{
+			name: "Added column produces a union",
+			sheets: []connection.SheetData{
+				{
+					Name:   "Fall",
+					Header: []interface{}{"Date", "Count"},
+					Rows:   [][]interface{}{{"2024-09-01", "10"}},
+				},
+				{
+					Name:   "Spring",
+					Header: []interface{}{"Date", "Location", "Count"},
+					Rows:   [][]interface{}{{"2025-02-01", "Linderman", "20"}},
+				},
+			},
+			opts:            connection.SheetMergeOptions{SheetColumn: "term", HeaderMode: connection.HeaderModeUnion},
+			expectedColumns: []string{"Date", "Count", "Location", "term"},
+			expectedResults: []map[string]string{
+				{"Date": "2024-09-01", "Count": "10", "Location": "", "term": "Fall"},
+				{"Date": "2025-02-01", "Location": "Linderman", "Count": "20", "term": "Spring"},
+			},
+		},
+		{
+			name: "Sheet column can be turned off",
+			sheets: []connection.SheetData{
+				{
+					Name:   "Fall",
+					Header: []interface{}{"Date", "Count"},
+					Rows:   [][]interface{}{{"2024-09-01", "10"}},
+				},
+			},
+			opts:            connection.SheetMergeOptions{HeaderMode: connection.HeaderModeUnion},
+			expectedColumns: []string{"Date", "Count"},
+			expectedResults: []map[string]string{
+				{"Date": "2024-09-01", "Count": "10"},
+			},
+		},
+		{
+			name: "Strict mode fails on mismatch",
+			sheets: []connection.SheetData{
+				{
+					Name:   "Fall",
+					Header: []interface{}{"Date", "Count"},
+					Rows:   [][]interface{}{{"2024-09-01", "10"}},
+				},
+				{
+					Name:   "Spring",
+					Header: []interface{}{"Date", "Location", "Count"},
+					Rows:   [][]interface{}{{"2025-02-01", "Linderman", "20"}},
+				},
+			},
+			opts:        connection.SheetMergeOptions{SheetColumn: "sheet", HeaderMode: connection.HeaderModeStrict},
+			expectError: true,
+		},
+		{
+			name: "Strict mode allows reordering",
+			sheets: []connection.SheetData{
+				{
+					Name:   "Fall",
+					Header: []interface{}{"Date", "Count"},
+					Rows:   [][]interface{}{{"2024-09-01", "10"}},
+				},
+				{
+					Name:   "Spring",
+					Header: []interface{}{"Count", "Date"},
+					Rows:   [][]interface{}{{"20", "2025-02-01"}},
+				},
+			},
+			opts:            connection.SheetMergeOptions{HeaderMode: connection.HeaderModeStrict},
+			expectedColumns: []string{"Date", "Count"},
+			expectedResults: []map[string]string{
+				{"Date": "2024-09-01", "Count": "10"},
+				{"Date": "2025-02-01", "Count": "20"},
+			},
+		},
+		{
+			name: "Duplicate header names are rejected across tabs",
+			sheets: []connection.SheetData{
+				{
+					Name:   "Fall",
+					Header: []interface{}{"Date", "Date"},
+					Rows:   [][]interface{}{{"2024-09-01", "2024-09-02"}},
+				},
+				{
+					Name:   "Spring",
+					Header: []interface{}{"Date"},
+					Rows:   [][]interface{}{{"2025-02-01"}},
+				},
+			},
+			opts:        connection.SheetMergeOptions{SheetColumn: "sheet", HeaderMode: connection.HeaderModeUnion},
+			expectError: true,
+		},
+		{
+			name: "Header conflicting with sheet column is rejected across tabs",
+			sheets: []connection.SheetData{
+				{
+					Name:   "Fall",
+					Header: []interface{}{"Date", "sheet"},
+					Rows:   [][]interface{}{{"2024-09-01", "x"}},
+				},
+				{
+					Name:   "Spring",
+					Header: []interface{}{"Date"},
+					Rows:   [][]interface{}{{"2025-02-01"}},
+				},
+			},
+			opts:        connection.SheetMergeOptions{SheetColumn: "sheet", HeaderMode: connection.HeaderModeUnion},
+			expectError: true,
+		},
+		{
+			name: "A single tab keeps the last of duplicate columns",
+			sheets: []connection.SheetData{
+				{
+					Name:   "Fall",
+					Header: []interface{}{"Date", "Count", "Date"},
+					Rows:   [][]interface{}{{"2024-09-01", "10", "2024-09-02"}},
+				},
+			},
+			opts:            connection.SheetMergeOptions{SheetColumn: "sheet", HeaderMode: connection.HeaderModeUnion},
+			expectedColumns: []string{"Date", "Count", "sheet"},
+			expectedResults: []map[string]string{
+				{"Date": "2024-09-02", "Count": "10", "sheet": "Fall"},
+			},
+		},
+		{
+			name: "A single tab keeps its header as written",
+			sheets: []connection.SheetData{
+				{
+					Name:   "Fall",
+					Header: []interface{}{" Date ", "", "sheet"},
+					Rows:   [][]interface{}{{"2024-09-01", "note", "x"}},
+				},
+			},
+			opts:            connection.SheetMergeOptions{SheetColumn: "sheet", HeaderMode: connection.HeaderModeUnion},
+			expectedColumns: []string{" Date ", "", "sheet"},
+			expectedResults: []map[string]string{
+				{" Date ": "2024-09-01", "": "note", "sheet": "Fall"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, results, err := connection.MergeSheetData(tt.sheets, tt.opts)
+
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MergeSheetData() failed: %v", err)
+			}
+
+			if !reflect.DeepEqual(tt.expectedColumns, columns) {
+				t.Errorf("Expected columns %v, got %v", tt.expectedColumns, columns)
+			}
+			if !reflect.DeepEqual(tt.expectedResults, results) {
+				t.Errorf("Expected:\n%v\nGot:\n%v", tt.expectedResults, results)
+			}
+		})
+	}
+}
+
 func TestGoogleSheetsAuth_MissingParameters(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -0,0 +1,32 @@
+package connection
+
+import "log/slog"
+
+// Logger is implemented by connections that log while they work, so a
+// report run can have their lines carry its run ID and report name
+type Logger interface {
+	// WithLogger returns a copy of the connection that logs to log. The
+	// copy shares the original's clients and pools
+	WithLogger(log *slog.Logger) ConnectionProvider
+}
+
+// WithLogger returns conn logging to log, or conn itself if it does not log
+func WithLogger[T any](conn T, log *slog.Logger) T {
+	l, ok := any(conn).(Logger)
+	if !ok || log == nil {
+		return conn
+	}
+	c, ok := l.WithLogger(log).(T)
+	if !ok {
+		return conn
+	}
+	return c
+}
+
+// logger is log, or the default logger if it is unset
+func logger(log *slog.Logger) *slog.Logger {
+	if log == nil {
+		return slog.Default()
+	}
+	return log
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"log/slog"
+	"strings"
+	"sync"
 
 	_ "github.com/go-sql-driver/mysql"
 )
@@ -14,9 +17,48 @@ type SqlQuerier interface {
 	Close() error
 }
 
+// sqlBeginner is satisfied by *sql.DB and lets InsertRows use a transaction
+type sqlBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
 type MariaDBAuth struct {
 	DSN string
 	DB  SqlQuerier
+	log *slog.Logger
+	// shared is the connection a WithLogger copy uses the pool of
+	shared *MariaDBAuth
+	mu     sync.Mutex
+}
+
+// WithLogger returns a copy of the connection that logs to log and uses the
+// original's pool
+func (m *MariaDBAuth) WithLogger(log *slog.Logger) ConnectionProvider {
+	return &MariaDBAuth{DSN: m.DSN, log: log, shared: m.owner()}
+}
+
+func (m *MariaDBAuth) owner() *MariaDBAuth {
+	if m.shared != nil {
+		return m.shared
+	}
+	return m
+}
+
+// pool returns the connection pool, opening it on first use. Runs share one
+// pool however many copies of the connection they log through
+func (m *MariaDBAuth) pool() (SqlQuerier, error) {
+	o := m.owner()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	// Only authenticate if DB is not already set (e.g., for testing with mocks)
+	if o.DB == nil {
+		err := o.Authenticate()
+		if err != nil {
+			logger(m.log).Warn("Unable to authenticate")
+			return nil, err
+		}
+	}
+	return o.DB, nil
 }
 
 func (m *MariaDBAuth) Authenticate() error {
@@ -31,6 +73,7 @@ func (m *MariaDBAuth) Authenticate() error {
 
 	// Verify connection
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return err
 	}
 
@@ -40,16 +83,26 @@ func (m *MariaDBAuth) Authenticate() error {
 
 // FetchReport executes a SQL query and returns results
 func (m *MariaDBAuth) FetchReport(params map[string]string) ([]map[string]string, error) {
-	// Only authenticate if DB is not already set (e.g., for testing with mocks)
-	if m.DB == nil {
-		err := m.Authenticate()
-		if err != nil {
-			slog.Warn("Unable to authenticate")
-			return nil, err
-		}
+	result, err := m.FetchResult(params)
+	if err != nil {
+		return nil, err
 	}
+	return result.Rows, nil
+}
+
+// FetchResult executes a SQL query and returns results along with the
+// column order reported by the database
+func (m *MariaDBAuth) FetchResult(params map[string]string) (*Result, error) {
+	return m.FetchBound(params, nil)
+}
 
-	if m.DB == nil {
+// FetchBound executes a SQL query with binds sent as driver parameters
+func (m *MariaDBAuth) FetchBound(params map[string]string, binds []BindParam) (*Result, error) {
+	pool, err := m.pool()
+	if err != nil {
+		return nil, err
+	}
+	if pool == nil {
 		return nil, errors.New("MariaDB database not initialized")
 	}
 
@@ -58,7 +111,11 @@ func (m *MariaDBAuth) FetchReport(params map[string]string) ([]map[string]string
 		return nil, errors.New("missing query parameter")
 	}
 
-	rows, err := m.DB.QueryContext(context.Background(), query)
+	query, args, err := bindQuery(query, params, binds, placeholderQuestion)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := pool.QueryContext(context.Background(), query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -107,5 +164,73 @@ func (m *MariaDBAuth) FetchReport(params map[string]string) ([]map[string]string
 		return nil, err
 	}
 
-	return results, nil
+	return &Result{Columns: cols, Rows: results}, nil
+}
+
+// InsertRows loads rows into table inside a single transaction.
+// When truncate is set the table is emptied first. Blank values are inserted as NULL
+func (m *MariaDBAuth) InsertRows(table string, columns []string, rows []map[string]string, truncate bool) error {
+	pool, err := m.pool()
+	if err != nil {
+		return err
+	}
+	db, ok := pool.(sqlBeginner)
+	if !ok {
+		return errors.New("MariaDB connection does not support transactions")
+	}
+
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	ident := quoteMariaDBIdentifier(table)
+	if truncate {
+		_, err = tx.ExecContext(ctx, "DELETE FROM "+ident)
+		if err != nil {
+			return fmt.Errorf("failed to truncate %s: %w", table, err)
+		}
+	}
+
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteMariaDBIdentifier(c)
+	}
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES ", ident, strings.Join(quoted, ", "))
+	tuple := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+
+	for start := 0; start < len(rows); start += insertBatchSize {
+		end := min(start+insertBatchSize, len(rows))
+		tuples := make([]string, 0, end-start)
+		var args []any
+		for _, row := range rows[start:end] {
+			for _, c := range columns {
+				args = append(args, nullIfEmpty(row[c]))
+			}
+			tuples = append(tuples, tuple)
+		}
+		_, err = tx.ExecContext(ctx, prefix+strings.Join(tuples, ", "), args...)
+		if err != nil {
+			return fmt.Errorf("failed to insert into %s: %w", table, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger(m.log).Debug("Inserted rows into MariaDB", "table", table, "rows", len(rows))
+	return nil
+}
+
+// quoteMariaDBIdentifier backtick-quotes each dot-separated part of name
+func quoteMariaDBIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = "`" + strings.ReplaceAll(part, "`", "``") + "`"
+	}
+	return strings.Join(parts, ".")
 }
@@ -3,6 +3,7 @@ package connection_test
 import (
 	"errors"
 	"reflect"
+	"regexp"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -47,6 +48,44 @@ func TestMariaDBAuth_FetchReport(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Watermark Is Bound",
+			setupMock: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name FROM users WHERE updated_at > ? AND note <> ':watermark' AND id <> ?`)).
+					WithArgs("2024-01-01 00:00:00", "2024-01-01 00:00:00").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).
+						AddRow("2", "Bob"))
+			},
+			params: map[string]string{
+				"query":     `SELECT id, name FROM users WHERE updated_at > :watermark AND note <> ':watermark' AND id <> :watermark`,
+				"watermark": "2024-01-01 00:00:00",
+			},
+			expectedResults: []map[string]string{
+				{
+					"id":   "2",
+					"name": "Bob",
+				},
+			},
+		},
+		{
+			name: "Backslash Escaped Quote Stays In The Literal",
+			setupMock: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name FROM users WHERE note <> 'it\'s :watermark' AND updated_at > ?`)).
+					WithArgs("2024-01-01 00:00:00").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).
+						AddRow("2", "Bob"))
+			},
+			params: map[string]string{
+				"query":     `SELECT id, name FROM users WHERE note <> 'it\'s :watermark' AND updated_at > :watermark`,
+				"watermark": "2024-01-01 00:00:00",
+			},
+			expectedResults: []map[string]string{
+				{
+					"id":   "2",
+					"name": "Bob",
+				},
+			},
+		},
 		{
 			name:            "Missing Query Parameter",
 			setupMock:       func() {},
@@ -102,3 +141,110 @@ func TestMariaDBAuth_FetchReport(t *testing.T) {
 		})
 	}
 }
+
+func TestMariaDBAuth_InsertRows(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mariaAuth := &connection.MariaDBAuth{DB: db}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `reports`.`gate_counts`").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("INSERT INTO `reports`.`gate_counts` \\(`date`, `count`\\) VALUES \\(\\?, \\?\\), \\(\\?, \\?\\)").
+		WithArgs("2024-01-01", "5", "2024-01-02", nil).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err = mariaAuth.InsertRows("reports.gate_counts", []string{"date", "count"}, []map[string]string{
+		{"date": "2024-01-01", "count": "5"},
+		{"date": "2024-01-02", "count": ""},
+	}, true)
+	if err != nil {
+		t.Fatalf("InsertRows() failed: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet mock expectations: %v", err)
+	}
+}
+
+func TestMariaDBAuth_FetchBound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mariaAuth := &connection.MariaDBAuth{DB: db}
+
+	tests := []struct {
+		name      string
+		setupMock func()
+		params    map[string]string
+		binds     []connection.BindParam
+	}{
+		{
+			name: "Named Params Repeat",
+			setupMock: func() {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM loans WHERE branch = ? AND renewals > ? AND branch <> ?")).
+					WithArgs("Linderman", int64(2), "Linderman").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1"))
+			},
+			params: map[string]string{"query": "SELECT id FROM loans WHERE branch = :branch AND renewals > :renewals AND branch <> :branch"},
+			binds: []connection.BindParam{
+				{Name: "branch", Value: "Linderman"},
+				{Name: "renewals", Type: connection.ParamTypeInt, Value: "2"},
+			},
+		},
+		{
+			name: "Positional Params",
+			setupMock: func() {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM loans WHERE branch = ? AND fine > ?")).
+					WithArgs("Fairchild", 1.5).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("2"))
+			},
+			params: map[string]string{"query": "SELECT id FROM loans WHERE branch = ? AND fine > ?"},
+			binds: []connection.BindParam{
+				{Value: "Fairchild"},
+				{Type: connection.ParamTypeFloat, Value: "1.5"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			_, err := connection.FetchBound(mariaAuth, tt.params, tt.binds)
+			if err != nil {
+				t.Errorf("FetchBound() failed: %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unmet mock expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestMariaDBAuth_FetchResult_NoRows(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT id, name FROM overdue").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	result, err := connection.Fetch(&connection.MariaDBAuth{DB: db}, map[string]string{"query": "SELECT id, name FROM overdue"})
+	if err != nil {
+		t.Fatalf("Fetch() failed: %v", err)
+	}
+	if len(result.Rows) != 0 || !reflect.DeepEqual(result.Columns, []string{"id", "name"}) {
+		t.Errorf("Expected columns without rows, got %+v", result)
+	}
+}
@@ -0,0 +1,42 @@
+package connection
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/metrics"
+)
+
+var (
+	requestDuration = metrics.Default.Histogram("encode_connector_request_duration_seconds",
+		"Time taken by requests to a connector, by connector and operation", metrics.DefaultBuckets, "connector", "operation")
+	requestErrors = metrics.Default.Counter("encode_connector_errors_total",
+		"Connector requests that failed, by connector and operation", "connector", "operation")
+)
+
+// Type is the name a connection is labelled with in metrics, e.g. postgresql
+func Type(conn any) string {
+	switch conn.(type) {
+	case *PostgresAuth:
+		return "postgresql"
+	case *MariaDBAuth:
+		return "mariadb"
+	case *FolioAuth:
+		return "folio"
+	case *GoogleSheetsAuth:
+		return "googlesheets"
+	case *GoogleAuth:
+		return "googleanalytics"
+	case *MockConnection:
+		return "mock"
+	}
+	return fmt.Sprintf("%T", conn)
+}
+
+// Observe records a connector request that started at start
+func Observe(connector, operation string, start time.Time, err error) {
+	requestDuration.Observe(time.Since(start).Seconds(), connector, operation)
+	if err != nil {
+		requestErrors.Inc(connector, operation)
+	}
+}
@@ -1,5 +1,11 @@
 package connection
 
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
 // MockConnection is a simple mock implementation for testing
 type MockConnection struct {
 	Name string
@@ -10,9 +16,37 @@ func (m *MockConnection) Authenticate() error {
 }
 
 func (m *MockConnection) FetchReport(params map[string]string) ([]map[string]string, error) {
-	// Return some mock data
-	return []map[string]string{
-		{"id": "1", "name": "Test User 1"},
-		{"id": "2", "name": "Test User 2"},
-	}, nil
+	result, err := m.FetchResult(params)
+	if err != nil {
+		return nil, err
+	}
+	return result.Rows, nil
+}
+
+// FetchResult returns two rows of mock data. A "rows" param returns fewer,
+// down to none with the columns still set, like a SQL driver, and a "delay"
+// param waits that long first, like a slow query
+func (m *MockConnection) FetchResult(params map[string]string) (*Result, error) {
+	if d, ok := params["delay"]; ok {
+		delay, err := time.ParseDuration(d)
+		if err != nil {
+			return nil, fmt.Errorf("invalid delay param '%s'", d)
+		}
+		time.Sleep(delay)
+	}
+	result := &Result{
+		Columns: []string{"id", "name"},
+		Rows: []map[string]string{
+			{"id": "1", "name": "Test User 1"},
+			{"id": "2", "name": "Test User 2"},
+		},
+	}
+	if n, ok := params["rows"]; ok {
+		rows, err := strconv.Atoi(n)
+		if err != nil || rows < 0 {
+			return nil, fmt.Errorf("invalid rows param '%s'", n)
+		}
+		result.Rows = result.Rows[:min(rows, len(result.Rows))]
+	}
+	return result, nil
 }
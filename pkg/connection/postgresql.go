@@ -3,7 +3,10 @@ package connection
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"strings"
+	"sync"
 
 	pgx "github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -14,9 +17,48 @@ type PgxQuerier interface {
 	Close()
 }
 
+// pgxBeginner is satisfied by pgx pools and lets InsertRows use a transaction
+type pgxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
 type PostgresAuth struct {
 	DSN string
 	DB  PgxQuerier
+	log *slog.Logger
+	// shared is the connection a WithLogger copy uses the pool of
+	shared *PostgresAuth
+	mu     sync.Mutex
+}
+
+// WithLogger returns a copy of the connection that logs to log and uses the
+// original's pool
+func (p *PostgresAuth) WithLogger(log *slog.Logger) ConnectionProvider {
+	return &PostgresAuth{DSN: p.DSN, log: log, shared: p.owner()}
+}
+
+func (p *PostgresAuth) owner() *PostgresAuth {
+	if p.shared != nil {
+		return p.shared
+	}
+	return p
+}
+
+// pool returns the connection pool, opening it on first use. Runs share one
+// pool however many copies of the connection they log through
+func (p *PostgresAuth) pool() (PgxQuerier, error) {
+	o := p.owner()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	// Only authenticate if DB is not already set (e.g., for testing with mocks)
+	if o.DB == nil {
+		err := o.Authenticate()
+		if err != nil {
+			logger(p.log).Warn("Unable to authenticate")
+			return nil, err
+		}
+	}
+	return o.DB, nil
 }
 
 func (p *PostgresAuth) Authenticate() error {
@@ -41,16 +83,26 @@ func (p *PostgresAuth) Authenticate() error {
 
 // FetchReport executes a SQL query and returns results
 func (p *PostgresAuth) FetchReport(params map[string]string) ([]map[string]string, error) {
-	// Only authenticate if DB is not already set (e.g., for testing with mocks)
-	if p.DB == nil {
-		err := p.Authenticate()
-		if err != nil {
-			slog.Warn("Unable to authenticate")
-			return nil, err
-		}
+	result, err := p.FetchResult(params)
+	if err != nil {
+		return nil, err
 	}
+	return result.Rows, nil
+}
 
-	if p.DB == nil {
+// FetchResult executes a SQL query and returns results along with the
+// column order reported by the database
+func (p *PostgresAuth) FetchResult(params map[string]string) (*Result, error) {
+	return p.FetchBound(params, nil)
+}
+
+// FetchBound executes a SQL query with binds sent as driver parameters
+func (p *PostgresAuth) FetchBound(params map[string]string, binds []BindParam) (*Result, error) {
+	pool, err := p.pool()
+	if err != nil {
+		return nil, err
+	}
+	if pool == nil {
 		return nil, errors.New("PostgreSQL database not initialized")
 	}
 
@@ -59,7 +111,11 @@ func (p *PostgresAuth) FetchReport(params map[string]string) ([]map[string]strin
 		return nil, errors.New("missing query parameter")
 	}
 
-	rows, err := p.DB.Query(context.Background(), query)
+	query, args, err := bindQuery(query, params, binds, placeholderDollar)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := pool.Query(context.Background(), query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -67,6 +123,10 @@ func (p *PostgresAuth) FetchReport(params map[string]string) ([]map[string]strin
 
 	var results []map[string]string
 	cols := rows.FieldDescriptions()
+	columns := make([]string, len(cols))
+	for i, col := range cols {
+		columns[i] = string(col.Name)
+	}
 
 	for rows.Next() {
 		rowData := make([]any, len(cols))
@@ -87,5 +147,65 @@ func (p *PostgresAuth) FetchReport(params map[string]string) ([]map[string]strin
 		results = append(results, rowMap)
 	}
 
-	return results, nil
+	return &Result{Columns: columns, Rows: results}, nil
+}
+
+// InsertRows loads rows into table inside a single transaction.
+// When truncate is set the table is emptied first. Blank values are inserted as NULL
+func (p *PostgresAuth) InsertRows(table string, columns []string, rows []map[string]string, truncate bool) error {
+	pool, err := p.pool()
+	if err != nil {
+		return err
+	}
+	db, ok := pool.(pgxBeginner)
+	if !ok {
+		return errors.New("PostgreSQL connection does not support transactions")
+	}
+
+	ctx := context.Background()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	ident := pgx.Identifier(strings.Split(table, ".")).Sanitize()
+	if truncate {
+		_, err = tx.Exec(ctx, "DELETE FROM "+ident)
+		if err != nil {
+			return fmt.Errorf("failed to truncate %s: %w", table, err)
+		}
+	}
+
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = pgx.Identifier{c}.Sanitize()
+	}
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES ", ident, strings.Join(quoted, ", "))
+
+	for start := 0; start < len(rows); start += insertBatchSize {
+		end := min(start+insertBatchSize, len(rows))
+		var tuples []string
+		var args []any
+		for _, row := range rows[start:end] {
+			placeholders := make([]string, len(columns))
+			for i, c := range columns {
+				args = append(args, nullIfEmpty(row[c]))
+				placeholders[i] = fmt.Sprintf("$%d", len(args))
+			}
+			tuples = append(tuples, "("+strings.Join(placeholders, ", ")+")")
+		}
+		_, err = tx.Exec(ctx, prefix+strings.Join(tuples, ", "), args...)
+		if err != nil {
+			return fmt.Errorf("failed to insert into %s: %w", table, err)
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger(p.log).Debug("Inserted rows into PostgreSQL", "table", table, "rows", len(rows))
+	return nil
 }
@@ -1,9 +1,14 @@
 package connection_test
 
 import (
+	"bytes"
 	"errors"
+	"log/slog"
 	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/lehigh-university-libraries/encode/pkg/connection"
 	pgxmock "github.com/pashagolub/pgxmock/v4"
@@ -46,6 +51,25 @@ func TestPostgresAuth_FetchReport(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Watermark Is Bound",
+			setupMock: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name FROM users WHERE updated_at > $1 AND note <> ':watermark' AND id::text <> $1`)).
+					WithArgs("2024-01-01 00:00:00").
+					WillReturnRows(pgxmock.NewRows([]string{"id", "name"}).
+						AddRow("2", "Bob"))
+			},
+			params: map[string]string{
+				"query":     `SELECT id, name FROM users WHERE updated_at > :watermark AND note <> ':watermark' AND id::text <> :watermark`,
+				"watermark": "2024-01-01 00:00:00",
+			},
+			expectedResults: []map[string]string{
+				{
+					"id":   "2",
+					"name": "Bob",
+				},
+			},
+		},
 		{
 			name:            "Missing Query Parameter",
 			setupMock:       func() {},
@@ -101,3 +125,171 @@ func TestPostgresAuth_FetchReport(t *testing.T) {
 		})
 	}
 }
+
+func TestPostgresAuth_InsertRows(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	pgAuth := &connection.PostgresAuth{DB: mock}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "public"."gate_counts" \("date", "count"\) VALUES \(\$1, \$2\), \(\$3, \$4\)`).
+		WithArgs("2024-01-01", "5", "2024-01-02", nil).
+		WillReturnResult(pgxmock.NewResult("INSERT", 2))
+	mock.ExpectCommit()
+	mock.ExpectRollback()
+
+	err = pgAuth.InsertRows("public.gate_counts", []string{"date", "count"}, []map[string]string{
+		{"date": "2024-01-01", "count": "5"},
+		{"date": "2024-01-02", "count": ""},
+	}, false)
+	if err != nil {
+		t.Fatalf("InsertRows() failed: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet mock expectations: %v", err)
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	pgAuth := &connection.PostgresAuth{DB: mock}
+	var buf bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})).With("run", "r1")
+	writer := connection.WithLogger[connection.RowWriter](pgAuth, log)
+	if writer == connection.RowWriter(pgAuth) {
+		t.Fatal("Expected a copy of the connection")
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "gate_counts"`).
+		WithArgs("2024-01-01").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+	mock.ExpectRollback()
+
+	err = writer.InsertRows("gate_counts", []string{"date"}, []map[string]string{{"date": "2024-01-01"}}, false)
+	if err != nil {
+		t.Fatalf("InsertRows() failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Inserted rows into PostgreSQL") || !strings.Contains(buf.String(), "run=r1") {
+		t.Errorf("Expected the copy to log to the run's logger, got %q", buf.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet mock expectations: %v", err)
+	}
+
+	mockConn := &connection.MockConnection{}
+	if connection.WithLogger[connection.ConnectionProvider](mockConn, log) != connection.ConnectionProvider(mockConn) {
+		t.Error("Expected a connection that does not log to be returned as is")
+	}
+}
+
+func TestPostgresAuth_FetchBound(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	pgAuth := &connection.PostgresAuth{DB: mock}
+
+	tests := []struct {
+		name        string
+		setupMock   func()
+		params      map[string]string
+		binds       []connection.BindParam
+		expectError bool
+	}{
+		{
+			name: "Named Params Are Typed",
+			setupMock: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name FROM loans WHERE branch = $1 AND loaned_at >= $2 AND renewals > $3 AND updated_at > $4 AND branch <> $1`)).
+					WithArgs("Linderman", time.Date(2024, 9, 1, 0, 0, 0, 0, time.Local), int64(2), "2024-01-01").
+					WillReturnRows(pgxmock.NewRows([]string{"id", "name"}).AddRow("1", "Alice"))
+			},
+			params: map[string]string{
+				"query":     `SELECT id, name FROM loans WHERE branch = :branch AND loaned_at >= :since AND renewals > :renewals AND updated_at > :watermark AND branch <> :branch`,
+				"watermark": "2024-01-01",
+			},
+			binds: []connection.BindParam{
+				{Name: "branch", Value: "Linderman"},
+				{Name: "since", Type: connection.ParamTypeDate, Value: "2024-09-01"},
+				{Name: "renewals", Type: connection.ParamTypeInt, Value: "2"},
+			},
+		},
+		{
+			name: "Positional Params",
+			setupMock: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name FROM loans WHERE branch = $1 AND active = $2 AND fine > $3`)).
+					WithArgs("Fairchild", true, nil).
+					WillReturnRows(pgxmock.NewRows([]string{"id", "name"}).AddRow("2", "Bob"))
+			},
+			params: map[string]string{"query": `SELECT id, name FROM loans WHERE branch = $1 AND active = $2 AND fine > $3`},
+			binds: []connection.BindParam{
+				{Value: "Fairchild"},
+				{Type: connection.ParamTypeBool, Value: "true"},
+				{Type: connection.ParamTypeFloat},
+			},
+		},
+		{
+			name:        "Invalid Value",
+			setupMock:   func() {},
+			params:      map[string]string{"query": `SELECT 1 WHERE :n > 0`},
+			binds:       []connection.BindParam{{Name: "n", Type: connection.ParamTypeInt, Value: "ten"}},
+			expectError: true,
+		},
+		{
+			name:        "Mixed Named And Positional",
+			setupMock:   func() {},
+			params:      map[string]string{"query": `SELECT 1`},
+			binds:       []connection.BindParam{{Name: "a", Value: "1"}, {Value: "2"}},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			_, err := connection.FetchBound(pgAuth, tt.params, tt.binds)
+			if tt.expectError && err == nil {
+				t.Errorf("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("FetchBound() failed: %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unmet mock expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestPostgresAuth_FetchResult_NoRows(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("SELECT id, name FROM overdue").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "name"}))
+
+	result, err := connection.Fetch(&connection.PostgresAuth{DB: mock}, map[string]string{"query": "SELECT id, name FROM overdue"})
+	if err != nil {
+		t.Fatalf("Fetch() failed: %v", err)
+	}
+	if len(result.Rows) != 0 || !reflect.DeepEqual(result.Columns, []string{"id", "name"}) {
+		t.Errorf("Expected columns without rows, got %+v", result)
+	}
+}
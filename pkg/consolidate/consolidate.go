@@ -0,0 +1,138 @@
+package consolidate
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Options turns a report into one consolidated file that each run merges into
+type Options struct {
+	// Key columns identify a row across runs
+	Key []string `yaml:"key"`
+	// DeleteMissing drops rows the latest run did not return
+	DeleteMissing bool `yaml:"delete_missing"`
+}
+
+// Stats counts what a merge did to the master dataset
+type Stats struct {
+	Inserted  int
+	Updated   int
+	Deleted   int
+	Unchanged int
+}
+
+// Validate checks the key columns
+func (o Options) Validate() error {
+	if len(o.Key) == 0 {
+		return errors.New("consolidate requires at least one key column")
+	}
+	for i, k := range o.Key {
+		if k == "" {
+			return errors.New("consolidate key columns can not be empty")
+		}
+		if slices.Contains(o.Key[:i], k) {
+			return fmt.Errorf("duplicate consolidate key column '%s'", k)
+		}
+	}
+	return nil
+}
+
+// Merge upserts rows into the master dataset by the key columns. Existing rows
+// keep their position, changed rows are updated in place and new rows are
+// appended. Columns are the master's followed by any new ones
+func Merge(opts Options, masterColumns []string, master []map[string]string, columns []string, rows []map[string]string) ([]string, []map[string]string, Stats, error) {
+	var stats Stats
+	for _, k := range opts.Key {
+		if !slices.Contains(columns, k) {
+			return nil, nil, stats, fmt.Errorf("key column '%s' is missing from the report results", k)
+		}
+		if len(master) > 0 && !slices.Contains(masterColumns, k) {
+			return nil, nil, stats, fmt.Errorf("key column '%s' is missing from the consolidated file", k)
+		}
+	}
+
+	merged := append([]string{}, masterColumns...)
+	for _, c := range columns {
+		if !slices.Contains(merged, c) {
+			merged = append(merged, c)
+		}
+	}
+
+	incoming := make(map[string]map[string]string, len(rows))
+	var order []string
+	for _, row := range rows {
+		k := opts.key(row)
+		if _, ok := incoming[k]; ok {
+			return nil, nil, stats, fmt.Errorf("duplicate key %s in the report results", opts.describe(row))
+		}
+		incoming[k] = row
+		order = append(order, k)
+	}
+
+	seen := make(map[string]bool, len(master))
+	out := make([]map[string]string, 0, len(master)+len(rows))
+	for _, existing := range master {
+		k := opts.key(existing)
+		if seen[k] {
+			return nil, nil, stats, fmt.Errorf("duplicate key %s in the consolidated file", opts.describe(existing))
+		}
+		seen[k] = true
+
+		row, ok := incoming[k]
+		if !ok {
+			if opts.DeleteMissing {
+				stats.Deleted++
+				continue
+			}
+			stats.Unchanged++
+			out = append(out, existing)
+			continue
+		}
+
+		updated := make(map[string]string, len(merged))
+		changed := false
+		for c, v := range existing {
+			updated[c] = v
+		}
+		for _, c := range columns {
+			if existing[c] != row[c] {
+				changed = true
+			}
+			updated[c] = row[c]
+		}
+		if changed {
+			stats.Updated++
+		} else {
+			stats.Unchanged++
+		}
+		out = append(out, updated)
+	}
+
+	for _, k := range order {
+		if seen[k] {
+			continue
+		}
+		stats.Inserted++
+		out = append(out, incoming[k])
+	}
+
+	return merged, out, stats, nil
+}
+
+func (o Options) key(row map[string]string) string {
+	values := make([]string, len(o.Key))
+	for i, k := range o.Key {
+		values[i] = row[k]
+	}
+	return strings.Join(values, "\x1f")
+}
+
+func (o Options) describe(row map[string]string) string {
+	parts := make([]string, len(o.Key))
+	for i, k := range o.Key {
+		parts[i] = fmt.Sprintf("%s=%q", k, row[k])
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,113 @@
+package consolidate_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lehigh-university-libraries/encode/pkg/consolidate"
+)
+
+func TestMerge(t *testing.T) {
+	master := []map[string]string{
+		{"date": "2024-01-01", "branch": "Linderman", "count": "5"},
+		{"date": "2024-01-01", "branch": "Fairchild", "count": "3"},
+		{"date": "2024-01-02", "branch": "Linderman", "count": "7"},
+	}
+	masterColumns := []string{"date", "branch", "count"}
+
+	tests := []struct {
+		name          string
+		opts          consolidate.Options
+		columns       []string
+		rows          []map[string]string
+		expectError   bool
+		expectColumns []string
+		expectRows    []map[string]string
+		expectStats   consolidate.Stats
+	}{
+		{
+			name:    "Insert and update by composite key",
+			opts:    consolidate.Options{Key: []string{"date", "branch"}},
+			columns: []string{"date", "branch", "count"},
+			rows: []map[string]string{
+				{"date": "2024-01-02", "branch": "Linderman", "count": "8"},
+				{"date": "2024-01-02", "branch": "Fairchild", "count": "2"},
+			},
+			expectColumns: masterColumns,
+			expectRows: []map[string]string{
+				master[0],
+				master[1],
+				{"date": "2024-01-02", "branch": "Linderman", "count": "8"},
+				{"date": "2024-01-02", "branch": "Fairchild", "count": "2"},
+			},
+			expectStats: consolidate.Stats{Inserted: 1, Updated: 1, Unchanged: 2},
+		},
+		{
+			name:    "Delete missing",
+			opts:    consolidate.Options{Key: []string{"date", "branch"}, DeleteMissing: true},
+			columns: []string{"date", "branch", "count"},
+			rows: []map[string]string{
+				{"date": "2024-01-01", "branch": "Linderman", "count": "5"},
+			},
+			expectColumns: masterColumns,
+			expectRows:    []map[string]string{master[0]},
+			expectStats:   consolidate.Stats{Deleted: 2, Unchanged: 1},
+		},
+		{
+			name:    "New column is added to the end",
+			opts:    consolidate.Options{Key: []string{"date", "branch"}},
+			columns: []string{"date", "branch", "visitors", "count"},
+			rows: []map[string]string{
+				{"date": "2024-01-01", "branch": "Linderman", "visitors": "40", "count": "5"},
+			},
+			expectColumns: []string{"date", "branch", "count", "visitors"},
+			expectRows: []map[string]string{
+				{"date": "2024-01-01", "branch": "Linderman", "visitors": "40", "count": "5"},
+				master[1],
+				master[2],
+			},
+			expectStats: consolidate.Stats{Updated: 1, Unchanged: 2},
+		},
+		{
+			name:        "Key column missing from results",
+			opts:        consolidate.Options{Key: []string{"date", "branch"}},
+			columns:     []string{"date", "count"},
+			rows:        []map[string]string{{"date": "2024-01-01", "count": "5"}},
+			expectError: true,
+		},
+		{
+			name:    "Duplicate key in results",
+			opts:    consolidate.Options{Key: []string{"date"}},
+			columns: []string{"date", "branch", "count"},
+			rows: []map[string]string{
+				{"date": "2024-01-03", "branch": "Linderman", "count": "1"},
+				{"date": "2024-01-03", "branch": "Fairchild", "count": "1"},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, rows, stats, err := consolidate.Merge(tt.opts, masterColumns, master, tt.columns, tt.rows)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Merge() failed: %v", err)
+			}
+			if !reflect.DeepEqual(tt.expectColumns, columns) {
+				t.Errorf("Expected columns %v, got %v", tt.expectColumns, columns)
+			}
+			if !reflect.DeepEqual(tt.expectRows, rows) {
+				t.Errorf("Expected rows %v, got %v", tt.expectRows, rows)
+			}
+			if tt.expectStats != stats {
+				t.Errorf("Expected stats %+v, got %+v", tt.expectStats, stats)
+			}
+		})
+	}
+}
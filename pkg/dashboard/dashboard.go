@@ -0,0 +1,216 @@
+// Package dashboard serves a read-only status page of encode's reports: when
+// each last ran, how it went, its row counts and where its data went
+package dashboard
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/config"
+	"github.com/lehigh-university-libraries/encode/pkg/history"
+	cron "github.com/robfig/cron/v3"
+)
+
+// trendRuns is how many successful runs the row-count trend covers
+const trendRuns = 30
+
+// previewRows is how many rows of the latest output a report's page shows
+const previewRows = 20
+
+// recentRuns is how many runs a report's page lists
+const recentRuns = 20
+
+//go:embed dashboard.html
+var page string
+
+var templates = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"since":    since,
+	"datetime": func(t time.Time) string { return t.Local().Format("2006-01-02 15:04") },
+	"duration": func(d time.Duration) string { return d.Round(time.Second).String() },
+	"join":     strings.Join,
+}).Parse(page))
+
+// Dashboard renders the status page for one config and its scheduler
+type Dashboard struct {
+	config    *config.Config
+	scheduler *cron.Cron
+	password  string
+}
+
+// New returns the dashboard for c. Pages show report data, so every request
+// must log in with password over HTTP basic auth
+func New(c *config.Config, scheduler *cron.Cron, password string) *Dashboard {
+	return &Dashboard{config: c, scheduler: scheduler, password: password}
+}
+
+// Register adds the dashboard's pages to mux: the list of reports at / and a
+// page per report under /reports/
+func (d *Dashboard) Register(mux *http.ServeMux) {
+	mux.Handle("GET /{$}", d.auth(d.index))
+	mux.Handle("GET /reports/{name}", d.auth(d.report))
+}
+
+// auth asks for the dashboard's password; any user name is accepted
+func (d *Dashboard) auth(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, password, ok := r.BasicAuth()
+		if !ok || d.password == "" || subtle.ConstantTimeCompare([]byte(password), []byte(d.password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="encode", charset="UTF-8"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// status is what the dashboard shows about a report
+type status struct {
+	Name     string
+	Schedule string
+	Owners   []string
+	Paused   time.Time
+	NextRun  time.Time
+	Running  bool
+	LastRun  *history.Record
+	// Trend is the row counts of the last successful runs, oldest first
+	Trend     []int
+	Sparkline string
+	// ManifestURL is the report's QuickSight manifest, when it goes to S3
+	ManifestURL string
+	// Folder is the S3 folder the report's files are uploaded to
+	Folder string
+}
+
+func (d *Dashboard) index(w http.ResponseWriter, r *http.Request) {
+	next := config.NextRuns(d.scheduler)
+	reports := make([]status, 0, len(d.config.Reports))
+	for _, report := range d.config.Reports {
+		s, err := d.status(report, next)
+		if err != nil {
+			d.fail(w, err)
+			return
+		}
+		reports = append(reports, s)
+	}
+	d.render(w, "index", map[string]any{"Reports": reports, "Now": time.Now()})
+}
+
+func (d *Dashboard) report(w http.ResponseWriter, r *http.Request) {
+	report, err := d.config.Report(r.PathValue("name"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	s, err := d.status(*report, config.NextRuns(d.scheduler))
+	if err != nil {
+		d.fail(w, err)
+		return
+	}
+	runs, err := d.config.History().List(history.Filter{Report: report.Name, Limit: recentRuns})
+	if err != nil {
+		d.fail(w, err)
+		return
+	}
+	data := map[string]any{"Report": s, "Runs": runs, "Now": time.Now()}
+	preview, err := report.Preview(previewRows)
+	if err != nil {
+		// A file that can't be read shouldn't hide the rest of the page
+		slog.Warn("Unable to preview report output", "report", report.Name, "err", err)
+		data["PreviewError"] = err.Error()
+	}
+	data["Preview"] = preview
+	d.render(w, "report", data)
+}
+
+func (d *Dashboard) status(report config.ReportConfig, next map[string]time.Time) (status, error) {
+	s := status{
+		Name:     report.Name,
+		Schedule: report.Schedule,
+		Owners:   report.Owners,
+	}
+	var err error
+	s.Paused, err = report.Paused()
+	if err != nil {
+		return s, err
+	}
+	if s.Paused.IsZero() {
+		s.NextRun = next[report.Name]
+	}
+	_, s.Running = d.config.Running(report.Name)
+
+	last, err := d.config.History().List(history.Filter{Report: report.Name, Limit: 1})
+	if err != nil {
+		return s, err
+	}
+	if len(last) == 1 {
+		s.LastRun = &last[0]
+	}
+	succeeded, err := d.config.History().List(history.Filter{Report: report.Name, Status: history.StatusSucceeded, NotTrigger: config.TriggerBackfill, Limit: trendRuns})
+	if err != nil {
+		return s, err
+	}
+	for _, rec := range succeeded {
+		s.Trend = append(s.Trend, rec.Rows)
+	}
+	slices.Reverse(s.Trend)
+	s.Sparkline = sparkline(s.Trend)
+
+	if u, err := d.config.ManifestUploader(report.Name); err == nil {
+		s.ManifestURL = u.ObjectURL(u.ManifestKey(report.Name))
+		s.Folder = u.ReportPrefixURI(report.Name)
+	}
+	return s, nil
+}
+
+func (d *Dashboard) render(w http.ResponseWriter, name string, data any) {
+	var buf strings.Builder
+	err := templates.ExecuteTemplate(&buf, name, data)
+	if err != nil {
+		d.fail(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(buf.String()))
+}
+
+func (d *Dashboard) fail(w http.ResponseWriter, err error) {
+	slog.Error("Unable to render dashboard", "err", err)
+	http.Error(w, "unable to load report status", http.StatusInternalServerError)
+}
+
+// sparkline returns the points of an SVG polyline, 100 wide and 20 high,
+// plotting counts from zero to their maximum
+func sparkline(counts []int) string {
+	if len(counts) < 2 {
+		return ""
+	}
+	top := max(slices.Max(counts), 1)
+	points := make([]string, len(counts))
+	for i, n := range counts {
+		x := float64(i) * 100 / float64(len(counts)-1)
+		y := 20 - float64(n)*20/float64(top)
+		points[i] = fmt.Sprintf("%.1f,%.1f", x, y)
+	}
+	return strings.Join(points, " ")
+}
+
+// since is how long ago t was, to the most significant unit
+func since(now, t time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+}
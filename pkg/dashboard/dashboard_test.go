@@ -0,0 +1,145 @@
+package dashboard_test
+
+import (
+	"cmp"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lehigh-university-libraries/encode/pkg/config"
+	"github.com/lehigh-university-libraries/encode/pkg/dashboard"
+)
+
+func TestDashboard(t *testing.T) {
+	staging := t.TempDir()
+	filename := filepath.Join(staging, "encode.yaml")
+	err := os.WriteFile(filename, []byte(`
+stagingDirectory: `+staging+`
+
+connections:
+  - name: mock
+    type: Mock
+
+reports:
+  - name: users
+    connection: mock
+    schedule: "0 12 * * *"
+    owners: [owner@example.edu]
+    query_params:
+      rows: "2"
+    sinks:
+      - type: Local
+        path: `+filepath.Join(staging, "out")+`
+  - name: idle
+    connection: mock
+    schedule: "0 6 * * 1"
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := config.LoadConfig(filename)
+	if err != nil {
+		t.Fatalf("LoadConfig() failed: %v", err)
+	}
+	report, err := c.Report("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rows := range []string{"1", "2"} {
+		err = report.Execute(config.RunOptions{Params: map[string]string{"rows": rows}})
+		if err != nil {
+			t.Fatalf("Execute() failed: %v", err)
+		}
+	}
+	err = c.Pause("idle")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	dashboard.New(c, c.StartCron(), "secret").Register(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		path         string
+		noAuth       bool
+		password     string
+		expectStatus int
+		expect       []string
+	}{
+		{
+			name:         "No password",
+			path:         "/",
+			noAuth:       true,
+			expectStatus: http.StatusUnauthorized,
+		},
+		{
+			name:         "Wrong password",
+			path:         "/reports/users",
+			password:     "nope",
+			expectStatus: http.StatusUnauthorized,
+		},
+		{
+			name:         "Reports",
+			path:         "/",
+			expectStatus: http.StatusOK,
+			expect:       []string{`href="reports/users"`, "owner@example.edu", "0 12 * * *", "succeeded", "<polyline", "never run", "paused"},
+		},
+		{
+			name:         "Report",
+			path:         "/reports/users",
+			expectStatus: http.StatusOK,
+			expect:       []string{"<th>id</th><th>name</th>", "<td>Test User 2</td>", "first 2 of 2 rows", "manual"},
+		},
+		{
+			name:         "Report without output",
+			path:         "/reports/idle",
+			expectStatus: http.StatusOK,
+			expect:       []string{"No output is left in the staging directory", "No runs yet"},
+		},
+		{
+			name:         "Unknown report",
+			path:         "/reports/missing",
+			expectStatus: http.StatusNotFound,
+		},
+		{
+			name:         "Unknown page",
+			path:         "/missing",
+			expectStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, server.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.noAuth {
+				req.SetBasicAuth("staff", cmp.Or(tt.password, "secret"))
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.expectStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectStatus, resp.StatusCode, body)
+			}
+			for _, s := range tt.expect {
+				if !strings.Contains(string(body), s) {
+					t.Errorf("Expected page to contain %q:\n%s", s, body)
+				}
+			}
+		})
+	}
+}
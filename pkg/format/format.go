@@ -0,0 +1,174 @@
+package format
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatTSV  = "tsv"
+	FormatJSON = "json"
+)
+
+// Options is the file format a report is written in
+type Options struct {
+	// Format is csv (default), tsv or json
+	Format string `yaml:"format"`
+	// Delimiter is a single character separating csv fields (default ",")
+	Delimiter string `yaml:"delimiter"`
+	// NoHeader leaves the header row out of csv and tsv files
+	NoHeader bool `yaml:"no_header"`
+}
+
+// Name is the normalized format name
+func (o Options) Name() string {
+	if o.Format == "" {
+		return FormatCSV
+	}
+	return strings.ToLower(o.Format)
+}
+
+// Extension is the file extension for the format, without a dot
+func (o Options) Extension() string {
+	return o.Name()
+}
+
+// Comma is the field separator for delimited formats
+func (o Options) Comma() rune {
+	if o.Name() == FormatTSV {
+		return '\t'
+	}
+	if o.Delimiter != "" {
+		r, _ := utf8.DecodeRuneInString(o.Delimiter)
+		return r
+	}
+	return ','
+}
+
+// Validate checks the format and delimiter
+func (o Options) Validate() error {
+	switch o.Name() {
+	case FormatCSV:
+		if o.Delimiter == "" {
+			return nil
+		}
+		if utf8.RuneCountInString(o.Delimiter) != 1 {
+			return fmt.Errorf("delimiter '%s' must be a single character", o.Delimiter)
+		}
+		switch r := o.Comma(); r {
+		case '"', '\r', '\n', utf8.RuneError:
+			return fmt.Errorf("invalid delimiter %q", r)
+		}
+	case FormatTSV, FormatJSON:
+		if o.Delimiter != "" {
+			return fmt.Errorf("delimiter is only supported for %s output", FormatCSV)
+		}
+	default:
+		return fmt.Errorf("unknown output format '%s': must be %s, %s or %s", o.Format, FormatCSV, FormatTSV, FormatJSON)
+	}
+	return nil
+}
+
+// WriteFile creates filename and writes the rows to it in the given format
+func WriteFile(filename string, opts Options, columns []string, rows []map[string]string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	defer file.Close()
+
+	return Write(file, opts, columns, rows)
+}
+
+// Write writes rows in the given format, ordering fields by columns
+func Write(w io.Writer, opts Options, columns []string, rows []map[string]string) error {
+	switch opts.Name() {
+	case FormatCSV, FormatTSV:
+		return writeDelimited(w, opts, columns, rows)
+	case FormatJSON:
+		return writeJSON(w, columns, rows)
+	}
+	return opts.Validate()
+}
+
+// writeDelimited writes the header even without rows, so an empty result
+// still tells readers its columns
+func writeDelimited(w io.Writer, opts Options, columns []string, rows []map[string]string) error {
+	if len(rows) == 0 && len(columns) == 0 {
+		return nil
+	}
+
+	file := csv.NewWriter(w)
+	file.Comma = opts.Comma()
+
+	if !opts.NoHeader {
+		err := file.Write(columns)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for i, key := range columns {
+			record[i] = row[key]
+		}
+		err := file.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+
+	file.Flush()
+	return file.Error()
+}
+
+// writeJSON writes a JSON array of objects whose keys follow columns
+func writeJSON(w io.Writer, columns []string, rows []map[string]string) error {
+	if len(rows) == 0 {
+		_, err := io.WriteString(w, "[]\n")
+		return err
+	}
+
+	keys := make([][]byte, len(columns))
+	for i, c := range columns {
+		k, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		keys[i] = k
+	}
+
+	var b strings.Builder
+	b.WriteString("[\n")
+	for n, row := range rows {
+		b.WriteString("  {")
+		for i, c := range columns {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			v, err := json.Marshal(row[c])
+			if err != nil {
+				return err
+			}
+			b.Write(keys[i])
+			b.WriteString(":")
+			b.Write(v)
+		}
+		b.WriteString("}")
+		if n < len(rows)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("]\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,191 @@
+package format_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lehigh-university-libraries/encode/pkg/format"
+)
+
+func TestWrite(t *testing.T) {
+	columns := []string{"date", "note"}
+	rows := []map[string]string{
+		{"date": "2024-01-01", "note": "a, b"},
+		{"date": "2024-01-02", "note": `say "hi"`},
+	}
+
+	tests := []struct {
+		name     string
+		opts     format.Options
+		expected string
+	}{
+		{
+			name:     "CSV",
+			expected: "date,note\n2024-01-01,\"a, b\"\n2024-01-02,\"say \"\"hi\"\"\"\n",
+		},
+		{
+			name:     "Custom delimiter without header",
+			opts:     format.Options{Delimiter: "|", NoHeader: true},
+			expected: "2024-01-01|a, b\n2024-01-02|\"say \"\"hi\"\"\"\n",
+		},
+		{
+			name:     "TSV",
+			opts:     format.Options{Format: "tsv"},
+			expected: "date\tnote\n2024-01-01\ta, b\n2024-01-02\t\"say \"\"hi\"\"\"\n",
+		},
+		{
+			name:     "JSON keeps column order",
+			opts:     format.Options{Format: "json"},
+			expected: "[\n  {\"date\":\"2024-01-01\",\"note\":\"a, b\"},\n  {\"date\":\"2024-01-02\",\"note\":\"say \\\"hi\\\"\"}\n]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := format.Write(&buf, tt.opts, columns, rows)
+			if err != nil {
+				t.Fatalf("Write() failed: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected:\n%q\nGot:\n%q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestWrite_NoRows(t *testing.T) {
+	columns := []string{"date", "note"}
+
+	tests := []struct {
+		name     string
+		opts     format.Options
+		columns  []string
+		expected string
+	}{
+		{
+			name:     "CSV header only",
+			columns:  columns,
+			expected: "date,note\n",
+		},
+		{
+			name:    "CSV without header",
+			opts:    format.Options{NoHeader: true},
+			columns: columns,
+		},
+		{
+			name: "CSV without columns",
+		},
+		{
+			name:     "JSON empty array",
+			opts:     format.Options{Format: "json"},
+			columns:  columns,
+			expected: "[]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := format.Write(&buf, tt.opts, tt.columns, nil)
+			if err != nil {
+				t.Fatalf("Write() failed: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected:\n%q\nGot:\n%q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        format.Options
+		expectError bool
+	}{
+		{name: "Default", opts: format.Options{}},
+		{name: "Semicolon", opts: format.Options{Delimiter: ";"}},
+		{name: "Multi-character delimiter", opts: format.Options{Delimiter: "||"}, expectError: true},
+		{name: "Quote delimiter", opts: format.Options{Delimiter: `"`}, expectError: true},
+		{name: "Delimiter on JSON", opts: format.Options{Format: "json", Delimiter: ";"}, expectError: true},
+		{name: "Unknown format", opts: format.Options{Format: "xlsx"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.expectError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRead_RoundTrip(t *testing.T) {
+	columns := []string{"date", "note"}
+	rows := []map[string]string{
+		{"date": "2024-01-01", "note": "a, b"},
+		{"date": "2024-01-02", "note": `say "hi"`},
+	}
+
+	for _, opts := range []format.Options{{}, {Delimiter: ";"}, {Format: "tsv"}, {Format: "json"}} {
+		t.Run(opts.Name()+opts.Delimiter, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := format.Write(&buf, opts, columns, rows)
+			if err != nil {
+				t.Fatalf("Write() failed: %v", err)
+			}
+			gotColumns, gotRows, err := format.Read(&buf, opts, nil)
+			if err != nil {
+				t.Fatalf("Read() failed: %v", err)
+			}
+			if !reflect.DeepEqual(columns, gotColumns) || !reflect.DeepEqual(rows, gotRows) {
+				t.Errorf("Expected %v %v, got %v %v", columns, rows, gotColumns, gotRows)
+			}
+		})
+	}
+}
+
+func TestReadFileHead(t *testing.T) {
+	columns := []string{"id", "name"}
+	rows := []map[string]string{
+		{"id": "1", "name": "a"},
+		{"id": "2", "name": "b"},
+		{"id": "3", "name": "c"},
+	}
+
+	tests := []struct {
+		name   string
+		opts   format.Options
+		n      int
+		expect int
+	}{
+		{name: "CSV", n: 2, expect: 2},
+		{name: "CSV without a header", opts: format.Options{NoHeader: true}, n: 1, expect: 1},
+		{name: "JSON", opts: format.Options{Format: "json"}, n: 2, expect: 2},
+		{name: "More than the file has", n: 10, expect: 3},
+		{name: "Every row", opts: format.Options{Format: "json"}, n: -1, expect: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "report."+tt.opts.Extension())
+			err := format.WriteFile(filename, tt.opts, columns, rows)
+			if err != nil {
+				t.Fatalf("WriteFile() failed: %v", err)
+			}
+			gotColumns, gotRows, err := format.ReadFileHead(filename, tt.opts, columns, tt.n)
+			if err != nil {
+				t.Fatalf("ReadFileHead() failed: %v", err)
+			}
+			if !reflect.DeepEqual(columns, gotColumns) || !reflect.DeepEqual(rows[:tt.expect], gotRows) {
+				t.Errorf("Expected %v %v, got %v %v", columns, rows[:tt.expect], gotColumns, gotRows)
+			}
+		})
+	}
+}
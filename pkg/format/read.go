@@ -0,0 +1,168 @@
+package format
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// ReadFile reads a file written by WriteFile back into columns and rows.
+// columns names the fields of files written without a header
+func ReadFile(filename string, opts Options, columns []string) ([]string, []map[string]string, error) {
+	return ReadFileHead(filename, opts, columns, -1)
+}
+
+// ReadFileHead reads only the first n rows of a file written by WriteFile,
+// or every row if n is negative
+func ReadFileHead(filename string, opts Options, columns []string, n int) ([]string, []map[string]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	return read(file, opts, columns, n)
+}
+
+// Read parses rows in the given format
+func Read(r io.Reader, opts Options, columns []string) ([]string, []map[string]string, error) {
+	return read(r, opts, columns, -1)
+}
+
+func read(r io.Reader, opts Options, columns []string, limit int) ([]string, []map[string]string, error) {
+	switch opts.Name() {
+	case FormatCSV, FormatTSV:
+		return readDelimited(r, opts, columns, limit)
+	case FormatJSON:
+		return readJSON(r, limit)
+	}
+	return nil, nil, opts.Validate()
+}
+
+func readDelimited(r io.Reader, opts Options, columns []string, limit int) ([]string, []map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = opts.Comma()
+	header := !opts.NoHeader
+
+	var rows []map[string]string
+	for limit < 0 || len(rows) < limit {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading %s: %w", opts.Name(), err)
+		}
+		if header {
+			columns = record
+			header = false
+			continue
+		}
+		if len(record) != len(columns) {
+			return nil, nil, fmt.Errorf("row %d has %d fields, expected %d", len(rows)+1, len(record), len(columns))
+		}
+		row := make(map[string]string, len(columns))
+		for j, c := range columns {
+			row[c] = record[j]
+		}
+		rows = append(rows, row)
+	}
+
+	return columns, rows, nil
+}
+
+// readJSON reads an array of flat objects, taking column order from the
+// order keys first appear in
+func readJSON(r io.Reader, limit int) ([]string, []map[string]string, error) {
+	dec := json.NewDecoder(r)
+	t, err := dec.Token()
+	if err == io.EOF || (err == nil && t == nil) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading json: %w", err)
+	}
+	if d, ok := t.(json.Delim); !ok || d != '[' {
+		return nil, nil, fmt.Errorf("error reading json: expected an array")
+	}
+
+	var objects []json.RawMessage
+	for dec.More() && (limit < 0 || len(objects) < limit) {
+		var raw json.RawMessage
+		err = dec.Decode(&raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading json: %w", err)
+		}
+		objects = append(objects, raw)
+	}
+
+	var columns []string
+	known := make(map[string]bool)
+	rows := make([]map[string]string, len(objects))
+	for i, raw := range objects {
+		var fields map[string]any
+		err = json.Unmarshal(raw, &fields)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading json object %d: %w", i+1, err)
+		}
+
+		keys, err := objectKeys(raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading json object %d: %w", i+1, err)
+		}
+		for _, k := range keys {
+			if !known[k] {
+				known[k] = true
+				columns = append(columns, k)
+			}
+		}
+
+		row := make(map[string]string, len(fields))
+		for k, v := range fields {
+			switch v := v.(type) {
+			case nil:
+				row[k] = ""
+			case string:
+				row[k] = v
+			case float64:
+				row[k] = strconv.FormatFloat(v, 'f', -1, 64)
+			default:
+				row[k] = fmt.Sprint(v)
+			}
+		}
+		rows[i] = row
+	}
+
+	return columns, rows, nil
+}
+
+// objectKeys returns the keys of a JSON object in document order
+func objectKeys(raw json.RawMessage) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := t.(json.Delim); !ok || d != '{' {
+		return nil, fmt.Errorf("expected an object")
+	}
+
+	var keys []string
+	for dec.More() {
+		t, err = dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, t.(string))
+		var skip json.RawMessage
+		err = dec.Decode(&skip)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
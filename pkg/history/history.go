@@ -0,0 +1,314 @@
+// Package history keeps a record of every report run
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Run statuses
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	// StatusHalted is a run stopped by a validation check before it published anything
+	StatusHalted = "halted"
+	// StatusQuarantined is a run whose results failed an anomaly check and were set aside instead of published
+	StatusQuarantined = "quarantined"
+)
+
+// Record is the outcome of one report run
+type Record struct {
+	ID            string        `json:"id"`
+	Report        string        `json:"report"`
+	Trigger       string        `json:"trigger"`
+	ScheduledTime time.Time     `json:"scheduled_time"`
+	Start         time.Time     `json:"start"`
+	End           time.Time     `json:"end,omitzero"`
+	Duration      time.Duration `json:"duration"`
+	Status        string        `json:"status"`
+	// Rows is how many rows the run extracted
+	Rows  int   `json:"rows"`
+	Bytes int64 `json:"bytes"`
+	// TotalRows is how many rows a consolidated report's file held after the run
+	TotalRows int `json:"total_rows,omitempty"`
+	// Metrics are the values the report's anomaly checks measured, such as rows or sum(count)
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+	// Files are the staged report file and what non-S3 sinks wrote
+	Files []string `json:"files,omitempty"`
+	// URIs are the S3 objects the run uploaded
+	URIs []string `json:"uris,omitempty"`
+	// Warnings are problems that did not stop the run, such as schema drift
+	Warnings []string `json:"warnings,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// NewID returns a run ID that sorts by start time
+func NewID(start time.Time) string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return start.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(b)
+}
+
+// Finish fills in the end of a run. A nil err means the run succeeded
+func (r *Record) Finish(end time.Time, err error) {
+	r.End = end
+	r.Duration = end.Sub(r.Start)
+	r.Status = StatusSucceeded
+	if err != nil {
+		r.Status = StatusFailed
+		r.Error = err.Error()
+	}
+}
+
+// Filter selects records from the store. Zero fields match everything
+type Filter struct {
+	Report  string
+	Trigger string
+	Status  string
+	Since   time.Time
+	// NotTrigger excludes the records of runs with this trigger
+	NotTrigger string
+	// Limit caps how many records are returned, newest first. With Report
+	// set, only as much of the report's history is read as it takes
+	Limit int
+}
+
+func (f Filter) match(r Record) bool {
+	return (f.Trigger == "" || r.Trigger == f.Trigger) &&
+		(f.NotTrigger == "" || r.Trigger != f.NotTrigger) &&
+		(f.Status == "" || r.Status == f.Status) &&
+		(f.Since.IsZero() || !r.Start.Before(f.Since))
+}
+
+// ErrNotFound is wrapped by the error of looking up a run that is not in the history
+var ErrNotFound = errors.New("not found")
+
+// Store appends records to one JSON Lines file per report in a directory
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewStore returns a store that keeps its files in dir
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Append saves a finished run
+func (s *Store) Append(r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err = os.MkdirAll(s.dir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	f, err := os.OpenFile(s.path(r.Report), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history for report '%s': %w", r.Report, err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	if err != nil {
+		return fmt.Errorf("failed to write history for report '%s': %w", r.Report, err)
+	}
+	return nil
+}
+
+// List returns the records matching f, newest first
+func (s *Store) List(f Filter) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f.Report != "" && f.Limit > 0 {
+		return readTail(s.path(f.Report), f)
+	}
+
+	var files []string
+	if f.Report != "" {
+		files = []string{s.path(f.Report)}
+	} else {
+		var err error
+		files, err = filepath.Glob(filepath.Join(s.dir, "*.jsonl"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var records []Record
+	for _, file := range files {
+		read, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range read {
+			if f.match(r) {
+				records = append(records, r)
+			}
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Start.After(records[j].Start) })
+	if f.Limit > 0 && len(records) > f.Limit {
+		records = records[:f.Limit]
+	}
+	return records, nil
+}
+
+// Get returns the run with the given ID
+func (s *Store) Get(id string) (*Record, error) {
+	records, err := s.List(Filter{})
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		if r.ID == id {
+			return &r, nil
+		}
+	}
+	return nil, fmt.Errorf("run '%s' %w", id, ErrNotFound)
+}
+
+func readFile(file string) ([]Record, error) {
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var r Record
+		err = json.Unmarshal(scanner.Bytes(), &r)
+		if err != nil {
+			// e.g. a line cut short by a crash; the rest of the history is still good
+			slog.Warn("Skipping unreadable run record", "file", file, "line", line, "err", err)
+			continue
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return records, nil
+}
+
+// readTail returns up to f.Limit records of a history file matching f, newest
+// first, reading the file backwards a chunk at a time. A report runs once at
+// a time, so its records are appended in the order its runs started
+func readTail(file string, f Filter) ([]Record, error) {
+	fh, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer fh.Close()
+	info, err := fh.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	const chunkSize = 64 * 1024
+	var records []Record
+	// partial is the start of a line whose beginning is in an earlier chunk
+	var partial []byte
+	for end := info.Size(); end > 0 && len(records) < f.Limit; {
+		start := max(end-chunkSize, 0)
+		buf := make([]byte, end-start, end-start+int64(len(partial)))
+		_, err = fh.ReadAt(buf, start)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		buf = append(buf, partial...)
+		end = start
+
+		lines := bytes.Split(buf, []byte("\n"))
+		partial = nil
+		if start > 0 {
+			partial, lines = lines[0], lines[1:]
+		}
+		for i := len(lines) - 1; i >= 0 && len(records) < f.Limit; i-- {
+			if len(bytes.TrimSpace(lines[i])) == 0 {
+				continue
+			}
+			var r Record
+			err = json.Unmarshal(lines[i], &r)
+			if err != nil {
+				slog.Warn("Skipping unreadable run record", "file", file, "err", err)
+				continue
+			}
+			if f.match(r) {
+				records = append(records, r)
+			}
+		}
+	}
+	return records, nil
+}
+
+// SaveLog attaches a run's log to the history, in logs/{report}/{id}.log
+func (s *Store) SaveLog(r Record, log []byte) error {
+	path := s.logPath(r)
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	err = os.WriteFile(path, log, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to save log of run '%s': %w", r.ID, err)
+	}
+	return nil
+}
+
+// Log returns the log attached to a run
+func (s *Store) Log(r Record) ([]byte, error) {
+	log, err := os.ReadFile(s.logPath(r))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("log of run '%s' %w", r.ID, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log of run '%s': %w", r.ID, err)
+	}
+	return log, nil
+}
+
+func (s *Store) path(reportName string) string {
+	return filepath.Join(s.dir, safeName(reportName)+".jsonl")
+}
+
+func (s *Store) logPath(r Record) string {
+	return filepath.Join(s.dir, "logs", safeName(r.Report), safeName(r.ID)+".log")
+}
+
+// safeName keeps a report name or run ID from leaving the history directory
+func safeName(name string) string {
+	return strings.NewReplacer("/", "_", "\\", "_").Replace(name)
+}
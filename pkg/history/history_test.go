@@ -0,0 +1,138 @@
+package history_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/history"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	store := history.NewStore(dir)
+
+	start := time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)
+	runs := []struct {
+		report  string
+		trigger string
+		offset  time.Duration
+		err     error
+	}{
+		{"gate_counts", "cron", 0, nil},
+		{"gate_counts", "cron", 24 * time.Hour, errors.New("database error")},
+		{"circulation", "manual", 36 * time.Hour, nil},
+		{"gate_counts", "backfill", 48 * time.Hour, nil},
+	}
+	for _, run := range runs {
+		rec := history.Record{
+			ID:      history.NewID(start.Add(run.offset)),
+			Report:  run.report,
+			Trigger: run.trigger,
+			Start:   start.Add(run.offset),
+			Rows:    10,
+		}
+		rec.Finish(rec.Start.Add(time.Minute), run.err)
+		err := store.Append(rec)
+		if err != nil {
+			t.Fatalf("Append() failed: %v", err)
+		}
+	}
+
+	// A record cut short by a crash is skipped
+	f, err := os.OpenFile(filepath.Join(dir, "gate_counts.jsonl"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"id":"half`)
+	f.Close()
+
+	tests := []struct {
+		name         string
+		filter       history.Filter
+		expectCount  int
+		expectNewest string
+	}{
+		{name: "Everything newest first", filter: history.Filter{}, expectCount: 4, expectNewest: "backfill"},
+		{name: "By report", filter: history.Filter{Report: "circulation"}, expectCount: 1, expectNewest: "manual"},
+		{name: "By trigger", filter: history.Filter{Report: "gate_counts", Trigger: "cron"}, expectCount: 2, expectNewest: "cron"},
+		{name: "Failed", filter: history.Filter{Status: history.StatusFailed}, expectCount: 1, expectNewest: "cron"},
+		{name: "Since", filter: history.Filter{Since: start.Add(30 * time.Hour)}, expectCount: 2, expectNewest: "backfill"},
+		{name: "Limit", filter: history.Filter{Limit: 1}, expectCount: 1, expectNewest: "backfill"},
+		{name: "Limit by report", filter: history.Filter{Report: "gate_counts", Limit: 5}, expectCount: 3, expectNewest: "backfill"},
+		{name: "Not trigger", filter: history.Filter{Report: "gate_counts", NotTrigger: "backfill", Limit: 1}, expectCount: 1, expectNewest: "cron"},
+		{name: "Unknown report", filter: history.Filter{Report: "missing"}, expectCount: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := store.List(tt.filter)
+			if err != nil {
+				t.Fatalf("List() failed: %v", err)
+			}
+			if len(records) != tt.expectCount {
+				t.Fatalf("Expected %d records, got %d", tt.expectCount, len(records))
+			}
+			if tt.expectCount > 0 && records[0].Trigger != tt.expectNewest {
+				t.Errorf("Expected newest record to be %s, got %s", tt.expectNewest, records[0].Trigger)
+			}
+		})
+	}
+
+	records, _ := store.List(history.Filter{Status: history.StatusFailed})
+	got, err := store.Get(records[0].ID)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if got.Error != "database error" || got.Duration != time.Minute {
+		t.Errorf("Unexpected record %+v", got)
+	}
+}
+
+func TestStore_ListLimitReadsTail(t *testing.T) {
+	store := history.NewStore(t.TempDir())
+	start := time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)
+
+	// Enough history to span several of the chunks read from the end
+	for i := 0; i < 2000; i++ {
+		rec := history.Record{
+			ID:       history.NewID(start.Add(time.Duration(i) * time.Hour)),
+			Report:   "gate_counts",
+			Trigger:  "cron",
+			Start:    start.Add(time.Duration(i) * time.Hour),
+			Rows:     i,
+			Warnings: []string{strings.Repeat("x", i%300)},
+		}
+		var err error
+		if i%2 == 1 {
+			err = errors.New("database error")
+		}
+		rec.Finish(rec.Start.Add(time.Minute), err)
+		if err := store.Append(rec); err != nil {
+			t.Fatalf("Append() failed: %v", err)
+		}
+	}
+
+	all, err := store.List(history.Filter{Report: "gate_counts", Status: history.StatusSucceeded})
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	for _, limit := range []int{1, 30, 999, 1000, 5000} {
+		records, err := store.List(history.Filter{Report: "gate_counts", Status: history.StatusSucceeded, Limit: limit})
+		if err != nil {
+			t.Fatalf("List() failed: %v", err)
+		}
+		expected := all[:min(limit, len(all))]
+		if len(records) != len(expected) {
+			t.Fatalf("Expected %d records, got %d", len(expected), len(records))
+		}
+		for i := range records {
+			if records[i].ID != expected[i].ID {
+				t.Fatalf("Expected record %d to be %s, got %s", i, expected[i].ID, records[i].ID)
+			}
+		}
+	}
+}
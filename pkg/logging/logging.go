@@ -0,0 +1,158 @@
+// Package logging builds encode's log handler: text or JSON lines, written to
+// stdout or to a log file that is rotated by size
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// Log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Options configure the log handler
+type Options struct {
+	Level slog.Level
+	// Format is text (default) or json
+	Format string
+	// File is written instead of stdout when set
+	File string
+	// MaxSize is the size in bytes the log file is rotated at; zero never rotates
+	MaxSize int64
+	// MaxFiles is how many rotated files are kept, as File.1 (newest) to File.N
+	MaxFiles int
+}
+
+// NewHandler returns a handler for opts and the log file it writes to, if any,
+// for the caller to close
+func NewHandler(opts Options) (slog.Handler, io.Closer, error) {
+	var w io.Writer = os.Stdout
+	var closer io.Closer
+	if opts.File != "" {
+		f, err := OpenRotatingFile(opts.File, opts.MaxSize, opts.MaxFiles)
+		if err != nil {
+			return nil, nil, err
+		}
+		w, closer = f, f
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: opts.Level}
+	switch opts.Format {
+	case "", FormatText:
+		return slog.NewTextHandler(w, handlerOpts), closer, nil
+	case FormatJSON:
+		return slog.NewJSONHandler(w, handlerOpts), closer, nil
+	}
+	if closer != nil {
+		closer.Close()
+	}
+	return nil, nil, fmt.Errorf("invalid log format '%s': must be %s or %s", opts.Format, FormatText, FormatJSON)
+}
+
+// RotatingFile is a log file that is renamed to name.1 once writing to it
+// would take it past its maximum size, shifting older files up to name.N
+type RotatingFile struct {
+	mu       sync.Mutex
+	name     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+// OpenRotatingFile opens name for appending, creating its directory
+func OpenRotatingFile(name string, maxSize int64, maxFiles int) (*RotatingFile, error) {
+	f := &RotatingFile{name: name, maxSize: maxSize, maxFiles: maxFiles}
+	err := os.MkdirAll(filepath.Dir(name), 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	err = f.open()
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// Write appends p, rotating first if p would not fit. A line longer than the
+// maximum size is still written whole, to a file of its own
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		err := f.rotate()
+		if err != nil {
+			// The logger can't log its own failure, and losing lines is worse
+			// than an oversized file
+			fmt.Fprintf(os.Stderr, "encode: %v\n", err)
+		}
+		if f.file == nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate shifts name.1 to name.2 and so on, dropping the oldest, moves the
+// current file to name.1 and starts a new one. The file is reopened even if
+// it could not be moved
+func (f *RotatingFile) rotate() error {
+	err := f.file.Close()
+	f.file = nil
+	for i := f.maxFiles - 1; i >= 1 && err == nil; i-- {
+		err = os.Rename(f.rotated(i), f.rotated(i+1))
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+	}
+	if err == nil && f.maxFiles > 0 {
+		err = os.Rename(f.name, f.rotated(1))
+	} else if err == nil {
+		err = os.Remove(f.name)
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	return errors.Join(err, f.open())
+}
+
+func (f *RotatingFile) rotated(i int) string {
+	return f.name + "." + strconv.Itoa(i)
+}
+
+// Close closes the current file
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Close()
+}
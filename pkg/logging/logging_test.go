@@ -0,0 +1,107 @@
+package logging_test
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lehigh-university-libraries/encode/pkg/logging"
+)
+
+func TestRotatingFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxFiles    int
+		expectFiles []string
+	}{
+		{
+			name:        "Keeps the newest rotated files",
+			maxFiles:    2,
+			expectFiles: []string{"encode.log", "encode.log.1", "encode.log.2"},
+		},
+		{
+			name:        "No rotated files",
+			maxFiles:    0,
+			expectFiles: []string{"encode.log"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			name := filepath.Join(dir, "logs", "encode.log")
+			f, err := logging.OpenRotatingFile(name, 10, tt.maxFiles)
+			if err != nil {
+				t.Fatalf("OpenRotatingFile() failed: %v", err)
+			}
+			for _, line := range []string{"line 1\n", "line 2\n", "line 3\n", "line 4\n"} {
+				_, err = f.Write([]byte(line))
+				if err != nil {
+					t.Fatalf("Write() failed: %v", err)
+				}
+			}
+			err = f.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			entries, err := os.ReadDir(filepath.Join(dir, "logs"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var files []string
+			for _, e := range entries {
+				files = append(files, e.Name())
+			}
+			if strings.Join(files, " ") != strings.Join(tt.expectFiles, " ") {
+				t.Fatalf("Expected files %v, got %v", tt.expectFiles, files)
+			}
+			data, err := os.ReadFile(name)
+			if err != nil || string(data) != "line 4\n" {
+				t.Errorf("Expected the current file to hold the last line, got %q, %v", data, err)
+			}
+			if tt.maxFiles > 0 {
+				data, err = os.ReadFile(name + ".1")
+				if err != nil || string(data) != "line 3\n" {
+					t.Errorf("Expected the newest rotated file to hold the line before, got %q, %v", data, err)
+				}
+			}
+		})
+	}
+}
+
+func TestNewHandler(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "encode.log")
+	handler, closer, err := logging.NewHandler(logging.Options{Level: slog.LevelInfo, Format: logging.FormatJSON, File: name, MaxSize: 1024 * 1024, MaxFiles: 1})
+	if err != nil {
+		t.Fatalf("NewHandler() failed: %v", err)
+	}
+	log := slog.New(handler)
+	log.Debug("hidden")
+	log.Info("Report run finished", "report", "users", "run", "r1")
+	err = closer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry map[string]any
+	err = json.Unmarshal(data, &entry)
+	if err != nil {
+		t.Fatalf("Expected one JSON line, got %q", data)
+	}
+	if entry["msg"] != "Report run finished" || entry["run"] != "r1" {
+		t.Errorf("Unexpected log line %q", data)
+	}
+
+	_, _, err = logging.NewHandler(logging.Options{Format: "xml"})
+	if err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
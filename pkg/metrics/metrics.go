@@ -0,0 +1,234 @@
+// Package metrics keeps counters, gauges and histograms and serves them in
+// the Prometheus text exposition format
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Default is the registry encode's packages record to and `encode run` serves
+var Default = NewRegistry()
+
+// DefaultBuckets are the upper bounds, in seconds, of duration histograms.
+// They reach further than Prometheus' defaults because queries can take minutes
+var DefaultBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600}
+
+// Registry holds metric families and the hooks run before they are served
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+	hooks    []func()
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+type family struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+	series  map[string]*series
+}
+
+type series struct {
+	labels []string
+	value  float64
+	// counts and sum are used by histograms; counts are per bucket, not cumulative
+	counts []uint64
+	sum    float64
+}
+
+// Vec is a metric family with labels. Label values are passed in the order
+// the labels were declared
+type Vec struct {
+	r *Registry
+	f *family
+}
+
+// Counter declares a counter family
+func (r *Registry) Counter(name, help string, labels ...string) *Vec {
+	return r.declare(name, help, "counter", labels, nil)
+}
+
+// Gauge declares a gauge family
+func (r *Registry) Gauge(name, help string, labels ...string) *Vec {
+	return r.declare(name, help, "gauge", labels, nil)
+}
+
+// Histogram declares a histogram family with the given bucket upper bounds
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Vec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return r.declare(name, help, "histogram", labels, buckets)
+}
+
+// declare returns the family with the name, creating it the first time
+func (r *Registry) declare(name, help, kind string, labels []string, buckets []float64) *Vec {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{name: name, help: help, kind: kind, labels: labels, buckets: buckets, series: make(map[string]*series)}
+		r.families[name] = f
+	}
+	return &Vec{r: r, f: f}
+}
+
+// OnScrape adds a hook that runs before every scrape, for gauges that are
+// cheaper to compute when asked for, like the next scheduled run
+func (r *Registry) OnScrape(hook func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks = append(r.hooks, hook)
+}
+
+func (v *Vec) series(values []string) *series {
+	if len(values) != len(v.f.labels) {
+		panic(fmt.Sprintf("metric %s takes %d label values, got %d", v.f.name, len(v.f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	s, ok := v.f.series[key]
+	if !ok {
+		s = &series{labels: append([]string(nil), values...)}
+		if v.f.kind == "histogram" {
+			s.counts = make([]uint64, len(v.f.buckets)+1)
+		}
+		v.f.series[key] = s
+	}
+	return s
+}
+
+// Inc adds one to a counter
+func (v *Vec) Inc(values ...string) {
+	v.Add(1, values...)
+}
+
+// Add adds delta to a counter or gauge
+func (v *Vec) Add(delta float64, values ...string) {
+	v.r.mu.Lock()
+	defer v.r.mu.Unlock()
+	v.series(values).value += delta
+}
+
+// Set sets a gauge
+func (v *Vec) Set(value float64, values ...string) {
+	v.r.mu.Lock()
+	defer v.r.mu.Unlock()
+	v.series(values).value = value
+}
+
+// Delete removes a series, e.g. the next run of a paused report
+func (v *Vec) Delete(values ...string) {
+	v.r.mu.Lock()
+	defer v.r.mu.Unlock()
+	delete(v.f.series, strings.Join(values, "\xff"))
+}
+
+// Observe records a value in a histogram
+func (v *Vec) Observe(value float64, values ...string) {
+	v.r.mu.Lock()
+	defer v.r.mu.Unlock()
+	s := v.series(values)
+	i := sort.SearchFloat64s(v.f.buckets, value)
+	s.counts[i]++
+	s.sum += value
+}
+
+// Write writes every family in the text exposition format, sorted by name
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	hooks := append([]func(){}, r.hooks...)
+	r.mu.Unlock()
+	for _, hook := range hooks {
+		hook()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		f := r.families[name]
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.name, f.kind)
+		keys := make([]string, 0, len(f.series))
+		for k := range f.series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			s := f.series[k]
+			if f.kind != "histogram" {
+				fmt.Fprintf(&b, "%s%s %s\n", f.name, labelString(f.labels, s.labels, "", ""), formatFloat(s.value))
+				continue
+			}
+			var cumulative uint64
+			for i, le := range f.buckets {
+				cumulative += s.counts[i]
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", f.name, labelString(f.labels, s.labels, "le", formatFloat(le)), cumulative)
+			}
+			cumulative += s.counts[len(f.buckets)]
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", f.name, labelString(f.labels, s.labels, "le", "+Inf"), cumulative)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", f.name, labelString(f.labels, s.labels, "", ""), formatFloat(s.sum))
+			fmt.Fprintf(&b, "%s_count%s %d\n", f.name, labelString(f.labels, s.labels, "", ""), cumulative)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Handler serves the registry for Prometheus to scrape
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.Write(w)
+	})
+}
+
+func labelString(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	pairs := make([]string, 0, len(names)+1)
+	for i, n := range names {
+		pairs = append(pairs, n+`="`+escapeLabel(values[i])+`"`)
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+extraValue+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,59 @@
+package metrics_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lehigh-university-libraries/encode/pkg/metrics"
+)
+
+func TestRegistry(t *testing.T) {
+	r := metrics.NewRegistry()
+	runs := r.Counter("encode_runs_total", "Runs by status", "report", "status")
+	runs.Inc("gate_counts", "succeeded")
+	runs.Inc("gate_counts", "succeeded")
+	runs.Inc(`say "hi"`, "failed")
+
+	rows := r.Gauge("encode_rows", "Rows in the last run", "report")
+	rows.Set(3000, "gate_counts")
+	rows.Set(12, "overdue")
+	rows.Delete("overdue")
+
+	latency := r.Histogram("encode_latency_seconds", "Latency", []float64{1, 0.1}, "connector")
+	latency.Observe(0.05, "postgresql")
+	latency.Observe(0.5, "postgresql")
+	latency.Observe(5, "postgresql")
+
+	scraped := 0
+	r.OnScrape(func() { scraped++ })
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	expected := `# HELP encode_latency_seconds Latency
+# TYPE encode_latency_seconds histogram
+encode_latency_seconds_bucket{connector="postgresql",le="0.1"} 1
+encode_latency_seconds_bucket{connector="postgresql",le="1"} 2
+encode_latency_seconds_bucket{connector="postgresql",le="+Inf"} 3
+encode_latency_seconds_sum{connector="postgresql"} 5.55
+encode_latency_seconds_count{connector="postgresql"} 3
+# HELP encode_rows Rows in the last run
+# TYPE encode_rows gauge
+encode_rows{report="gate_counts"} 3000
+# HELP encode_runs_total Runs by status
+# TYPE encode_runs_total counter
+encode_runs_total{report="gate_counts",status="succeeded"} 2
+encode_runs_total{report="say \"hi\"",status="failed"} 1
+`
+	if body != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, body)
+	}
+	if scraped != 1 {
+		t.Errorf("Expected the scrape hook to run once, got %d", scraped)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type %q", ct)
+	}
+}
@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmailConfig sends notifications through an SMTP server
+type EmailConfig struct {
+	Host string `yaml:"host"`
+	// Port defaults to 587; the connection is upgraded with STARTTLS when the server offers it
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+	// To is added to the report's owners
+	To []string `yaml:"to"`
+	// Timeout bounds connecting to the server and sending the message (default: 30s)
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Email is a channel that mails the report's owners and any extra recipients
+type Email struct {
+	name   string
+	config EmailConfig
+}
+
+// NewEmail returns an SMTP email channel
+func NewEmail(name string, config EmailConfig) (*Email, error) {
+	if config.Host == "" {
+		return nil, errors.New("email notifications require host")
+	}
+	if config.From == "" {
+		return nil, errors.New("email notifications require from")
+	}
+	if config.Port == 0 {
+		config.Port = 587
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 30 * time.Second
+	}
+	return &Email{name: name, config: config}, nil
+}
+
+func (m *Email) Name() string {
+	return m.name
+}
+
+func (m *Email) Notify(e Event) error {
+	to := slices.Concat(e.Owners, m.config.To)
+	slices.Sort(to)
+	to = slices.Compact(to)
+	if len(to) == 0 {
+		return errors.New("no recipients: set owners on the report or to on the channel")
+	}
+
+	err := m.send(to, m.message(e, to))
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// send delivers msg like smtp.SendMail, but gives up once the timeout passes
+// so a server that stops responding can't hold up the run
+func (m *Email) send(to []string, msg []byte) error {
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	conn, err := net.DialTimeout("tcp", addr, m.config.Timeout)
+	if err != nil {
+		return err
+	}
+	err = conn.SetDeadline(time.Now().Add(m.config.Timeout))
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	c, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		err = c.StartTLS(&tls.Config{ServerName: m.config.Host})
+		if err != nil {
+			return err
+		}
+	}
+	if m.config.Username != "" {
+		err = c.Auth(smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host))
+		if err != nil {
+			return err
+		}
+	}
+	err = c.Mail(m.config.From)
+	if err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		err = c.Rcpt(rcpt)
+		if err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(msg)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (m *Email) message(e Event, to []string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.config.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", e.Subject())
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(e.Text(), "\n", "\r\n"))
+	return []byte(b.String())
+}
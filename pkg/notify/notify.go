@@ -0,0 +1,132 @@
+// Package notify tells report owners about failed, halted and recovered runs,
+// and runs that succeeded with warnings
+package notify
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Event kinds
+const (
+	// EventFailure is a run that failed
+	EventFailure = "failure"
+	// EventHalt is a run stopped by a validation check: halted or quarantined
+	EventHalt = "halt"
+	// EventRecovery is the first successful run after a failure or halt
+	EventRecovery = "recovery"
+	// EventWarning is a successful run with warnings, such as schema drift
+	EventWarning = "warning"
+)
+
+// Event is what a channel is told about a run
+type Event struct {
+	Kind    string `json:"event"`
+	Report  string `json:"report"`
+	RunID   string `json:"run_id"`
+	Status  string `json:"status"`
+	Trigger string `json:"trigger"`
+	// ScheduledTime is the run's logical time
+	ScheduledTime time.Time `json:"scheduled_time"`
+	Error         string    `json:"error,omitempty"`
+	Warnings      []string  `json:"warnings,omitempty"`
+	// LastSuccess is the scheduled time of the report's last successful run
+	LastSuccess time.Time `json:"last_success,omitzero"`
+	// Repeated counts the earlier notifications of the same problem
+	Repeated int      `json:"repeated,omitempty"`
+	Owners   []string `json:"owners,omitempty"`
+}
+
+// ValidateKind checks that kind is a known event
+func ValidateKind(kind string) error {
+	switch kind {
+	case EventFailure, EventHalt, EventRecovery, EventWarning:
+		return nil
+	}
+	return fmt.Errorf("invalid event '%s': must be %s, %s, %s or %s", kind, EventFailure, EventHalt, EventRecovery, EventWarning)
+}
+
+// Subject is a one-line summary, e.g. "encode: gate_counts failed"
+func (e Event) Subject() string {
+	switch e.Kind {
+	case EventRecovery:
+		return fmt.Sprintf("encode: %s recovered", e.Report)
+	case EventHalt:
+		return fmt.Sprintf("encode: %s halted by a check", e.Report)
+	case EventWarning:
+		return fmt.Sprintf("encode: %s finished with warnings", e.Report)
+	}
+	return fmt.Sprintf("encode: %s failed", e.Report)
+}
+
+// Text is the body of the notification
+func (e Event) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Report: %s\n", e.Report)
+	fmt.Fprintf(&b, "Run: %s (%s, %s)\n", e.RunID, e.Trigger, e.Status)
+	fmt.Fprintf(&b, "Scheduled: %s\n", e.ScheduledTime.Format(time.RFC3339))
+	if e.LastSuccess.IsZero() {
+		b.WriteString("Last success: never\n")
+	} else {
+		fmt.Fprintf(&b, "Last success: %s\n", e.LastSuccess.Format(time.RFC3339))
+	}
+	if len(e.Owners) > 0 {
+		fmt.Fprintf(&b, "Owners: %s\n", strings.Join(e.Owners, ", "))
+	}
+	if e.Repeated > 0 {
+		fmt.Fprintf(&b, "Still failing after %d earlier notifications\n", e.Repeated)
+	}
+	if e.Error != "" {
+		fmt.Fprintf(&b, "\nError: %s\n", e.Error)
+	}
+	for _, w := range e.Warnings {
+		fmt.Fprintf(&b, "Warning: %s\n", w)
+	}
+	return b.String()
+}
+
+// Notifier delivers events to one channel
+type Notifier interface {
+	// Name identifies the channel in logs
+	Name() string
+	Notify(e Event) error
+}
+
+// Filter wraps n so it only receives the listed event kinds. No kinds means all
+func Filter(n Notifier, kinds []string) Notifier {
+	if len(kinds) == 0 {
+		return n
+	}
+	return &filtered{Notifier: n, kinds: kinds}
+}
+
+type filtered struct {
+	Notifier
+	kinds []string
+}
+
+func (f *filtered) Notify(e Event) error {
+	if !slices.Contains(f.kinds, e.Kind) {
+		return nil
+	}
+	return f.Notifier.Notify(e)
+}
+
+// NotifyAll sends e to every channel. A failed channel does not stop the
+// others; the number that failed is returned
+func NotifyAll(notifiers []Notifier, e Event) int {
+	failed := 0
+	for _, n := range notifiers {
+		err := n.Notify(e)
+		if err != nil {
+			failed++
+			slog.Error("Notification failed", "report", e.Report, "run", e.RunID, "channel", n.Name(), "event", e.Kind, "err", err)
+			continue
+		}
+		slog.Debug("Notification sent", "report", e.Report, "run", e.RunID, "channel", n.Name(), "event", e.Kind)
+	}
+	return failed
+}
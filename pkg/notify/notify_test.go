@@ -0,0 +1,256 @@
+package notify_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/notify"
+)
+
+var event = notify.Event{
+	Kind:          notify.EventFailure,
+	Report:        "gate_counts",
+	RunID:         "20250101T060000Z-0a1b2c3d",
+	Status:        "failed",
+	Trigger:       "cron",
+	ScheduledTime: time.Date(2025, 1, 1, 6, 0, 0, 0, time.UTC),
+	Error:         "unable to fetch report: connection refused",
+	LastSuccess:   time.Date(2024, 12, 31, 6, 0, 0, 0, time.UTC),
+	Owners:        []string{"owner@example.edu"},
+}
+
+func TestWebhooks(t *testing.T) {
+	tests := []struct {
+		name   string
+		new    func(string, notify.WebhookConfig) (*notify.Webhook, error)
+		expect []string
+	}{
+		{
+			name:   "Webhook",
+			new:    notify.NewWebhook,
+			expect: []string{`"event":"failure"`, `"run_id":"20250101T060000Z-0a1b2c3d"`, `"last_success":"2024-12-31T06:00:00Z"`},
+		},
+		{
+			name:   "Slack",
+			new:    notify.NewSlack,
+			expect: []string{`"text":"*encode: gate_counts failed*`, `connection refused`},
+		},
+		{
+			name:   "Teams",
+			new:    notify.NewTeams,
+			expect: []string{`"@type":"MessageCard"`, `"title":"encode: gate_counts failed"`, `Last success: 2024-12-31T06:00:00Z  \n`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body, auth string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				body = string(b)
+				auth = r.Header.Get("Authorization")
+			}))
+			defer server.Close()
+
+			n, err := tt.new("hook", notify.WebhookConfig{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer token"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = n.Notify(event)
+			if err != nil {
+				t.Fatalf("Notify() failed: %v", err)
+			}
+			if !json.Valid([]byte(body)) {
+				t.Fatalf("Expected a JSON body, got %s", body)
+			}
+			for _, want := range tt.expect {
+				if !strings.Contains(body, want) {
+					t.Errorf("Expected body to contain %s, got %s", want, body)
+				}
+			}
+			if auth != "Bearer token" {
+				t.Errorf("Expected configured headers to be sent, got %q", auth)
+			}
+		})
+	}
+}
+
+func TestWebhook_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no_service", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	n, err := notify.NewSlack("slack", notify.WebhookConfig{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(event); err == nil {
+		t.Errorf("Expected error but got none")
+	}
+	if failed := notify.NotifyAll([]notify.Notifier{n}, event); failed != 1 {
+		t.Errorf("Expected 1 failed channel, got %d", failed)
+	}
+
+	_, err = notify.NewWebhook("hook", notify.WebhookConfig{})
+	if err == nil {
+		t.Errorf("Expected error for a webhook without url but got none")
+	}
+}
+
+type recorder struct {
+	events []notify.Event
+}
+
+func (r *recorder) Name() string { return "recorder" }
+
+func (r *recorder) Notify(e notify.Event) error {
+	r.events = append(r.events, e)
+	return nil
+}
+
+func TestFilter(t *testing.T) {
+	rec := &recorder{}
+	n := notify.Filter(rec, []string{notify.EventRecovery})
+	_ = n.Notify(event)
+	_ = n.Notify(notify.Event{Kind: notify.EventRecovery})
+	if len(rec.events) != 1 || rec.events[0].Kind != notify.EventRecovery {
+		t.Errorf("Expected only the recovery, got %+v", rec.events)
+	}
+}
+
+// smtpServer accepts one message and returns what was sent
+func smtpServer(t *testing.T) (int, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	sent := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost")
+		var b strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			b.WriteString(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					b.WriteString(line)
+				}
+				reply("250 ok")
+			case cmd == "QUIT":
+				reply("221 bye")
+				sent <- b.String()
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, sent
+}
+
+func TestEmail(t *testing.T) {
+	port, sent := smtpServer(t)
+	n, err := notify.NewEmail("email", notify.EmailConfig{
+		Host: "127.0.0.1",
+		Port: port,
+		From: "encode@example.edu",
+		To:   []string{"team@example.edu", "owner@example.edu"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = n.Notify(event)
+	if err != nil {
+		t.Fatalf("Notify() failed: %v", err)
+	}
+
+	var session string
+	select {
+	case session = <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the message")
+	}
+	for _, want := range []string{
+		"RCPT TO:<owner@example.edu>",
+		"RCPT TO:<team@example.edu>",
+		"Subject: encode: gate_counts failed",
+		"Run: 20250101T060000Z-0a1b2c3d (cron, failed)",
+		"Error: unable to fetch report: connection refused",
+	} {
+		if !strings.Contains(session, want) {
+			t.Errorf("Expected session to contain %q, got:\n%s", want, session)
+		}
+	}
+	if strings.Count(session, "RCPT TO:<owner@example.edu>") != 1 {
+		t.Errorf("Expected each recipient once, got:\n%s", session)
+	}
+
+	_, err = notify.NewEmail("email", notify.EmailConfig{Host: "127.0.0.1", Port: port})
+	if err == nil {
+		t.Errorf("Expected error for email without from but got none")
+	}
+}
+
+func TestEmail_Timeout(t *testing.T) {
+	// A server that accepts the connection but never greets
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(5 * time.Second)
+		}
+	}()
+
+	n, err := notify.NewEmail("email", notify.EmailConfig{
+		Host:    "127.0.0.1",
+		Port:    ln.Addr().(*net.TCPAddr).Port,
+		From:    "encode@example.edu",
+		Timeout: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	err = n.Notify(event)
+	if err == nil {
+		t.Fatal("Expected error from a server that never responds but got none")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected Notify() to give up after the timeout, took %s", elapsed)
+	}
+}
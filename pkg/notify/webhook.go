@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WebhookConfig posts notifications to a URL
+type WebhookConfig struct {
+	URL string `yaml:"url"`
+	// Headers are added to every request, e.g. an Authorization header
+	Headers map[string]string `yaml:"headers"`
+}
+
+// Webhook is a channel that posts JSON to a URL. A generic webhook gets the
+// event itself; Slack and Teams get a message in the shape they expect
+type Webhook struct {
+	name    string
+	config  WebhookConfig
+	payload func(Event) any
+	client  *http.Client
+}
+
+// NewWebhook returns a channel that posts each event as JSON
+func NewWebhook(name string, config WebhookConfig) (*Webhook, error) {
+	return newWebhook(name, config, func(e Event) any { return e })
+}
+
+// NewSlack returns a channel for a Slack incoming webhook
+func NewSlack(name string, config WebhookConfig) (*Webhook, error) {
+	return newWebhook(name, config, func(e Event) any {
+		return map[string]string{"text": "*" + e.Subject() + "*\n" + e.Text()}
+	})
+}
+
+// NewTeams returns a channel for a Microsoft Teams incoming webhook
+func NewTeams(name string, config WebhookConfig) (*Webhook, error) {
+	return newWebhook(name, config, func(e Event) any {
+		return map[string]any{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  e.Subject(),
+			"title":    e.Subject(),
+			// Teams renders markdown, where a line break needs two trailing spaces
+			"text": markdownLines(e.Text()),
+		}
+	})
+}
+
+func newWebhook(name string, config WebhookConfig, payload func(Event) any) (*Webhook, error) {
+	if config.URL == "" {
+		return nil, errors.New("webhook notifications require url")
+	}
+	return &Webhook{
+		name:    name,
+		config:  config,
+		payload: payload,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (w *Webhook) Name() string {
+	return w.name
+}
+
+func (w *Webhook) Notify(e Event) error {
+	body, err := json.Marshal(w.payload(e))
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, w.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, string(msg))
+	}
+	return nil
+}
+
+func markdownLines(s string) string {
+	return strings.ReplaceAll(s, "\n", "  \n")
+}
@@ -0,0 +1,129 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DefaultTerms split the calendar year into spring, summer and fall terms
+var DefaultTerms = []TermConfig{
+	{Name: "Spring", Start: "01-01"},
+	{Name: "Summer", Start: "06-01"},
+	{Name: "Fall", Start: "09-01"},
+}
+
+// TermConfig is a term starting on a month and day each year
+type TermConfig struct {
+	Name string `yaml:"name"`
+	// Start is the first day of the term as MM-DD
+	Start string `yaml:"start"`
+}
+
+// Calendar is the academic calendar used by the term helpers
+type Calendar struct {
+	// YearStart is the first day of the academic year as MM-DD (default 07-01)
+	YearStart string `yaml:"yearStart"`
+	// Terms partition each calendar year; each term runs until the next one starts
+	Terms []TermConfig `yaml:"terms"`
+}
+
+// Term is one term occurrence
+type Term struct {
+	Name  string
+	Start time.Time
+	// End is the first day after the term
+	End time.Time
+}
+
+// Validate checks the calendar's dates
+func (c Calendar) Validate() error {
+	if c.YearStart != "" {
+		if _, err := parseMonthDay(c.YearStart); err != nil {
+			return err
+		}
+	}
+	seen := make(map[string]bool)
+	for _, term := range c.Terms {
+		if term.Name == "" {
+			return errors.New("academic terms need a name")
+		}
+		md, err := parseMonthDay(term.Start)
+		if err != nil {
+			return fmt.Errorf("term '%s': %w", term.Name, err)
+		}
+		if seen[md] {
+			return fmt.Errorf("term '%s' starts on the same day as another term", term.Name)
+		}
+		seen[md] = true
+	}
+	return nil
+}
+
+// Term returns the term t falls in
+func (c Calendar) Term(t time.Time) Term {
+	terms := c.terms()
+	starts := func(year int) []time.Time {
+		out := make([]time.Time, len(terms))
+		for i, term := range terms {
+			out[i] = monthDay(year, term.Start, t.Location())
+		}
+		return out
+	}
+
+	this := starts(t.Year())
+	// Before the first term starts, t belongs to last year's final term
+	if t.Before(this[0]) {
+		last := len(terms) - 1
+		return Term{Name: terms[last].Name, Start: starts(t.Year() - 1)[last], End: this[0]}
+	}
+	for i := len(terms) - 1; i >= 0; i-- {
+		if t.Before(this[i]) {
+			continue
+		}
+		end := starts(t.Year() + 1)[0]
+		if i+1 < len(terms) {
+			end = this[i+1]
+		}
+		return Term{Name: terms[i].Name, Start: this[i], End: end}
+	}
+	return Term{}
+}
+
+// AcademicYearStart returns the first day of the academic year t falls in
+func (c Calendar) AcademicYearStart(t time.Time) time.Time {
+	md := c.YearStart
+	if md == "" {
+		md = "07-01"
+	}
+	start := monthDay(t.Year(), md, t.Location())
+	if t.Before(start) {
+		return monthDay(t.Year()-1, md, t.Location())
+	}
+	return start
+}
+
+// terms returns the configured terms sorted by start date
+func (c Calendar) terms() []TermConfig {
+	terms := c.Terms
+	if len(terms) == 0 {
+		terms = DefaultTerms
+	}
+	sorted := append([]TermConfig{}, terms...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	return sorted
+}
+
+func parseMonthDay(s string) (string, error) {
+	t, err := time.Parse("01-02", s)
+	if err != nil {
+		return "", fmt.Errorf("invalid date '%s': use MM-DD", s)
+	}
+	return t.Format("01-02"), nil
+}
+
+func monthDay(year int, s string, loc *time.Location) time.Time {
+	t, _ := time.Parse("01-02", s)
+	return time.Date(year, t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,135 @@
+// Package render expands Go templates in report queries at run time
+package render
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Data is what a query template can reference
+type Data struct {
+	// ReportName is the name of the report being run
+	ReportName string
+	// ScheduledTime is the logical time of the run, e.g. the cron tick it belongs to
+	ScheduledTime time.Time
+	// PeriodStart and PeriodEnd bound the data the run covers: from the
+	// schedule's previous tick to ScheduledTime, or a backfill window
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	// LastSuccess is when the report last finished with every sink succeeding (zero if never)
+	LastSuccess time.Time
+	// Watermark is an incremental report's current watermark
+	Watermark string
+	// Params are the report's query params, before rendering
+	Params map[string]string
+}
+
+// Dialect is the SQL dialect the quoting helpers escape string literals for
+type Dialect string
+
+// Dialects
+const (
+	// DialectStandard only doubles single quotes, as PostgreSQL does
+	DialectStandard Dialect = ""
+	// DialectMySQL also escapes backslashes, which MariaDB and MySQL treat as
+	// an escape character inside string literals by default
+	DialectMySQL Dialect = "mysql"
+)
+
+// Parse parses text as a query template using the helpers in Funcs
+func Parse(name, text string, cal Calendar, dialect Dialect) (*template.Template, error) {
+	t, err := template.New(name).Option("missingkey=error").Funcs(Funcs(cal, dialect)).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %s: %w", name, err)
+	}
+	return t, nil
+}
+
+// Execute renders t with data
+func Execute(t *template.Template, data Data) (string, error) {
+	var b strings.Builder
+	err := t.Execute(&b, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", t.Name(), err)
+	}
+	return b.String(), nil
+}
+
+// Funcs are the helpers available to query templates. Time helpers take the
+// time last so they can be piped: {{ .ScheduledTime | addDays -1 | isoDate }}.
+// The quoting helpers escape literals for dialect
+func Funcs(cal Calendar, dialect Dialect) template.FuncMap {
+	return template.FuncMap{
+		// Date math
+		"addDays":     func(n int, t time.Time) time.Time { return t.AddDate(0, 0, n) },
+		"addMonths":   func(n int, t time.Time) time.Time { return t.AddDate(0, n, 0) },
+		"addYears":    func(n int, t time.Time) time.Time { return t.AddDate(n, 0, 0) },
+		"addDuration": addDuration,
+		"startOfDay":  startOfDay,
+		"startOfWeek": func(t time.Time) time.Time {
+			// Weeks start on Monday
+			return startOfDay(t).AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+		},
+		"startOfMonth": func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()) },
+		"startOfYear":  func(t time.Time) time.Time { return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location()) },
+		"formatTime":   func(layout string, t time.Time) string { return t.Format(layout) },
+		"parseTime":    func(layout, s string) (time.Time, error) { return time.ParseInLocation(layout, s, time.Local) },
+		"isoDate":      func(t time.Time) string { return t.Format("2006-01-02") },
+		"isoDateTime":  func(t time.Time) string { return t.Format("2006-01-02 15:04:05") },
+		"now":          time.Now,
+
+		// Academic calendar
+		"termName":          func(t time.Time) string { return cal.Term(t).Name },
+		"termStart":         func(t time.Time) time.Time { return cal.Term(t).Start },
+		"termEnd":           func(t time.Time) time.Time { return cal.Term(t).End },
+		"academicYearStart": cal.AcademicYearStart,
+		"academicYearEnd":   func(t time.Time) time.Time { return cal.AcademicYearStart(t).AddDate(1, 0, 0) },
+
+		// SQL-safe quoting
+		"sqlQuote":   func(v any) string { return sqlQuote(dialect, v) },
+		"sqlIdent":   func(s string) string { return `"` + strings.ReplaceAll(s, `"`, `""`) + `"` },
+		"mysqlIdent": func(s string) string { return "`" + strings.ReplaceAll(s, "`", "``") + "`" },
+		"sqlList":    func(values []string) string { return sqlList(dialect, values) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+	}
+}
+
+func addDuration(d string, t time.Time) (time.Time, error) {
+	duration, err := time.ParseDuration(d)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.Add(duration), nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// sqlQuote renders v as a single-quoted SQL string literal of dialect
+func sqlQuote(dialect Dialect, v any) string {
+	var s string
+	switch v := v.(type) {
+	case time.Time:
+		s = v.Format("2006-01-02 15:04:05")
+	case fmt.Stringer:
+		s = v.String()
+	default:
+		s = fmt.Sprint(v)
+	}
+	if dialect == DialectMySQL {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// sqlList renders values as a comma-separated list of SQL string literals
+func sqlList(dialect Dialect, values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = sqlQuote(dialect, strings.TrimSpace(v))
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,137 @@
+package render_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/render"
+)
+
+func TestExecute(t *testing.T) {
+	data := render.Data{
+		ReportName:    "circulation",
+		ScheduledTime: time.Date(2024, 3, 15, 6, 30, 0, 0, time.UTC),
+		LastSuccess:   time.Date(2024, 3, 14, 6, 30, 0, 0, time.UTC),
+		Watermark:     "2024-03-14 05:00:00",
+		Params:        map[string]string{"branches": "Linderman, Fairchild"},
+	}
+
+	tests := []struct {
+		name        string
+		text        string
+		calendar    render.Calendar
+		dialect     render.Dialect
+		expect      string
+		expectError bool
+	}{
+		{
+			name:   "Date math",
+			text:   "{{ .ScheduledTime | addDays -1 | isoDate }} {{ .ScheduledTime | startOfMonth | addMonths -1 | isoDate }}",
+			expect: "2024-03-14 2024-02-01",
+		},
+		{
+			name:   "Start of week is Monday",
+			text:   "{{ .ScheduledTime | startOfWeek | isoDate }}",
+			expect: "2024-03-11",
+		},
+		{
+			name:   "Default terms",
+			text:   "{{ termName .ScheduledTime }} {{ .ScheduledTime | termStart | isoDate }} {{ .ScheduledTime | termEnd | isoDate }}",
+			expect: "Spring 2024-01-01 2024-06-01",
+		},
+		{
+			name: "Configured terms wrap the year",
+			text: "{{ termName .ScheduledTime }} {{ .ScheduledTime | termStart | isoDate }} {{ .ScheduledTime | termEnd | isoDate }} {{ .ScheduledTime | academicYearStart | isoDate }}",
+			calendar: render.Calendar{
+				YearStart: "08-15",
+				Terms: []render.TermConfig{
+					{Name: "Fall", Start: "08-20"},
+					{Name: "Spring", Start: "04-01"},
+				},
+			},
+			expect: "Fall 2023-08-20 2024-04-01 2023-08-15",
+		},
+		{
+			name:   "SQL quoting",
+			text:   "WHERE name = {{ sqlQuote \"O'Brien\" }} AND branch IN ({{ split \",\" .Params.branches | sqlList }}) AND {{ sqlIdent .ReportName }}",
+			expect: `WHERE name = 'O''Brien' AND branch IN ('Linderman', 'Fairchild') AND "circulation"`,
+		},
+		{
+			name:   "Standard quoting leaves backslashes",
+			text:   `{{ sqlQuote "x\\" }} {{ split "," "a\\,b'" | sqlList }}`,
+			expect: `'x\' 'a\', 'b'''`,
+		},
+		{
+			name:    "MySQL quoting escapes backslashes",
+			text:    `{{ sqlQuote "x\\" }} {{ split "," "a\\,b'" | sqlList }}`,
+			dialect: render.DialectMySQL,
+			expect:  `'x\\' 'a\\', 'b'''`,
+		},
+		{
+			name:   "Quoted times and watermark",
+			text:   "{{ sqlQuote .LastSuccess }} {{ sqlQuote .Watermark }}",
+			expect: "'2024-03-14 06:30:00' '2024-03-14 05:00:00'",
+		},
+		{
+			name:        "Missing param",
+			text:        "{{ .Params.missing }}",
+			expectError: true,
+		},
+		{
+			name:        "Unknown field",
+			text:        "{{ .Nope }}",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := render.Parse(tt.name, tt.text, tt.calendar, tt.dialect)
+			if err != nil {
+				t.Fatalf("Unexpected parse error: %v", err)
+			}
+			got, err := render.Execute(tmpl, data)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.expect {
+				t.Errorf("Expected %q, got %q", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestCalendar_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		calendar    render.Calendar
+		expectError bool
+	}{
+		{name: "Empty uses defaults", calendar: render.Calendar{}},
+		{name: "Bad year start", calendar: render.Calendar{YearStart: "July"}, expectError: true},
+		{name: "Unnamed term", calendar: render.Calendar{Terms: []render.TermConfig{{Start: "01-01"}}}, expectError: true},
+		{
+			name:        "Duplicate start",
+			calendar:    render.Calendar{Terms: []render.TermConfig{{Name: "A", Start: "01-01"}, {Name: "B", Start: "1-01"}}},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.calendar.Validate()
+			if tt.expectError && err == nil {
+				t.Errorf("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
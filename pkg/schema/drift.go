@@ -0,0 +1,154 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Kinds of schema change
+const (
+	ChangeAdded     = "added"
+	ChangeRemoved   = "removed"
+	ChangeReordered = "reordered"
+	ChangeType      = "type"
+)
+
+// InferredColumn is a column's name and the narrowest type all its values fit
+type InferredColumn struct {
+	Name string `json:"name"`
+	// Type is empty when the column had no values to infer it from
+	Type string `json:"type,omitempty"`
+}
+
+// inferOrder is tried narrowest first; string always fits
+var inferOrder = []string{TypeInteger, TypeNumber, TypeBoolean, TypeDate, TypeTimestamp}
+
+// Infer returns the schema of a result
+func Infer(columns []string, rows []map[string]string) []InferredColumn {
+	inferred := make([]InferredColumn, len(columns))
+	for i, name := range columns {
+		candidates := slices.Clone(inferOrder)
+		seen := false
+		for _, row := range rows {
+			v := row[name]
+			if v == "" {
+				continue
+			}
+			seen = true
+			candidates = slices.DeleteFunc(candidates, func(t string) bool {
+				_, err := Parse(t, v)
+				return err != nil
+			})
+			if len(candidates) == 0 {
+				break
+			}
+		}
+		inferred[i] = InferredColumn{Name: name}
+		switch {
+		case !seen:
+		case len(candidates) == 0:
+			inferred[i].Type = TypeString
+		default:
+			inferred[i].Type = candidates[0]
+		}
+	}
+	return inferred
+}
+
+// Change is one difference between two schemas
+type Change struct {
+	Kind   string
+	Column string
+	From   string
+	To     string
+}
+
+// Breaking reports whether the change can break a dataset built on the old
+// schema: a removed column or a changed type
+func (c Change) Breaking() bool {
+	return c.Kind == ChangeRemoved || c.Kind == ChangeType
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("column '%s' added", c.Column)
+	case ChangeRemoved:
+		return fmt.Sprintf("column '%s' removed", c.Column)
+	case ChangeReordered:
+		return fmt.Sprintf("columns reordered from [%s] to [%s]", c.From, c.To)
+	default:
+		return fmt.Sprintf("column '%s' changed type from %s to %s", c.Column, c.From, c.To)
+	}
+}
+
+// Drift is every change between two schemas
+type Drift []Change
+
+// Breaking returns the breaking changes
+func (d Drift) Breaking() Drift {
+	var breaking Drift
+	for _, c := range d {
+		if c.Breaking() {
+			breaking = append(breaking, c)
+		}
+	}
+	return breaking
+}
+
+// Strings describes each change
+func (d Drift) Strings() []string {
+	out := make([]string, len(d))
+	for i, c := range d {
+		out[i] = c.String()
+	}
+	return out
+}
+
+func (d Drift) String() string {
+	return strings.Join(d.Strings(), "; ")
+}
+
+// Diff returns how current differs from previous. Integer and number are
+// treated as the same type, and a column with no values matches any type
+func Diff(previous, current []InferredColumn) Drift {
+	var drift Drift
+	prev := make(map[string]InferredColumn, len(previous))
+	for _, c := range previous {
+		prev[c.Name] = c
+	}
+	cur := make(map[string]InferredColumn, len(current))
+	for _, c := range current {
+		cur[c.Name] = c
+	}
+
+	var prevOrder, curOrder []string
+	for _, c := range previous {
+		if _, ok := cur[c.Name]; !ok {
+			drift = append(drift, Change{Kind: ChangeRemoved, Column: c.Name})
+			continue
+		}
+		prevOrder = append(prevOrder, c.Name)
+	}
+	for _, c := range current {
+		p, ok := prev[c.Name]
+		if !ok {
+			drift = append(drift, Change{Kind: ChangeAdded, Column: c.Name})
+			continue
+		}
+		curOrder = append(curOrder, c.Name)
+		if !sameType(p.Type, c.Type) {
+			drift = append(drift, Change{Kind: ChangeType, Column: c.Name, From: p.Type, To: c.Type})
+		}
+	}
+	if !slices.Equal(prevOrder, curOrder) {
+		drift = append(drift, Change{Kind: ChangeReordered, From: strings.Join(prevOrder, ", "), To: strings.Join(curOrder, ", ")})
+	}
+	return drift
+}
+
+func sameType(a, b string) bool {
+	numeric := func(t string) bool { return t == TypeInteger || t == TypeNumber }
+	return a == b || a == "" || b == "" || (numeric(a) && numeric(b))
+}
@@ -0,0 +1,77 @@
+package schema_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lehigh-university-libraries/encode/pkg/schema"
+)
+
+func TestInfer(t *testing.T) {
+	columns := []string{"id", "amount", "active", "day", "updated", "name", "empty"}
+	rows := []map[string]string{
+		{"id": "1", "amount": "2", "active": "true", "day": "2024-01-01", "updated": "2024-01-01 10:00:00", "name": "Alice"},
+		{"id": "2", "amount": "2.5", "active": "false", "day": "2024-01-02", "updated": "2024-01-02T10:00:00Z", "name": "42"},
+	}
+	expected := []schema.InferredColumn{
+		{Name: "id", Type: "integer"},
+		{Name: "amount", Type: "number"},
+		{Name: "active", Type: "boolean"},
+		{Name: "day", Type: "date"},
+		{Name: "updated", Type: "timestamp"},
+		{Name: "name", Type: "string"},
+		{Name: "empty"},
+	}
+	got := schema.Infer(columns, rows)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	previous := []schema.InferredColumn{
+		{Name: "date", Type: "date"},
+		{Name: "branch", Type: "string"},
+		{Name: "count", Type: "integer"},
+	}
+
+	tests := []struct {
+		name           string
+		current        []schema.InferredColumn
+		expect         []string
+		expectBreaking int
+	}{
+		{
+			name:    "Unchanged, with integer widened to number",
+			current: []schema.InferredColumn{{Name: "date", Type: "date"}, {Name: "branch"}, {Name: "count", Type: "number"}},
+		},
+		{
+			name:    "Added column",
+			current: append(append([]schema.InferredColumn{}, previous...), schema.InferredColumn{Name: "visitors", Type: "integer"}),
+			expect:  []string{"column 'visitors' added"},
+		},
+		{
+			name:    "Reordered",
+			current: []schema.InferredColumn{{Name: "branch", Type: "string"}, {Name: "date", Type: "date"}, {Name: "count", Type: "integer"}},
+			expect:  []string{"columns reordered from [date, branch, count] to [branch, date, count]"},
+		},
+		{
+			name:           "Removed column and type change",
+			current:        []schema.InferredColumn{{Name: "date", Type: "string"}, {Name: "branch", Type: "string"}},
+			expect:         []string{"column 'count' removed", "column 'date' changed type from date to string"},
+			expectBreaking: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drift := schema.Diff(previous, tt.current)
+			if len(drift) != len(tt.expect) || (len(drift) > 0 && !reflect.DeepEqual(drift.Strings(), tt.expect)) {
+				t.Errorf("Expected %v, got %v", tt.expect, drift.Strings())
+			}
+			if len(drift.Breaking()) != tt.expectBreaking {
+				t.Errorf("Expected %d breaking changes, got %d", tt.expectBreaking, len(drift.Breaking()))
+			}
+		})
+	}
+}
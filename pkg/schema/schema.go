@@ -0,0 +1,282 @@
+// Package schema checks report results against a declared contract
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Column types
+const (
+	TypeString    = "string"
+	TypeInteger   = "integer"
+	TypeNumber    = "number"
+	TypeBoolean   = "boolean"
+	TypeDate      = "date"
+	TypeTimestamp = "timestamp"
+)
+
+// maxViolations caps how many problems a failed check lists
+const maxViolations = 20
+
+var dateLayouts = []string{"2006-01-02"}
+
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+}
+
+// Column is the contract for one column
+type Column struct {
+	Name string `yaml:"name"`
+	// Type is string (default), integer, number, boolean, date or timestamp
+	Type string `yaml:"type"`
+	// Nullable allows empty values (default true)
+	Nullable *bool `yaml:"nullable"`
+	// Min and Max bound numbers, dates and timestamps, or strings by sort order
+	Min string `yaml:"min"`
+	Max string `yaml:"max"`
+	// Pattern is a regular expression every value must match in full
+	Pattern string `yaml:"pattern"`
+	// Allowed is a controlled vocabulary
+	Allowed []string `yaml:"allowed"`
+	pattern *regexp.Regexp
+}
+
+// Contract declares the columns a report must return
+type Contract struct {
+	// Columns must all be present, in this order. Other columns may come between them
+	Columns []Column `yaml:"columns"`
+	// Strict rejects columns the contract does not declare
+	Strict bool `yaml:"strict"`
+}
+
+// ValidationError lists the ways a result broke its contract
+type ValidationError struct {
+	Violations []string
+	// Total is how many violations were found; only the first few are kept
+	Total int
+}
+
+func (e *ValidationError) Error() string {
+	msg := fmt.Sprintf("schema check failed with %d problems: %s", e.Total, strings.Join(e.Violations, "; "))
+	if e.Total > len(e.Violations) {
+		msg += fmt.Sprintf("; and %d more", e.Total-len(e.Violations))
+	}
+	return msg
+}
+
+func (e *ValidationError) add(format string, args ...any) {
+	e.Total++
+	if len(e.Violations) < maxViolations {
+		e.Violations = append(e.Violations, fmt.Sprintf(format, args...))
+	}
+}
+
+// ValidateType checks that t is a known column type
+func ValidateType(t string) error {
+	switch t {
+	case "", TypeString, TypeInteger, TypeNumber, TypeBoolean, TypeDate, TypeTimestamp:
+		return nil
+	}
+	return fmt.Errorf("invalid type '%s': must be %s, %s, %s, %s, %s or %s", t, TypeString, TypeInteger, TypeNumber, TypeBoolean, TypeDate, TypeTimestamp)
+}
+
+// Parse converts a value of type t so values can be compared. Strings are returned as is
+func Parse(t, s string) (any, error) {
+	switch t {
+	case TypeInteger:
+		return strconv.ParseInt(s, 10, 64)
+	case TypeNumber:
+		return strconv.ParseFloat(s, 64)
+	case TypeBoolean:
+		return strconv.ParseBool(s)
+	case TypeDate:
+		return parseTime(s, dateLayouts)
+	case TypeTimestamp:
+		return parseTime(s, timestampLayouts)
+	}
+	return s, nil
+}
+
+func parseTime(s string, layouts []string) (time.Time, error) {
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s'", s)
+}
+
+// compare returns -1, 0 or 1 as a sorts before, with or after b
+func compare(a, b any) int {
+	switch a := a.(type) {
+	case int64:
+		return cmpOrdered(float64(a), toFloat(b))
+	case float64:
+		return cmpOrdered(a, toFloat(b))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case string:
+		return strings.Compare(a, b.(string))
+	}
+	return 0
+}
+
+func toFloat(v any) float64 {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+func cmpOrdered(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Validate checks the contract's settings and compiles its patterns
+func (c *Contract) Validate() error {
+	if len(c.Columns) == 0 {
+		return errors.New("schema needs at least one column")
+	}
+	seen := make(map[string]bool)
+	for i := range c.Columns {
+		col := &c.Columns[i]
+		if col.Name == "" {
+			return errors.New("schema columns need a name")
+		}
+		if seen[col.Name] {
+			return fmt.Errorf("column '%s' is declared twice", col.Name)
+		}
+		seen[col.Name] = true
+
+		err := ValidateType(col.Type)
+		if err != nil {
+			return fmt.Errorf("column '%s': %w", col.Name, err)
+		}
+		if col.Type == TypeBoolean && (col.Min != "" || col.Max != "") {
+			return fmt.Errorf("column '%s': boolean columns can't have min or max", col.Name)
+		}
+		for _, bound := range []string{col.Min, col.Max} {
+			if bound == "" {
+				continue
+			}
+			if _, err := Parse(col.Type, bound); err != nil {
+				return fmt.Errorf("column '%s': invalid bound '%s' for type %s", col.Name, bound, col.typ())
+			}
+		}
+		if col.Pattern != "" {
+			col.pattern, err = regexp.Compile(`^(?:` + col.Pattern + `)$`)
+			if err != nil {
+				return fmt.Errorf("column '%s': invalid pattern: %w", col.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Check validates a result against the contract. It returns a
+// *ValidationError listing the problems found
+func (c *Contract) Check(columns []string, rows []map[string]string) error {
+	verr := &ValidationError{}
+
+	index := make(map[string]int, len(columns))
+	for i, name := range columns {
+		index[name] = i
+	}
+	last := -1
+	for _, col := range c.Columns {
+		i, ok := index[col.Name]
+		if !ok {
+			verr.add("column '%s' is missing", col.Name)
+			continue
+		}
+		if i < last {
+			verr.add("column '%s' is out of order", col.Name)
+		}
+		last = max(last, i)
+	}
+	if c.Strict {
+		for _, name := range columns {
+			if !slices.ContainsFunc(c.Columns, func(col Column) bool { return col.Name == name }) {
+				verr.add("column '%s' is not in the schema", name)
+			}
+		}
+	}
+
+	for _, col := range c.Columns {
+		if _, ok := index[col.Name]; !ok {
+			continue
+		}
+		for n, row := range rows {
+			msg := col.check(row[col.Name])
+			if msg != "" {
+				// Row numbers count the header as row 1, like the output file
+				verr.add("row %d column '%s': %s", n+2, col.Name, msg)
+			}
+		}
+	}
+
+	if verr.Total > 0 {
+		return verr
+	}
+	return nil
+}
+
+func (col *Column) typ() string {
+	if col.Type == "" {
+		return TypeString
+	}
+	return col.Type
+}
+
+// check returns what is wrong with v, or "" if it is valid
+func (col *Column) check(v string) string {
+	if v == "" {
+		if col.Nullable != nil && !*col.Nullable {
+			return "value is required"
+		}
+		return ""
+	}
+
+	parsed, err := Parse(col.Type, v)
+	if err != nil {
+		return fmt.Sprintf("'%s' is not a valid %s", v, col.typ())
+	}
+	if col.Min != "" {
+		bound, _ := Parse(col.Type, col.Min)
+		if compare(parsed, bound) < 0 {
+			return fmt.Sprintf("'%s' is less than %s", v, col.Min)
+		}
+	}
+	if col.Max != "" {
+		bound, _ := Parse(col.Type, col.Max)
+		if compare(parsed, bound) > 0 {
+			return fmt.Sprintf("'%s' is greater than %s", v, col.Max)
+		}
+	}
+	if col.pattern != nil && !col.pattern.MatchString(v) {
+		return fmt.Sprintf("'%s' does not match %s", v, col.Pattern)
+	}
+	if len(col.Allowed) > 0 && !slices.Contains(col.Allowed, v) {
+		return fmt.Sprintf("'%s' is not one of the allowed values", v)
+	}
+	return ""
+}
@@ -0,0 +1,132 @@
+package schema_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lehigh-university-libraries/encode/pkg/schema"
+	yaml "gopkg.in/yaml.v3"
+)
+
+const contractYAML = `
+columns:
+  - name: date
+    type: date
+    nullable: false
+    min: "2020-01-01"
+  - name: branch
+    allowed: [Linderman, Fairchild]
+  - name: count
+    type: integer
+    min: 0
+    max: 10000
+  - name: code
+    pattern: "[A-Z]{3}"
+`
+
+func TestContract_Check(t *testing.T) {
+	columns := []string{"date", "branch", "count", "code"}
+
+	tests := []struct {
+		name        string
+		strict      bool
+		columns     []string
+		rows        []map[string]string
+		expectError []string
+	}{
+		{
+			name:    "Valid",
+			columns: columns,
+			rows: []map[string]string{
+				{"date": "2024-01-01", "branch": "Linderman", "count": "5", "code": "ABC"},
+				{"date": "2024-01-02", "branch": "Fairchild", "count": "", "code": ""},
+			},
+		},
+		{
+			name:    "New columns are allowed",
+			columns: []string{"date", "visitors", "branch", "count", "code", "notes"},
+			rows:    []map[string]string{{"date": "2024-01-01", "branch": "Linderman", "count": "5", "code": "ABC"}},
+		},
+		{
+			name:        "Strict rejects new columns",
+			strict:      true,
+			columns:     []string{"date", "branch", "count", "code", "notes"},
+			expectError: []string{"column 'notes' is not in the schema"},
+		},
+		{
+			name:        "Missing and reordered columns",
+			columns:     []string{"branch", "date", "code"},
+			expectError: []string{"column 'branch' is out of order", "column 'count' is missing"},
+		},
+		{
+			name:    "Bad values",
+			columns: columns,
+			rows: []map[string]string{
+				{"date": "", "branch": "Packard", "count": "five", "code": "abc"},
+				{"date": "2019-12-31", "branch": "Linderman", "count": "10001", "code": "ABCD"},
+			},
+			expectError: []string{
+				"row 2 column 'date': value is required",
+				"row 3 column 'date': '2019-12-31' is less than 2020-01-01",
+				"row 2 column 'branch': 'Packard' is not one of the allowed values",
+				"row 2 column 'count': 'five' is not a valid integer",
+				"row 3 column 'count': '10001' is greater than 10000",
+				"row 2 column 'code': 'abc' does not match [A-Z]{3}",
+				"row 3 column 'code': 'ABCD' does not match [A-Z]{3}",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var contract schema.Contract
+			err := yaml.Unmarshal([]byte(contractYAML), &contract)
+			if err != nil {
+				t.Fatal(err)
+			}
+			contract.Strict = tt.strict
+			err = contract.Validate()
+			if err != nil {
+				t.Fatalf("Validate() failed: %v", err)
+			}
+
+			err = contract.Check(tt.columns, tt.rows)
+			if len(tt.expectError) == 0 {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			var verr *schema.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Expected a ValidationError, got %v", err)
+			}
+			if strings.Join(verr.Violations, "\n") != strings.Join(tt.expectError, "\n") {
+				t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(tt.expectError, "\n"), strings.Join(verr.Violations, "\n"))
+			}
+		})
+	}
+}
+
+func TestContract_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		contract schema.Contract
+	}{
+		{name: "No columns", contract: schema.Contract{}},
+		{name: "Unnamed column", contract: schema.Contract{Columns: []schema.Column{{Type: "integer"}}}},
+		{name: "Unknown type", contract: schema.Contract{Columns: []schema.Column{{Name: "a", Type: "money"}}}},
+		{name: "Bad bound", contract: schema.Contract{Columns: []schema.Column{{Name: "a", Type: "date", Min: "yesterday"}}}},
+		{name: "Bad pattern", contract: schema.Contract{Columns: []schema.Column{{Name: "a", Pattern: "("}}}},
+		{name: "Duplicate column", contract: schema.Contract{Columns: []schema.Column{{Name: "a"}, {Name: "a"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.contract.Validate(); err == nil {
+				t.Errorf("Expected error but got none")
+			}
+		})
+	}
+}
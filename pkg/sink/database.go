@@ -0,0 +1,45 @@
+package sink
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/connection"
+)
+
+// DatabaseConfig loads each run's rows into a table
+type DatabaseConfig struct {
+	Table string `yaml:"table"`
+	// Truncate empties the table before loading so it mirrors the latest run
+	Truncate bool `yaml:"truncate"`
+}
+
+// Database is a sink that inserts rows through a PostgreSQL or MariaDB connection
+type Database struct {
+	name   string
+	writer connection.RowWriter
+	config DatabaseConfig
+}
+
+// NewDatabase returns a database table sink
+func NewDatabase(name string, writer connection.RowWriter, config DatabaseConfig) (*Database, error) {
+	if config.Table == "" {
+		return nil, errors.New("database sink requires table")
+	}
+	return &Database{name: name, writer: writer, config: config}, nil
+}
+
+func (d *Database) Name() string {
+	return d.name
+}
+
+func (d *Database) Write(out *Output) (string, error) {
+	writer := connection.WithLogger(d.writer, out.Log)
+	start := time.Now()
+	err := writer.InsertRows(d.config.Table, out.Columns, out.Rows, d.config.Truncate)
+	connection.Observe(connection.Type(d.writer), "insert", start, err)
+	if err != nil {
+		return "", err
+	}
+	return d.config.Table, nil
+}
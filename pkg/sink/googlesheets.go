@@ -0,0 +1,95 @@
+package sink
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/connection"
+	"github.com/lehigh-university-libraries/encode/pkg/format"
+)
+
+// GoogleSheetsConfig appends each run's rows to a tab in a Google Sheet
+type GoogleSheetsConfig struct {
+	SpreadsheetID    string `yaml:"spreadsheet_id"`
+	GID              string `yaml:"gid"`
+	Tab              string `yaml:"tab"`
+	HeaderRow        int    `yaml:"header_row"`
+	ValueInputOption string `yaml:"value_input_option"`
+	// Export re-reads the whole tab after appending and hands it to the
+	// sinks listed after this one as one consolidated file in the report's format
+	Export bool `yaml:"export"`
+}
+
+// GoogleSheets is a sink that appends rows to a Google Sheet
+type GoogleSheets struct {
+	name   string
+	auth   *connection.GoogleSheetsAuth
+	config GoogleSheetsConfig
+}
+
+// NewGoogleSheets returns a Google Sheets sink using auth's credentials
+func NewGoogleSheets(name string, auth *connection.GoogleSheetsAuth, config GoogleSheetsConfig) (*GoogleSheets, error) {
+	if config.SpreadsheetID == "" {
+		return nil, fmt.Errorf("GoogleSheets sink requires spreadsheet_id")
+	}
+	if config.GID == "" && config.Tab == "" {
+		return nil, fmt.Errorf("GoogleSheets sink requires gid or tab")
+	}
+	return &GoogleSheets{name: name, auth: auth, config: config}, nil
+}
+
+func (g *GoogleSheets) Name() string {
+	return g.name
+}
+
+func (g *GoogleSheets) target() connection.SheetTarget {
+	return connection.SheetTarget{
+		SpreadsheetID:    g.config.SpreadsheetID,
+		GID:              g.config.GID,
+		Tab:              g.config.Tab,
+		HeaderRow:        g.config.HeaderRow,
+		ValueInputOption: g.config.ValueInputOption,
+	}
+}
+
+// Write appends the run's rows. With Export set, the whole sheet is then
+// exported to ExportFile for later sinks to deliver
+func (g *GoogleSheets) Write(out *Output) (string, error) {
+	auth := connection.WithLogger(g.auth, out.Log)
+	start := time.Now()
+	err := auth.AppendRows(g.target(), out.Columns, out.Rows)
+	connection.Observe(connection.Type(g.auth), "append", start, err)
+	if err != nil {
+		return "", err
+	}
+
+	location := fmt.Sprintf("https://docs.google.com/spreadsheets/d/%s", g.config.SpreadsheetID)
+	if !g.config.Export {
+		return location, nil
+	}
+
+	start = time.Now()
+	export, err := auth.ExportSheet(g.target())
+	connection.Observe(connection.Type(g.auth), "export", start, err)
+	if err != nil {
+		return location, fmt.Errorf("failed to export sheet: %w", err)
+	}
+
+	consolidated := g.ExportFile(out)
+	err = format.WriteFile(consolidated, out.Format, export.Columns, export.Rows)
+	if err != nil {
+		return location, fmt.Errorf("failed to write consolidated export: %w", err)
+	}
+
+	return location, nil
+}
+
+// ExportFile is where Export writes the whole sheet: {report_name}.{ext}
+// beside the run's file. It is "" without Export
+func (g *GoogleSheets) ExportFile(out *Output) string {
+	if !g.config.Export {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(out.File), out.Report+"."+out.Format.Extension())
+}
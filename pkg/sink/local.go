@@ -0,0 +1,74 @@
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// LocalConfig copies staged files into another directory, e.g. a network share
+type LocalConfig struct {
+	Path string `yaml:"path"`
+}
+
+// Local is a sink that copies the staged file to {path}/{report_name}/{filename}
+type Local struct {
+	name   string
+	config LocalConfig
+}
+
+// NewLocal returns a local filesystem sink
+func NewLocal(name string, config LocalConfig) (*Local, error) {
+	if config.Path == "" {
+		return nil, errors.New("local sink requires path")
+	}
+	return &Local{name: name, config: config}, nil
+}
+
+func (l *Local) Name() string {
+	return l.name
+}
+
+func (l *Local) Write(out *Output) (string, error) {
+	dir := filepath.Join(l.config.Path, out.Report)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	dest := filepath.Join(dir, filepath.Base(out.File))
+	err = copyFile(out.File, dest)
+	if err != nil {
+		return "", err
+	}
+
+	return dest, nil
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close()
+
+	// Write to a temporary file first so readers never see a partial copy
+	tmp := dest + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmp, err)
+	}
+
+	_, err = io.Copy(f, in)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to copy %s to %s: %w", src, dest, err)
+	}
+
+	return os.Rename(tmp, dest)
+}
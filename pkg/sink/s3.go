@@ -0,0 +1,52 @@
+package sink
+
+import (
+	"github.com/lehigh-university-libraries/encode/pkg/storage"
+)
+
+// S3 is a sink that uploads the staged file and updates the report's QuickSight manifest
+type S3 struct {
+	name     string
+	uploader *storage.S3Uploader
+}
+
+// NewS3 returns an S3 sink backed by uploader
+func NewS3(name string, uploader *storage.S3Uploader) *S3 {
+	return &S3{name: name, uploader: uploader}
+}
+
+func (s *S3) Name() string {
+	return s.name
+}
+
+// Uploader is the uploader that writes the sink's files and manifest
+func (s *S3) Uploader() *storage.S3Uploader {
+	return s.uploader
+}
+
+func (s *S3) Write(out *Output) (string, error) {
+	uploader := s.uploader.WithLogger(out.Log)
+	s3URI, err := uploader.UploadFile(out.File, out.Report)
+	if err != nil {
+		return "", err
+	}
+
+	// Generate and upload manifest if URI was returned
+	if s3URI != "" {
+		err = uploader.GenerateManifest(out.Report, s3URI, storage.UploadSettingsFor(out.Format))
+		if err != nil {
+			return s3URI, err
+		}
+
+		// Upload the updated manifest to S3 for QuickSight
+		manifestURL, err := uploader.UploadManifestForReport(out.Report)
+		if err != nil {
+			return s3URI, err
+		}
+		if manifestURL != "" {
+			out.Logger().Info("Manifest available for QuickSight", "url", manifestURL)
+		}
+	}
+
+	return s3URI, nil
+}
@@ -0,0 +1,142 @@
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SFTPConfig uploads staged files to an SFTP server
+type SFTPConfig struct {
+	Host           string `yaml:"host"`
+	Port           int    `yaml:"port"`
+	Username       string `yaml:"username"`
+	Password       string `yaml:"password"`
+	PrivateKeyFile string `yaml:"private_key_file"`
+	KnownHostsFile string `yaml:"known_hosts_file"`
+	// InsecureIgnoreHostKey skips host key verification; only for testing
+	InsecureIgnoreHostKey bool   `yaml:"insecure_ignore_host_key"`
+	RemoteDirectory       string `yaml:"remote_directory"`
+}
+
+// SFTP is a sink that uploads the staged file to {remote_directory}/{report_name}/{filename}
+type SFTP struct {
+	name   string
+	config SFTPConfig
+}
+
+// NewSFTP returns an SFTP sink
+func NewSFTP(name string, config SFTPConfig) (*SFTP, error) {
+	if config.Host == "" {
+		return nil, errors.New("SFTP sink requires host")
+	}
+	if config.Username == "" {
+		return nil, errors.New("SFTP sink requires username")
+	}
+	if config.Password == "" && config.PrivateKeyFile == "" {
+		return nil, errors.New("SFTP sink requires password or private_key_file")
+	}
+	if config.KnownHostsFile == "" && !config.InsecureIgnoreHostKey {
+		return nil, errors.New("SFTP sink requires known_hosts_file")
+	}
+	if config.Port == 0 {
+		config.Port = 22
+	}
+	return &SFTP{name: name, config: config}, nil
+}
+
+func (s *SFTP) Name() string {
+	return s.name
+}
+
+func (s *SFTP) Write(out *Output) (string, error) {
+	clientConfig, err := s.clientConfig()
+	if err != nil {
+		return "", err
+	}
+
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	conn, err := ssh.Dial("tcp", addr, clientConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	defer conn.Close()
+
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		return "", fmt.Errorf("failed to start SFTP session: %w", err)
+	}
+	defer client.Close()
+
+	dir := path.Join(s.config.RemoteDirectory, out.Report)
+	err = client.MkdirAll(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to create remote directory %s: %w", dir, err)
+	}
+
+	local, err := os.Open(out.File)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", out.File, err)
+	}
+	defer local.Close()
+
+	remotePath := path.Join(dir, filepath.Base(out.File))
+	remote, err := client.Create(remotePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", remotePath, err)
+	}
+
+	_, err = io.Copy(remote, local)
+	if closeErr := remote.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to upload %s: %w", remotePath, err)
+	}
+
+	return fmt.Sprintf("sftp://%s%s", addr, remotePath), nil
+}
+
+func (s *SFTP) clientConfig() (*ssh.ClientConfig, error) {
+	var auth []ssh.AuthMethod
+	if s.config.PrivateKeyFile != "" {
+		key, err := os.ReadFile(s.config.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if s.config.Password != "" {
+		auth = append(auth, ssh.Password(s.config.Password))
+	}
+
+	hostKeyCallback := ssh.InsecureIgnoreHostKey()
+	if !s.config.InsecureIgnoreHostKey {
+		var err error
+		hostKeyCallback, err = knownhosts.New(s.config.KnownHostsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load known_hosts_file: %w", err)
+		}
+	}
+
+	return &ssh.ClientConfig{
+		User:            s.config.Username,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         30 * time.Second,
+	}, nil
+}
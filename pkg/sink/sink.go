@@ -0,0 +1,141 @@
+package sink
+
+import (
+	"log/slog"
+
+	"github.com/lehigh-university-libraries/encode/pkg/format"
+)
+
+// Output is a single report run handed to each sink
+type Output struct {
+	Report  string
+	File    string
+	Format  format.Options
+	Columns []string
+	Rows    []map[string]string
+	// Log is the run's logger; unset logs to the default logger
+	Log *slog.Logger
+}
+
+// Logger is the run's logger, or one that tags lines with the report
+func (o *Output) Logger() *slog.Logger {
+	if o.Log != nil {
+		return o.Log
+	}
+	return slog.Default().With("report", o.Report)
+}
+
+// Sink delivers a report run to one destination
+type Sink interface {
+	// Name identifies the sink in logs and run summaries
+	Name() string
+	// Write delivers out and returns where it was written
+	Write(out *Output) (string, error)
+}
+
+// Status is the outcome of writing a run to one sink
+type Status struct {
+	Sink     string
+	Location string
+	Err      error
+	Skipped  bool
+}
+
+// OK reports whether the sink succeeded
+func (s Status) OK() bool {
+	return s.Err == nil && !s.Skipped
+}
+
+// String is a short form for run summaries, e.g. "s3=ok"
+func (s Status) String() string {
+	switch {
+	case s.Skipped:
+		return s.Sink + "=skipped"
+	case s.Err != nil:
+		return s.Sink + "=failed"
+	default:
+		return s.Sink + "=ok"
+	}
+}
+
+// Halter is implemented by sinks whose failure should stop the sinks after them
+type Halter interface {
+	HaltOnFailure() bool
+}
+
+// Exporter is implemented by sinks that write a file for the sinks after them
+// to deliver instead of the run's file
+type Exporter interface {
+	// ExportFile is the file the sinks after a successful write deliver, or ""
+	ExportFile(out *Output) string
+}
+
+// WriteAll writes out to each sink in order and returns one status per sink.
+// A failed sink does not stop the ones after it unless it asks to halt.
+// Each sink gets its own copy of out, so out itself is left as it was
+func WriteAll(sinks []Sink, out *Output) []Status {
+	statuses := make([]Status, 0, len(sinks))
+	halted := false
+	next := *out
+	for _, s := range sinks {
+		status := Status{Sink: s.Name()}
+		if halted {
+			status.Skipped = true
+			statuses = append(statuses, status)
+			out.Logger().Warn("Skipping sink after earlier failure", "sink", s.Name())
+			continue
+		}
+
+		current := next
+		status.Location, status.Err = s.Write(&current)
+		statuses = append(statuses, status)
+		if status.Err != nil {
+			out.Logger().Error("Sink failed", "sink", s.Name(), "err", status.Err)
+			if h, ok := s.(Halter); ok && h.HaltOnFailure() {
+				halted = true
+			}
+			continue
+		}
+		out.Logger().Info("Sink succeeded", "sink", s.Name(), "location", status.Location)
+		if e, ok := Unwrap(s).(Exporter); ok {
+			if file := e.ExportFile(&current); file != "" {
+				next.File = file
+			}
+		}
+	}
+
+	return statuses
+}
+
+// Failed returns the statuses that did not succeed
+func Failed(statuses []Status) []Status {
+	var failed []Status
+	for _, s := range statuses {
+		if !s.OK() {
+			failed = append(failed, s)
+		}
+	}
+	return failed
+}
+
+// halting wraps a sink so its failure stops the sinks after it
+type halting struct {
+	Sink
+}
+
+func (halting) HaltOnFailure() bool {
+	return true
+}
+
+// WithHalt wraps s so that WriteAll skips the remaining sinks if s fails
+func WithHalt(s Sink) Sink {
+	return halting{s}
+}
+
+// Unwrap returns the sink WithHalt wrapped, or s itself
+func Unwrap(s Sink) Sink {
+	if h, ok := s.(halting); ok {
+		return h.Sink
+	}
+	return s
+}
@@ -0,0 +1,140 @@
+package sink_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lehigh-university-libraries/encode/pkg/sink"
+)
+
+type fakeSink struct {
+	name   string
+	err    error
+	calls  int
+	files  []string
+	export string
+}
+
+func (f *fakeSink) Name() string {
+	return f.name
+}
+
+func (f *fakeSink) Write(out *sink.Output) (string, error) {
+	f.calls++
+	f.files = append(f.files, out.File)
+	return "fake://" + out.Report, f.err
+}
+
+func (f *fakeSink) ExportFile(out *sink.Output) string {
+	return f.export
+}
+
+func TestWriteAll(t *testing.T) {
+	tests := []struct {
+		name            string
+		sinks           func() []sink.Sink
+		expectedSummary []string
+	}{
+		{
+			name: "All sinks succeed",
+			sinks: func() []sink.Sink {
+				return []sink.Sink{&fakeSink{name: "a"}, &fakeSink{name: "b"}}
+			},
+			expectedSummary: []string{"a=ok", "b=ok"},
+		},
+		{
+			name: "Failed sink does not stop the others",
+			sinks: func() []sink.Sink {
+				return []sink.Sink{&fakeSink{name: "a", err: errors.New("boom")}, &fakeSink{name: "b"}}
+			},
+			expectedSummary: []string{"a=failed", "b=ok"},
+		},
+		{
+			name: "Halting sink skips the rest",
+			sinks: func() []sink.Sink {
+				return []sink.Sink{
+					&fakeSink{name: "a"},
+					sink.WithHalt(&fakeSink{name: "b", err: errors.New("boom")}),
+					&fakeSink{name: "c"},
+				}
+			},
+			expectedSummary: []string{"a=ok", "b=failed", "c=skipped"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statuses := sink.WriteAll(tt.sinks(), &sink.Output{Report: "test_report"})
+
+			summary := make([]string, len(statuses))
+			for i, s := range statuses {
+				summary[i] = s.String()
+			}
+			if !reflect.DeepEqual(tt.expectedSummary, summary) {
+				t.Errorf("Expected %v, got %v", tt.expectedSummary, summary)
+			}
+		})
+	}
+}
+
+func TestWriteAll_Export(t *testing.T) {
+	tests := []struct {
+		name       string
+		exportErr  error
+		expectFile string
+	}{
+		{name: "Later sinks deliver the export", expectFile: "export.csv"},
+		{name: "A failed export leaves the run's file", exportErr: errors.New("boom"), expectFile: "run.csv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := &fakeSink{name: "before"}
+			exporter := &fakeSink{name: "sheet", err: tt.exportErr, export: "export.csv"}
+			after := &fakeSink{name: "after"}
+			out := &sink.Output{Report: "test_report", File: "run.csv"}
+			sink.WriteAll([]sink.Sink{before, exporter, after}, out)
+
+			if out.File != "run.csv" {
+				t.Errorf("Expected the shared output to keep the run's file, got %s", out.File)
+			}
+			if before.files[0] != "run.csv" || exporter.files[0] != "run.csv" {
+				t.Errorf("Expected sinks up to the export to deliver run.csv, got %v %v", before.files, exporter.files)
+			}
+			if after.files[0] != tt.expectFile {
+				t.Errorf("Expected the sink after the export to deliver %s, got %s", tt.expectFile, after.files[0])
+			}
+		})
+	}
+}
+
+func TestLocal_Write(t *testing.T) {
+	tmpDir := t.TempDir()
+	staged := filepath.Join(tmpDir, "staged.csv")
+	err := os.WriteFile(staged, []byte("id\n1\n"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write staged file: %v", err)
+	}
+
+	local, err := sink.NewLocal("share", sink.LocalConfig{Path: filepath.Join(tmpDir, "share")})
+	if err != nil {
+		t.Fatalf("NewLocal() failed: %v", err)
+	}
+
+	location, err := local.Write(&sink.Output{Report: "test_report", File: staged})
+	if err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	expected := filepath.Join(tmpDir, "share", "test_report", "staged.csv")
+	if location != expected {
+		t.Errorf("Expected location %s, got %s", expected, location)
+	}
+	data, err := os.ReadFile(expected)
+	if err != nil || string(data) != "id\n1\n" {
+		t.Errorf("Expected copied file contents, got %q (err %v)", data, err)
+	}
+}
@@ -0,0 +1,142 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/schema"
+)
+
+// ReportState is what encode remembers about a report between runs
+type ReportState struct {
+	// Watermark is the incremental position the next run extracts from
+	Watermark string `json:"watermark,omitempty"`
+	// WatermarkUpdated is when the watermark last advanced
+	WatermarkUpdated time.Time `json:"watermark_updated,omitzero"`
+	// LastSuccess is the scheduled time of the last run where every sink succeeded
+	LastSuccess time.Time `json:"last_success,omitzero"`
+	// LastRun is the scheduled time of the last cron tick the report ran for
+	LastRun time.Time `json:"last_run,omitzero"`
+	// Schema is the inferred schema of the last successful run, for drift detection
+	Schema []schema.InferredColumn `json:"schema,omitempty"`
+	// Backfill is the progress of an unfinished backfill
+	Backfill *Backfill `json:"backfill,omitempty"`
+	// Alert is the open problem the report's owners were notified about
+	Alert *Alert `json:"alert,omitempty"`
+	// Paused is when the report's schedule was paused; zero while it runs on schedule
+	Paused time.Time `json:"paused,omitzero"`
+}
+
+// Alert de-duplicates notifications while a report keeps failing
+type Alert struct {
+	// Event is the kind of problem: failure or halt
+	Event string `json:"event"`
+	// Since is when the first run with the problem finished
+	Since time.Time `json:"since"`
+	// Sent is when owners were last notified
+	Sent time.Time `json:"sent"`
+	// Notified counts the notifications sent for the problem
+	Notified int `json:"notified"`
+	// Suppressed counts the runs since Sent that were not notified
+	Suppressed int `json:"suppressed,omitempty"`
+}
+
+// Backfill records how far a backfill got so it can resume after a failure
+type Backfill struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	Step string    `json:"step"`
+	// Completed is the start of the last window that finished
+	Completed time.Time `json:"completed,omitzero"`
+}
+
+// Store keeps one JSON file of state per report in a directory
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewStore returns a store that keeps its files in dir
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir is the directory the store writes to
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Load returns a report's state. A report without saved state gets an empty one
+func (s *Store) Load(reportName string) (*ReportState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(reportName)
+}
+
+// Update loads a report's state, applies change and saves it
+func (s *Store) Update(reportName string, change func(*ReportState) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.load(reportName)
+	if err != nil {
+		return err
+	}
+	err = change(st)
+	if err != nil {
+		return err
+	}
+	return s.save(reportName, st)
+}
+
+func (s *Store) load(reportName string) (*ReportState, error) {
+	st := &ReportState{}
+	data, err := os.ReadFile(s.path(reportName))
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state for report '%s': %w", reportName, err)
+	}
+	err = json.Unmarshal(data, st)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse state for report '%s': %w", reportName, err)
+	}
+	return st, nil
+}
+
+func (s *Store) save(reportName string, st *ReportState) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	err = os.MkdirAll(s.dir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	// Write then rename so a crash never leaves a half-written state file
+	path := s.path(reportName)
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write state for report '%s': %w", reportName, err)
+	}
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return fmt.Errorf("failed to write state for report '%s': %w", reportName, err)
+	}
+	return nil
+}
+
+func (s *Store) path(reportName string) string {
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(reportName)
+	return filepath.Join(s.dir, name+".json")
+}
@@ -0,0 +1,48 @@
+package state_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/lehigh-university-libraries/encode/pkg/state"
+)
+
+func TestStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "state")
+	store := state.NewStore(dir)
+
+	st, err := store.Load("gate_counts")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if st.Watermark != "" {
+		t.Errorf("Expected empty state, got %+v", st)
+	}
+
+	err = store.Update("gate_counts", func(st *state.ReportState) error {
+		st.Watermark = "42"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+
+	// A failed change is not saved
+	err = store.Update("gate_counts", func(st *state.ReportState) error {
+		st.Watermark = "43"
+		return errors.New("sink failed")
+	})
+	if err == nil {
+		t.Error("Expected error but got none")
+	}
+
+	// A new store over the same directory sees the saved state
+	st, err = state.NewStore(dir).Load("gate_counts")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if st.Watermark != "42" {
+		t.Errorf("Expected watermark 42, got %q", st.Watermark)
+	}
+}
@@ -0,0 +1,276 @@
+package storage_test
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeObject is one stored object in fakeS3
+type fakeObject struct {
+	data         []byte
+	etag         string
+	lastModified time.Time
+}
+
+// fakeVersion is one stored version of an object in a versioned fakeS3
+type fakeVersion struct {
+	id  string
+	obj fakeObject
+}
+
+// fakeS3 is a minimal path-style S3-compatible server, standing in for MinIO in tests
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]fakeObject
+	server  *httptest.Server
+	// versioning keeps every write in versions, like a bucket with versioning enabled
+	versioning bool
+	versions   map[string][]fakeVersion
+}
+
+func newFakeS3(t *testing.T) *fakeS3 {
+	t.Helper()
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_CONFIG_FILE", "/dev/null")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/dev/null")
+
+	f := &fakeS3{objects: make(map[string]fakeObject), versions: make(map[string][]fakeVersion)}
+	f.server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeS3) put(bucket, key string, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sum := md5.Sum(data)
+	f.objects[bucket+"/"+key] = fakeObject{data: data, etag: `"` + hex.EncodeToString(sum[:]) + `"`, lastModified: time.Now().UTC()}
+}
+
+func (f *fakeS3) get(bucket, key string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, ok := f.objects[bucket+"/"+key]
+	return obj.data, ok
+}
+
+func (f *fakeS3) keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var keys []string
+	for k := range f.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// backdate moves every object and version back by d
+func (f *fakeS3) backdate(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for id, obj := range f.objects {
+		obj.lastModified = obj.lastModified.Add(-d)
+		f.objects[id] = obj
+	}
+	for id, versions := range f.versions {
+		for i := range versions {
+			versions[i].obj.lastModified = versions[i].obj.lastModified.Add(-d)
+		}
+		f.versions[id] = versions
+	}
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func (f *fakeS3) handle(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+	query := r.URL.Query()
+	if key == "" && r.Method == http.MethodGet {
+		switch {
+		case query.Get("list-type") == "2":
+			f.list(w, bucket, query.Get("prefix"))
+		case query.Has("versioning"):
+			status := ""
+			if f.versioning {
+				status = "<Status>Enabled</Status>"
+			}
+			w.Header().Set("Content-Type", "application/xml")
+			fmt.Fprintf(w, "<VersioningConfiguration>%s</VersioningConfiguration>", status)
+		case query.Has("versions"):
+			f.listVersions(w, bucket, query.Get("prefix"))
+		default:
+			writeS3Error(w, http.StatusNotImplemented, "NotImplemented")
+		}
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := bucket + "/" + key
+	existing, exists := f.objects[id]
+
+	switch r.Method {
+	case http.MethodPut:
+		if match := r.Header.Get("If-Match"); match != "" && (!exists || match != existing.etag) {
+			writeS3Error(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+		if r.Header.Get("If-None-Match") == "*" && exists {
+			writeS3Error(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+
+		var data []byte
+		if source := r.Header.Get("x-amz-copy-source"); source != "" {
+			source, _ = url.PathUnescape(source)
+			src, ok := f.objects[strings.TrimPrefix(source, "/")]
+			if !ok {
+				writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+				return
+			}
+			data = src.data
+		} else {
+			var err error
+			data, err = io.ReadAll(r.Body)
+			if err != nil {
+				writeS3Error(w, http.StatusBadRequest, "IncompleteBody")
+				return
+			}
+		}
+
+		sum := md5.Sum(data)
+		obj := fakeObject{data: data, etag: `"` + hex.EncodeToString(sum[:]) + `"`, lastModified: time.Now().UTC()}
+		f.objects[id] = obj
+		if f.versioning {
+			f.versions[id] = append(f.versions[id], fakeVersion{id: fmt.Sprint(len(f.versions[id]) + 1), obj: obj})
+		}
+		w.Header().Set("ETag", obj.etag)
+		if r.Header.Get("x-amz-copy-source") != "" {
+			fmt.Fprintf(w, "<CopyObjectResult><ETag>%s</ETag></CopyObjectResult>", obj.etag)
+		}
+	case http.MethodGet, http.MethodHead:
+		if versionID := query.Get("versionId"); versionID != "" {
+			exists = false
+			for _, v := range f.versions[id] {
+				if v.id == versionID {
+					existing, exists = v.obj, true
+				}
+			}
+		}
+		if !exists {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", existing.etag)
+		w.Header().Set("Last-Modified", existing.lastModified.Format(http.TimeFormat))
+		w.Header().Set("Content-Length", fmt.Sprint(len(existing.data)))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(existing.data)
+		}
+	case http.MethodDelete:
+		delete(f.objects, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+type listBucketResult struct {
+	XMLName  xml.Name `xml:"ListBucketResult"`
+	Name     string
+	Prefix   string
+	KeyCount int
+	Contents []listContent
+}
+
+type listContent struct {
+	Key          string
+	LastModified string
+	ETag         string
+	Size         int
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, bucket, prefix string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	result := listBucketResult{Name: bucket, Prefix: prefix}
+	for id, obj := range f.objects {
+		b, key, _ := strings.Cut(id, "/")
+		if b != bucket || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		result.Contents = append(result.Contents, listContent{
+			Key:          key,
+			LastModified: obj.lastModified.Format(time.RFC3339),
+			ETag:         obj.etag,
+			Size:         len(obj.data),
+		})
+	}
+	sort.Slice(result.Contents, func(i, j int) bool { return result.Contents[i].Key < result.Contents[j].Key })
+	result.KeyCount = len(result.Contents)
+
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+type listVersionsResult struct {
+	XMLName  xml.Name `xml:"ListVersionsResult"`
+	Name     string
+	Prefix   string
+	Versions []listVersion `xml:"Version"`
+}
+
+type listVersion struct {
+	Key          string
+	VersionId    string
+	IsLatest     bool
+	LastModified string
+	ETag         string
+	Size         int
+}
+
+func (f *fakeS3) listVersions(w http.ResponseWriter, bucket, prefix string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	result := listVersionsResult{Name: bucket, Prefix: prefix}
+	for id, versions := range f.versions {
+		b, key, _ := strings.Cut(id, "/")
+		if b != bucket || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		for i := len(versions) - 1; i >= 0; i-- {
+			v := versions[i]
+			result.Versions = append(result.Versions, listVersion{
+				Key:          key,
+				VersionId:    v.id,
+				IsLatest:     i == len(versions)-1,
+				LastModified: v.obj.lastModified.Format(time.RFC3339Nano),
+				ETag:         v.obj.etag,
+				Size:         len(v.obj.data),
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(result)
+}
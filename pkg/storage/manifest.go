@@ -0,0 +1,414 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/lehigh-university-libraries/encode/pkg/format"
+)
+
+const (
+	// ManifestStoreLocal keeps the manifest on local disk and uploads a copy after each change
+	ManifestStoreLocal = "local"
+	// ManifestStoreS3 reads the manifest from S3 and updates it with conditional writes
+	ManifestStoreS3 = "s3"
+
+	// ManifestModeURIs lists every uploaded file in the manifest
+	ManifestModeURIs = "uris"
+	// ManifestModePrefix points the manifest at the report's folder so it never needs rewriting
+	ManifestModePrefix = "prefix"
+
+	// manifestWriteAttempts bounds retries when another writer updates the manifest first
+	manifestWriteAttempts = 10
+)
+
+type QuickSightManifest struct {
+	FileLocations        []FileLocation       `json:"fileLocations"`
+	GlobalUploadSettings GlobalUploadSettings `json:"globalUploadSettings"`
+}
+
+type FileLocation struct {
+	URIs        []string `json:"URIs,omitempty"`
+	URIPrefixes []string `json:"URIPrefixes,omitempty"`
+}
+
+type GlobalUploadSettings struct {
+	Format         string `json:"format"`
+	Delimiter      string `json:"delimiter,omitempty"`
+	TextQualifier  string `json:"textqualifier,omitempty"`
+	ContainsHeader string `json:"containsHeader,omitempty"`
+}
+
+// DefaultUploadSettings describe the CSV files encode writes by default
+var DefaultUploadSettings = UploadSettingsFor(format.Options{})
+
+// UploadSettingsFor describes files written with opts to QuickSight
+func UploadSettingsFor(opts format.Options) GlobalUploadSettings {
+	switch opts.Name() {
+	case format.FormatJSON:
+		return GlobalUploadSettings{Format: "JSON"}
+	case format.FormatTSV:
+		return GlobalUploadSettings{
+			Format:         "TSV",
+			Delimiter:      "\t",
+			TextQualifier:  "\"",
+			ContainsHeader: strconv.FormatBool(!opts.NoHeader),
+		}
+	}
+	return GlobalUploadSettings{
+		Format:         "CSV",
+		Delimiter:      string(opts.Comma()),
+		TextQualifier:  "\"",
+		ContainsHeader: strconv.FormatBool(!opts.NoHeader),
+	}
+}
+
+// URIs returns every URI listed in the manifest
+func (m *QuickSightManifest) URIs() []string {
+	if len(m.FileLocations) == 0 {
+		return nil
+	}
+	return m.FileLocations[0].URIs
+}
+
+// SetURIs replaces the URIs listed in the manifest
+func (m *QuickSightManifest) SetURIs(uris []string) {
+	m.FileLocations = []FileLocation{
+		{
+			URIs: uris,
+		},
+	}
+}
+
+// URIPrefixes returns every folder the manifest points at
+func (m *QuickSightManifest) URIPrefixes() []string {
+	var prefixes []string
+	for _, l := range m.FileLocations {
+		prefixes = append(prefixes, l.URIPrefixes...)
+	}
+	return prefixes
+}
+
+// SetURIPrefixes points the manifest at folders instead of individual files
+func (m *QuickSightManifest) SetURIPrefixes(prefixes []string) {
+	m.FileLocations = []FileLocation{
+		{
+			URIPrefixes: prefixes,
+		},
+	}
+}
+
+// errManifestConflict means the manifest changed between reading and writing it
+var errManifestConflict = errors.New("manifest was modified by another writer")
+
+// GenerateManifest creates or updates a QuickSight-compatible manifest file
+// It appends new URIs to existing ones to maintain historical data for QuickSight.
+// settings describe the format newS3URI was written in
+func (u *S3Uploader) GenerateManifest(reportName string, newS3URI string, settings GlobalUploadSettings) error {
+	if !u.config.Enabled || (u.config.ManifestPath == "" && u.manifestStore() == ManifestStoreLocal) {
+		return nil
+	}
+
+	if u.manifestMode() == ManifestModePrefix {
+		return u.generatePrefixManifest(reportName, settings)
+	}
+
+	retention := u.config.Retention
+	var pruned []string
+	err := u.UpdateManifest(reportName, func(m *QuickSightManifest) error {
+		if settings != (GlobalUploadSettings{}) {
+			m.GlobalUploadSettings = settings
+		}
+		uris := m.URIs()
+
+		// Append new URI if not already present
+		if !slices.Contains(uris, newS3URI) {
+			uris = append(uris, newS3URI)
+		}
+
+		kept, p, err := retention.Apply(uris, time.Now())
+		if err != nil {
+			return err
+		}
+		pruned = p
+		if retention.DryRun {
+			kept = uris
+		}
+
+		m.SetURIs(kept)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(pruned) == 0 {
+		return nil
+	}
+	if retention.DryRun {
+		u.logger(reportName).Info("Retention dry run: would prune files from manifest", "uris", pruned)
+		return nil
+	}
+
+	u.logger(reportName).Info("Pruned manifest", "pruned", len(pruned))
+	return u.disposePruned(reportName, pruned)
+}
+
+// generatePrefixManifest points the manifest at the report's folder. The
+// manifest is only written when it is missing or its settings changed; old
+// files are pruned by deleting or archiving them
+func (u *S3Uploader) generatePrefixManifest(reportName string, settings GlobalUploadSettings) error {
+	prefix := u.ReportPrefixURI(reportName)
+	if settings == (GlobalUploadSettings{}) {
+		settings = DefaultUploadSettings
+	}
+
+	manifest, _, err := u.LoadManifest(reportName)
+	if err != nil {
+		return err
+	}
+	current := slices.Equal(manifest.URIPrefixes(), []string{prefix}) && len(manifest.URIs()) == 0 && manifest.GlobalUploadSettings == settings
+	if current && u.manifestStore() == ManifestStoreS3 {
+		u.logger(reportName).Debug("Manifest is current", "prefix", prefix)
+	} else {
+		err = u.UpdateManifest(reportName, func(m *QuickSightManifest) error {
+			m.SetURIPrefixes([]string{prefix})
+			m.GlobalUploadSettings = settings
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = u.PruneManifest(reportName, u.config.Retention.DryRun)
+	return err
+}
+
+// UpdateManifest loads a report's manifest, applies change and saves it.
+// With the S3 manifest store the write only succeeds if the manifest has not
+// changed since it was read; on conflict the manifest is re-read and change
+// is applied again
+func (u *S3Uploader) UpdateManifest(reportName string, change func(*QuickSightManifest) error) error {
+	for attempt := 1; ; attempt++ {
+		manifest, etag, err := u.LoadManifest(reportName)
+		if err != nil {
+			return err
+		}
+
+		err = change(manifest)
+		if err != nil {
+			return err
+		}
+
+		if manifest.GlobalUploadSettings == (GlobalUploadSettings{}) {
+			manifest.GlobalUploadSettings = DefaultUploadSettings
+		}
+
+		err = u.saveManifest(reportName, manifest, etag)
+		if errors.Is(err, errManifestConflict) && attempt < manifestWriteAttempts {
+			backoff := time.Duration(attempt*50+rand.IntN(100)) * time.Millisecond
+			u.logger(reportName).Warn("Manifest changed while updating, retrying", "attempt", attempt, "backoff", backoff)
+			time.Sleep(backoff)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		u.logger(reportName).Info("Generated QuickSight manifest", "store", u.manifestStore(), "totalURIs", len(manifest.URIs()), "uriPrefixes", manifest.URIPrefixes())
+		return nil
+	}
+}
+
+// LoadManifest returns a report's current manifest and, for the S3 store, its
+// ETag. A report without a manifest yet gets an empty one
+func (u *S3Uploader) LoadManifest(reportName string) (*QuickSightManifest, string, error) {
+	manifest := &QuickSightManifest{}
+	if u.manifestStore() == ManifestStoreLocal {
+		// Load existing manifest if it exists
+		if existingData, err := os.ReadFile(u.localManifestPath(reportName)); err == nil {
+			if err := json.Unmarshal(existingData, manifest); err != nil {
+				u.logger(reportName).Warn("Ignoring unreadable manifest", "file", u.localManifestPath(reportName), "err", err)
+			}
+		}
+		return manifest, "", nil
+	}
+
+	resp, err := u.client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(u.config.Bucket),
+		Key:    aws.String(u.ManifestKey(reportName)),
+	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		// Seed from a local mirror, e.g. when moving an existing report to the S3 store
+		if u.config.ManifestPath != "" {
+			if existingData, err := os.ReadFile(u.localManifestPath(reportName)); err == nil {
+				if err := json.Unmarshal(existingData, manifest); err == nil {
+					u.logger(reportName).Info("Seeding S3 manifest from local copy", "totalURIs", len(manifest.URIs()))
+				}
+			}
+		}
+		return manifest, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read manifest from S3: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read manifest from S3: %w", err)
+	}
+	err = json.Unmarshal(data, manifest)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse manifest from S3: %w", err)
+	}
+
+	return manifest, aws.ToString(resp.ETag), nil
+}
+
+func (u *S3Uploader) saveManifest(reportName string, manifest *QuickSightManifest, etag string) error {
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	if u.manifestStore() == ManifestStoreS3 {
+		input := &s3.PutObjectInput{
+			Bucket:      aws.String(u.config.Bucket),
+			Key:         aws.String(u.ManifestKey(reportName)),
+			Body:        strings.NewReader(string(manifestJSON)),
+			ContentType: aws.String("application/json"),
+		}
+		if etag != "" {
+			input.IfMatch = aws.String(etag)
+		} else {
+			input.IfNoneMatch = aws.String("*")
+		}
+
+		_, err = u.client.PutObject(context.Background(), input)
+		u.countUpload(uploadManifest, "", err)
+		if isConditionalWriteConflict(err) {
+			return errManifestConflict
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write manifest to S3: %w", err)
+		}
+
+		if u.config.ManifestPath == "" {
+			return nil
+		}
+	}
+
+	// Create manifest directory if it doesn't exist
+	manifestFile := u.localManifestPath(reportName)
+	if err := os.MkdirAll(filepath.Dir(manifestFile), 0755); err != nil {
+		return fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+
+	if err := os.WriteFile(manifestFile, manifestJSON, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest file: %w", err)
+	}
+
+	return nil
+}
+
+// isConditionalWriteConflict reports whether err is S3 rejecting an If-Match
+// or If-None-Match write because the object changed
+func isConditionalWriteConflict(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "PreconditionFailed", "ConditionalRequestConflict":
+		return true
+	}
+	return false
+}
+
+func (u *S3Uploader) manifestMode() string {
+	if u.config.ManifestMode == "" {
+		return ManifestModeURIs
+	}
+	return u.config.ManifestMode
+}
+
+func (u *S3Uploader) manifestStore() string {
+	if u.config.ManifestStore == "" {
+		return ManifestStoreLocal
+	}
+	return u.config.ManifestStore
+}
+
+// Use a static manifest filename per report (not date-based)
+func (u *S3Uploader) localManifestPath(reportName string) string {
+	return filepath.Join(u.config.ManifestPath, reportName, "manifest.json")
+}
+
+// ManifestKey is the S3 key of a report's manifest
+func (u *S3Uploader) ManifestKey(reportName string) string {
+	prefix := u.config.ManifestPrefix
+	if prefix == "" {
+		prefix = filepath.Join(u.config.Prefix, "manifests")
+	}
+	key := filepath.Join(prefix, reportName, "manifest.json")
+	return strings.ReplaceAll(key, "\\", "/")
+}
+
+// UploadManifestForReport uploads the manifest file for a specific report to S3.
+// With the S3 manifest store the manifest is already in S3, so only its URL is returned
+func (u *S3Uploader) UploadManifestForReport(reportName string) (string, error) {
+	if !u.config.Enabled {
+		return "", nil
+	}
+
+	key := u.ManifestKey(reportName)
+	if u.manifestStore() == ManifestStoreS3 {
+		return u.ObjectURL(key), nil
+	}
+
+	localManifestPath := u.localManifestPath(reportName)
+
+	// Check if manifest exists
+	if _, err := os.Stat(localManifestPath); os.IsNotExist(err) {
+		return "", fmt.Errorf("manifest file does not exist: %s", localManifestPath)
+	}
+
+	file, err := os.Open(localManifestPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open manifest file %s: %w", localManifestPath, err)
+	}
+	defer file.Close()
+
+	u.logger(reportName).Info("Uploading manifest to S3", "localPath", localManifestPath, "bucket", u.config.Bucket, "key", key)
+
+	_, err = u.client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String(u.config.Bucket),
+		Key:    aws.String(key),
+		Body:   file,
+	})
+	u.countUpload(uploadManifest, "", err)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload manifest to S3: %w", err)
+	}
+
+	manifestURL := u.ObjectURL(key)
+	u.logger(reportName).Info("Successfully uploaded manifest to S3", "url", manifestURL)
+	return manifestURL, nil
+}
@@ -0,0 +1,245 @@
+package storage_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/lehigh-university-libraries/encode/pkg/format"
+	"github.com/lehigh-university-libraries/encode/pkg/storage"
+)
+
+func newS3StoreUploader(t *testing.T, fake *fakeS3, manifestPath string) *storage.S3Uploader {
+	t.Helper()
+	uploader, err := storage.NewS3Uploader(storage.S3Config{
+		Enabled:       true,
+		Bucket:        "reports",
+		Prefix:        "encode",
+		ManifestPath:  manifestPath,
+		ManifestStore: storage.ManifestStoreS3,
+		Endpoint:      fake.server.URL,
+		UsePathStyle:  true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Uploader() failed: %v", err)
+	}
+	return uploader
+}
+
+func readManifestURIs(t *testing.T, fake *fakeS3) []string {
+	t.Helper()
+	data, ok := fake.get("reports", "encode/manifests/gate_counts/manifest.json")
+	if !ok {
+		t.Fatalf("Manifest not found in S3, have %v", fake.keys())
+	}
+	var manifest storage.QuickSightManifest
+	err := json.Unmarshal(data, &manifest)
+	if err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+	return manifest.URIs()
+}
+
+func TestGenerateManifest_S3StoreSurvivesFreshVolume(t *testing.T) {
+	fake := newFakeS3(t)
+
+	// First container writes two files and mirrors the manifest locally
+	first := newS3StoreUploader(t, fake, t.TempDir())
+	for _, uri := range []string{"s3://reports/encode/gate_counts/a.csv", "s3://reports/encode/gate_counts/b.csv"} {
+		err := first.GenerateManifest("gate_counts", uri, storage.DefaultUploadSettings)
+		if err != nil {
+			t.Fatalf("GenerateManifest() failed: %v", err)
+		}
+	}
+
+	// A restarted container with an empty volume keeps the history
+	mirror := t.TempDir()
+	second := newS3StoreUploader(t, fake, mirror)
+	err := second.GenerateManifest("gate_counts", "s3://reports/encode/gate_counts/c.csv", storage.DefaultUploadSettings)
+	if err != nil {
+		t.Fatalf("GenerateManifest() failed: %v", err)
+	}
+
+	expected := []string{
+		"s3://reports/encode/gate_counts/a.csv",
+		"s3://reports/encode/gate_counts/b.csv",
+		"s3://reports/encode/gate_counts/c.csv",
+	}
+	uris := readManifestURIs(t, fake)
+	if fmt.Sprint(expected) != fmt.Sprint(uris) {
+		t.Errorf("Expected %v, got %v", expected, uris)
+	}
+
+	local, err := os.ReadFile(filepath.Join(mirror, "gate_counts", "manifest.json"))
+	if err != nil {
+		t.Fatalf("Expected local mirror to be written: %v", err)
+	}
+	s3Copy, _ := fake.get("reports", "encode/manifests/gate_counts/manifest.json")
+	if string(local) != string(s3Copy) {
+		t.Error("Expected local mirror to match the S3 manifest")
+	}
+
+	url, err := second.UploadManifestForReport("gate_counts")
+	if err != nil {
+		t.Fatalf("UploadManifestForReport() failed: %v", err)
+	}
+	if url != fake.server.URL+"/reports/encode/manifests/gate_counts/manifest.json" {
+		t.Errorf("Unexpected manifest URL %s", url)
+	}
+}
+
+func TestGenerateManifest_ConcurrentWriters(t *testing.T) {
+	fake := newFakeS3(t)
+
+	var wg sync.WaitGroup
+	var expected []string
+	errs := make(chan error, 5)
+	for i := range 5 {
+		uri := fmt.Sprintf("s3://reports/encode/gate_counts/%d.csv", i)
+		expected = append(expected, uri)
+		uploader := newS3StoreUploader(t, fake, "")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- uploader.GenerateManifest("gate_counts", uri, storage.DefaultUploadSettings)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("GenerateManifest() failed: %v", err)
+		}
+	}
+
+	uris := readManifestURIs(t, fake)
+	sort.Strings(uris)
+	if fmt.Sprint(expected) != fmt.Sprint(uris) {
+		t.Errorf("Expected every writer's URI to be kept, got %v", uris)
+	}
+}
+
+func TestGenerateManifest_LocalStore(t *testing.T) {
+	manifestPath := t.TempDir()
+	uploader, err := storage.NewS3Uploader(storage.S3Config{ManifestPath: manifestPath, Enabled: true, Region: "us-east-1"})
+	if err != nil {
+		t.Fatalf("NewS3Uploader() failed: %v", err)
+	}
+
+	for _, uri := range []string{"s3://b/a.csv", "s3://b/b.csv", "s3://b/a.csv"} {
+		err := uploader.GenerateManifest("gate_counts", uri, storage.DefaultUploadSettings)
+		if err != nil {
+			t.Fatalf("GenerateManifest() failed: %v", err)
+		}
+	}
+
+	manifest, _, err := uploader.LoadManifest("gate_counts")
+	if err != nil {
+		t.Fatalf("LoadManifest() failed: %v", err)
+	}
+	if fmt.Sprint(manifest.URIs()) != "[s3://b/a.csv s3://b/b.csv]" {
+		t.Errorf("Expected duplicate URI to be skipped, got %v", manifest.URIs())
+	}
+}
+
+func TestGenerateManifest_PrefixMode(t *testing.T) {
+	fake := newFakeS3(t)
+	uploader, err := storage.NewS3Uploader(storage.S3Config{
+		Enabled:       true,
+		Bucket:        "reports",
+		Prefix:        "encode",
+		ManifestStore: storage.ManifestStoreS3,
+		ManifestMode:  storage.ManifestModePrefix,
+		Endpoint:      fake.server.URL,
+		UsePathStyle:  true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Uploader() failed: %v", err)
+	}
+
+	settings := storage.UploadSettingsFor(format.Options{Format: format.FormatTSV, NoHeader: true})
+	err = uploader.GenerateManifest("gate_counts", "s3://reports/encode/gate_counts/a.tsv", settings)
+	if err != nil {
+		t.Fatalf("GenerateManifest() failed: %v", err)
+	}
+	_, first, _ := uploader.LoadManifest("gate_counts")
+
+	// A second file does not rewrite the manifest
+	err = uploader.GenerateManifest("gate_counts", "s3://reports/encode/gate_counts/b.tsv", settings)
+	if err != nil {
+		t.Fatalf("GenerateManifest() failed: %v", err)
+	}
+	manifest, second, err := uploader.LoadManifest("gate_counts")
+	if err != nil {
+		t.Fatalf("LoadManifest() failed: %v", err)
+	}
+	if first != second {
+		t.Errorf("Expected manifest to be written once, ETag changed from %s to %s", first, second)
+	}
+
+	expected := &storage.QuickSightManifest{
+		FileLocations: []storage.FileLocation{{URIPrefixes: []string{"s3://reports/encode/gate_counts/"}}},
+		GlobalUploadSettings: storage.GlobalUploadSettings{
+			Format:         "TSV",
+			Delimiter:      "\t",
+			TextQualifier:  "\"",
+			ContainsHeader: "false",
+		},
+	}
+	if !reflect.DeepEqual(expected, manifest) {
+		t.Errorf("Expected %+v, got %+v", expected, manifest)
+	}
+
+	// Changing the writer rewrites the settings
+	err = uploader.GenerateManifest("gate_counts", "s3://reports/encode/gate_counts/c.json", storage.UploadSettingsFor(format.Options{Format: format.FormatJSON}))
+	if err != nil {
+		t.Fatalf("GenerateManifest() failed: %v", err)
+	}
+	manifest, _, _ = uploader.LoadManifest("gate_counts")
+	if manifest.GlobalUploadSettings != (storage.GlobalUploadSettings{Format: "JSON"}) {
+		t.Errorf("Expected JSON settings, got %+v", manifest.GlobalUploadSettings)
+	}
+}
+
+func TestUploadSettingsFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     format.Options
+		expected storage.GlobalUploadSettings
+	}{
+		{
+			name:     "Default CSV",
+			expected: storage.GlobalUploadSettings{Format: "CSV", Delimiter: ",", TextQualifier: "\"", ContainsHeader: "true"},
+		},
+		{
+			name:     "Custom delimiter",
+			opts:     format.Options{Delimiter: "|"},
+			expected: storage.GlobalUploadSettings{Format: "CSV", Delimiter: "|", TextQualifier: "\"", ContainsHeader: "true"},
+		},
+		{
+			name:     "TSV without header",
+			opts:     format.Options{Format: "TSV", NoHeader: true},
+			expected: storage.GlobalUploadSettings{Format: "TSV", Delimiter: "\t", TextQualifier: "\"", ContainsHeader: "false"},
+		},
+		{
+			name:     "JSON",
+			opts:     format.Options{Format: "json"},
+			expected: storage.GlobalUploadSettings{Format: "JSON"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := storage.UploadSettingsFor(tt.opts)
+			if got != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ManifestEntry is one report file with its S3 metadata
+type ManifestEntry struct {
+	URI          string
+	Size         int64
+	LastModified time.Time
+	// Missing is set when the manifest lists a file that is no longer in S3
+	Missing bool
+}
+
+// Time is when the file was written, read from its name or else from S3
+func (e ManifestEntry) Time() time.Time {
+	if t, ok := ParseURITime(e.URI); ok {
+		return t
+	}
+	return e.LastModified
+}
+
+// ListReportObjects lists every file under a report's prefix, oldest first
+func (u *S3Uploader) ListReportObjects(reportName string) ([]ManifestEntry, error) {
+	prefix := u.ReportKey(reportName, "") + "/"
+	manifestKey := u.ManifestKey(reportName)
+
+	entries, err := u.listPrefix(u.config.Bucket, prefix, manifestKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		ti, tj := entries[i].Time(), entries[j].Time()
+		if ti.Equal(tj) {
+			return entries[i].URI < entries[j].URI
+		}
+		return ti.Before(tj)
+	})
+	return entries, nil
+}
+
+// listPrefix lists the files under prefix, skipping "folders" and manifests
+func (u *S3Uploader) listPrefix(bucket, prefix, manifestKey string) ([]ManifestEntry, error) {
+	var entries []ManifestEntry
+	paginator := s3.NewListObjectsV2Paginator(u.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list s3://%s/%s: %w", bucket, prefix, err)
+		}
+		for _, obj := range page.Contents {
+			key := aws.ToString(obj.Key)
+			if strings.HasSuffix(key, "/") || key == manifestKey || path.Base(key) == "manifest.json" {
+				continue
+			}
+			entries = append(entries, ManifestEntry{
+				URI:          fmt.Sprintf("s3://%s/%s", bucket, key),
+				Size:         aws.ToInt64(obj.Size),
+				LastModified: aws.ToTime(obj.LastModified),
+			})
+		}
+	}
+	return entries, nil
+}
+
+// DescribeManifest returns the files a report's manifest lists, in manifest
+// order, with their size and modification time from S3. Files under the
+// manifest's URI prefixes come after them
+func (u *S3Uploader) DescribeManifest(reportName string) ([]ManifestEntry, error) {
+	manifest, _, err := u.LoadManifest(reportName)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []ManifestEntry
+	for _, uri := range manifest.URIs() {
+		entry := ManifestEntry{URI: uri}
+		bucket, key, err := ParseS3URI(uri)
+		if err != nil {
+			return nil, err
+		}
+		head, err := u.client.HeadObject(context.Background(), &s3.HeadObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		})
+		var notFound *types.NotFound
+		switch {
+		case errors.As(err, &notFound):
+			entry.Missing = true
+		case err != nil:
+			return nil, fmt.Errorf("failed to read %s: %w", uri, err)
+		default:
+			entry.Size = aws.ToInt64(head.ContentLength)
+			entry.LastModified = aws.ToTime(head.LastModified)
+		}
+		entries = append(entries, entry)
+	}
+
+	for _, prefix := range manifest.URIPrefixes() {
+		bucket, key, err := ParseS3URI(prefix)
+		if err != nil {
+			return nil, err
+		}
+		listed, err := u.listPrefix(bucket, key, u.ManifestKey(reportName))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, listed...)
+	}
+
+	return entries, nil
+}
+
+// RebuildManifest replaces a report's manifest with every file found under
+// the report's prefix, oldest first, and returns the new list of URIs. In
+// prefix mode the manifest is rewritten to point at the report's folder.
+// With dryRun the manifest is not written
+func (u *S3Uploader) RebuildManifest(reportName string, settings GlobalUploadSettings, dryRun bool) ([]string, error) {
+	if u.manifestMode() == ManifestModePrefix {
+		prefix := []string{u.ReportPrefixURI(reportName)}
+		if dryRun {
+			return prefix, nil
+		}
+		return prefix, u.UpdateManifest(reportName, func(m *QuickSightManifest) error {
+			m.SetURIPrefixes(prefix)
+			if settings != (GlobalUploadSettings{}) {
+				m.GlobalUploadSettings = settings
+			}
+			return nil
+		})
+	}
+
+	entries, err := u.ListReportObjects(reportName)
+	if err != nil {
+		return nil, err
+	}
+
+	uris := make([]string, len(entries))
+	for i, e := range entries {
+		uris[i] = e.URI
+	}
+	if dryRun {
+		return uris, nil
+	}
+
+	err = u.UpdateManifest(reportName, func(m *QuickSightManifest) error {
+		m.SetURIs(uris)
+		if settings != (GlobalUploadSettings{}) {
+			m.GlobalUploadSettings = settings
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	u.logger(reportName).Info("Rebuilt manifest from S3", "totalURIs", len(uris))
+	return uris, nil
+}
+
+// RollbackManifest drops the files added to a report's manifest after to and
+// returns the URIs it removed. When the manifest is kept in a versioned S3
+// bucket the manifest version current at to is restored; otherwise files are
+// dropped by the time in their name, or their S3 modification time.
+// With dryRun the manifest is not written
+func (u *S3Uploader) RollbackManifest(reportName string, to time.Time, dryRun bool) ([]string, error) {
+	if u.manifestMode() == ManifestModePrefix {
+		return nil, fmt.Errorf("manifest_mode '%s' imports every file under the report's folder; delete or move the files added after %s instead", ManifestModePrefix, to.Format(time.RFC3339))
+	}
+
+	var target []string
+	versioned := false
+	if u.manifestStore() == ManifestStoreS3 {
+		var err error
+		target, versioned, err = u.manifestVersionAt(reportName, to)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var removed []string
+	change := func(m *QuickSightManifest) error {
+		removed = nil
+		var kept []string
+		for _, uri := range m.URIs() {
+			drop := false
+			if versioned {
+				drop = !slices.Contains(target, uri)
+			} else {
+				t, err := u.uriTime(uri)
+				if err != nil {
+					return err
+				}
+				drop = !t.IsZero() && t.After(to)
+			}
+			if drop {
+				removed = append(removed, uri)
+			} else {
+				kept = append(kept, uri)
+			}
+		}
+		m.SetURIs(kept)
+		return nil
+	}
+
+	if dryRun {
+		manifest, _, err := u.LoadManifest(reportName)
+		if err != nil {
+			return nil, err
+		}
+		err = change(manifest)
+		return removed, err
+	}
+
+	err := u.UpdateManifest(reportName, change)
+	if err != nil {
+		return nil, err
+	}
+
+	u.logger(reportName).Info("Rolled back manifest", "to", to, "versioned", versioned, "removed", len(removed))
+	return removed, nil
+}
+
+// manifestVersionAt returns the URIs of the manifest version that was current
+// at t. The bool is false when the bucket does not have versioning enabled
+func (u *S3Uploader) manifestVersionAt(reportName string, t time.Time) ([]string, bool, error) {
+	versioning, err := u.client.GetBucketVersioning(context.Background(), &s3.GetBucketVersioningInput{
+		Bucket: aws.String(u.config.Bucket),
+	})
+	if err != nil {
+		u.logger(reportName).Warn("Unable to read bucket versioning, rolling back by file time", "bucket", u.config.Bucket, "err", err)
+		return nil, false, nil
+	}
+	if versioning.Status != types.BucketVersioningStatusEnabled {
+		return nil, false, nil
+	}
+
+	key := u.ManifestKey(reportName)
+	var best *types.ObjectVersion
+	paginator := s3.NewListObjectVersionsPaginator(u.client, &s3.ListObjectVersionsInput{
+		Bucket: aws.String(u.config.Bucket),
+		Prefix: aws.String(key),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to list manifest versions: %w", err)
+		}
+		for _, v := range page.Versions {
+			if aws.ToString(v.Key) != key || aws.ToTime(v.LastModified).After(t) {
+				continue
+			}
+			if best == nil || aws.ToTime(v.LastModified).After(aws.ToTime(best.LastModified)) {
+				best = &v
+			}
+		}
+	}
+	if best == nil {
+		return nil, true, fmt.Errorf("no version of s3://%s/%s exists at or before %s", u.config.Bucket, key, t.Format(time.RFC3339))
+	}
+
+	resp, err := u.client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket:    aws.String(u.config.Bucket),
+		Key:       aws.String(key),
+		VersionId: best.VersionId,
+	})
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to read manifest version %s: %w", aws.ToString(best.VersionId), err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to read manifest version %s: %w", aws.ToString(best.VersionId), err)
+	}
+	var manifest QuickSightManifest
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to parse manifest version %s: %w", aws.ToString(best.VersionId), err)
+	}
+
+	u.logger(reportName).Info("Found manifest version", "version", aws.ToString(best.VersionId), "lastModified", aws.ToTime(best.LastModified))
+	return manifest.URIs(), true, nil
+}
+
+// uriTime is when a report file was written, from its name or else from S3.
+// A file that no longer exists has a zero time
+func (u *S3Uploader) uriTime(uri string) (time.Time, error) {
+	if t, ok := ParseURITime(uri); ok {
+		return t, nil
+	}
+
+	bucket, key, err := ParseS3URI(uri)
+	if err != nil {
+		return time.Time{}, err
+	}
+	head, err := u.client.HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read %s: %w", uri, err)
+	}
+	return aws.ToTime(head.LastModified), nil
+}
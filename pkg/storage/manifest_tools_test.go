@@ -0,0 +1,126 @@
+package storage_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/storage"
+)
+
+func TestRebuildManifest(t *testing.T) {
+	fake := newFakeS3(t)
+	uploader := newS3StoreUploader(t, fake, "")
+
+	// Out of key order on purpose: the timestamp in the name decides
+	fake.put("reports", "encode/gate_counts/2024-02-01.02.00.00.csv", []byte("id\n2\n"))
+	fake.put("reports", "encode/gate_counts/2024-01-01.02.00.00.csv", []byte("id\n1\n"))
+	fake.put("reports", "encode/gate_counts/2024-03-01.csv", []byte("id\n3\n"))
+	fake.put("reports", "encode/other_report/2024-01-01.02.00.00.csv", []byte("id\n1\n"))
+
+	expected := []string{
+		"s3://reports/encode/gate_counts/2024-01-01.02.00.00.csv",
+		"s3://reports/encode/gate_counts/2024-02-01.02.00.00.csv",
+		"s3://reports/encode/gate_counts/2024-03-01.csv",
+	}
+
+	uris, err := uploader.RebuildManifest("gate_counts", storage.DefaultUploadSettings, true)
+	if err != nil {
+		t.Fatalf("RebuildManifest() failed: %v", err)
+	}
+	if fmt.Sprint(expected) != fmt.Sprint(uris) {
+		t.Errorf("Expected %v, got %v", expected, uris)
+	}
+	if _, ok := fake.get("reports", "encode/manifests/gate_counts/manifest.json"); ok {
+		t.Error("Expected dry run not to write the manifest")
+	}
+
+	_, err = uploader.RebuildManifest("gate_counts", storage.DefaultUploadSettings, false)
+	if err != nil {
+		t.Fatalf("RebuildManifest() failed: %v", err)
+	}
+	if got := readManifestURIs(t, fake); fmt.Sprint(expected) != fmt.Sprint(got) {
+		t.Errorf("Expected manifest %v, got %v", expected, got)
+	}
+
+	entries, err := uploader.DescribeManifest("gate_counts")
+	if err != nil {
+		t.Fatalf("DescribeManifest() failed: %v", err)
+	}
+	if len(entries) != 3 || entries[0].Size != 5 || entries[0].Missing {
+		t.Errorf("Unexpected entries %+v", entries)
+	}
+}
+
+func TestRollbackManifest(t *testing.T) {
+	tests := []struct {
+		name       string
+		versioning bool
+	}{
+		{name: "Versioned bucket restores the manifest version", versioning: true},
+		{name: "Unversioned bucket drops files by time", versioning: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t)
+			fake.versioning = tt.versioning
+			uploader := newS3StoreUploader(t, fake, "")
+
+			now := time.Now()
+			good := []string{
+				"s3://reports/encode/gate_counts/" + now.Add(-3*time.Hour).Format("2006-01-02.15.04.05") + ".csv",
+				"s3://reports/encode/gate_counts/" + now.Add(-2*time.Hour).Format("2006-01-02.15.04.05") + ".csv",
+			}
+			bad := "s3://reports/encode/gate_counts/" + now.Format("2006-01-02.15.04.05") + ".csv"
+
+			for _, uri := range good {
+				err := uploader.GenerateManifest("gate_counts", uri, storage.DefaultUploadSettings)
+				if err != nil {
+					t.Fatalf("GenerateManifest() failed: %v", err)
+				}
+			}
+			fake.backdate(2 * time.Hour)
+			err := uploader.GenerateManifest("gate_counts", bad, storage.DefaultUploadSettings)
+			if err != nil {
+				t.Fatalf("GenerateManifest() failed: %v", err)
+			}
+
+			to := now.Add(-time.Hour)
+			removed, err := uploader.RollbackManifest("gate_counts", to, true)
+			if err != nil {
+				t.Fatalf("RollbackManifest() failed: %v", err)
+			}
+			if fmt.Sprint([]string{bad}) != fmt.Sprint(removed) {
+				t.Errorf("Expected dry run to remove %v, got %v", []string{bad}, removed)
+			}
+			if got := readManifestURIs(t, fake); len(got) != 3 {
+				t.Errorf("Expected dry run to leave the manifest alone, got %v", got)
+			}
+
+			_, err = uploader.RollbackManifest("gate_counts", to, false)
+			if err != nil {
+				t.Fatalf("RollbackManifest() failed: %v", err)
+			}
+			if got := readManifestURIs(t, fake); fmt.Sprint(good) != fmt.Sprint(got) {
+				t.Errorf("Expected manifest %v, got %v", good, got)
+			}
+		})
+	}
+}
+
+func TestRollbackManifest_NoVersionBefore(t *testing.T) {
+	fake := newFakeS3(t)
+	fake.versioning = true
+	uploader := newS3StoreUploader(t, fake, "")
+
+	err := uploader.GenerateManifest("gate_counts", "s3://reports/encode/gate_counts/a.csv", storage.DefaultUploadSettings)
+	if err != nil {
+		t.Fatalf("GenerateManifest() failed: %v", err)
+	}
+
+	_, err = uploader.RollbackManifest("gate_counts", time.Now().Add(-24*time.Hour), false)
+	if err == nil {
+		t.Error("Expected error but got none")
+	}
+}
@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"os"
+
+	"github.com/lehigh-university-libraries/encode/pkg/metrics"
+)
+
+// Kinds of S3 upload
+const (
+	uploadReport   = "report"
+	uploadManifest = "manifest"
+)
+
+var (
+	s3Uploads = metrics.Default.Counter("encode_s3_uploads_total",
+		"S3 uploads by bucket, kind (report or manifest) and result", "bucket", "kind", "result")
+	s3UploadBytes = metrics.Default.Counter("encode_s3_upload_bytes_total",
+		"Bytes of report files uploaded to S3, by bucket", "bucket")
+)
+
+// countUpload records an upload of localPath, if it was a file
+func (u *S3Uploader) countUpload(kind, localPath string, err error) {
+	if err != nil {
+		s3Uploads.Inc(u.config.Bucket, kind, "error")
+		return
+	}
+	s3Uploads.Inc(u.config.Bucket, kind, "ok")
+	if kind != uploadReport {
+		return
+	}
+	if info, statErr := os.Stat(localPath); statErr == nil {
+		s3UploadBytes.Add(float64(info.Size()), u.config.Bucket)
+	}
+}
@@ -0,0 +1,324 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	// PrunedObjectsKeep only removes pruned files from the manifest
+	PrunedObjectsKeep = "keep"
+	// PrunedObjectsDelete deletes pruned files from S3
+	PrunedObjectsDelete = "delete"
+	// PrunedObjectsArchive moves pruned files under archive_prefix
+	PrunedObjectsArchive = "archive"
+)
+
+// uriTimeLayouts are the file name formats a URI's timestamp is read from
+var uriTimeLayouts = []string{
+	"2006-01-02.15.04.05",
+	"2006-01-02",
+	"2006-01",
+}
+
+// ManifestRetention decides which files stay in a report's manifest.
+// A file is kept if any rule keeps it. With no rules set every file is kept.
+// Files whose time can't be read from their name are always kept
+type ManifestRetention struct {
+	// KeepLast keeps the N most recent files
+	KeepLast int `yaml:"keep_last"`
+	// KeepWithin keeps files newer than a duration, e.g. "90d", "12w" or "36h"
+	KeepWithin string `yaml:"keep_within"`
+	// KeepDaily, KeepWeekly and KeepMonthly keep the newest file from each of
+	// the N most recent days, ISO weeks or months that have files
+	KeepDaily   int `yaml:"keep_daily"`
+	KeepWeekly  int `yaml:"keep_weekly"`
+	KeepMonthly int `yaml:"keep_monthly"`
+	// PrunedObjects is what happens to pruned files in S3: "keep" (default), "delete" or "archive"
+	PrunedObjects string `yaml:"pruned_objects"`
+	// ArchivePrefix is where "archive" moves files, as {archive_prefix}/{report_name}/{filename}
+	ArchivePrefix       string `yaml:"archive_prefix"`
+	ArchiveStorageClass string `yaml:"archive_storage_class"`
+	// DryRun logs what would be pruned without changing the manifest or S3
+	DryRun bool `yaml:"dry_run"`
+}
+
+// Enabled reports whether any retention rule is set
+func (r ManifestRetention) Enabled() bool {
+	return r.KeepLast > 0 || r.KeepWithin != "" || r.KeepDaily > 0 || r.KeepWeekly > 0 || r.KeepMonthly > 0
+}
+
+// Validate checks the policy's settings
+func (r ManifestRetention) Validate() error {
+	if r.KeepWithin != "" {
+		if _, err := ParseRetentionDuration(r.KeepWithin); err != nil {
+			return err
+		}
+	}
+	switch r.PrunedObjects {
+	case "", PrunedObjectsKeep, PrunedObjectsDelete:
+	case PrunedObjectsArchive:
+		if r.ArchivePrefix == "" {
+			return errors.New("retention.archive_prefix is required when pruned_objects is archive")
+		}
+	default:
+		return fmt.Errorf("invalid retention.pruned_objects '%s': must be %q, %q or %q", r.PrunedObjects, PrunedObjectsKeep, PrunedObjectsDelete, PrunedObjectsArchive)
+	}
+	return nil
+}
+
+// Apply splits uris into the ones the policy keeps and the ones it prunes,
+// both in their original order
+func (r ManifestRetention) Apply(uris []string, now time.Time) ([]string, []string, error) {
+	if !r.Enabled() {
+		return uris, nil, nil
+	}
+
+	var within time.Duration
+	if r.KeepWithin != "" {
+		var err error
+		within, err = ParseRetentionDuration(r.KeepWithin)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	type dated struct {
+		uri string
+		t   time.Time
+	}
+	var files []dated
+	keep := make(map[string]bool)
+	for _, uri := range uris {
+		t, ok := ParseURITime(uri)
+		if !ok {
+			keep[uri] = true
+			continue
+		}
+		files = append(files, dated{uri: uri, t: t})
+	}
+
+	// Newest first
+	sort.SliceStable(files, func(i, j int) bool { return files[i].t.After(files[j].t) })
+
+	buckets := []struct {
+		n   int
+		key func(time.Time) string
+	}{
+		{r.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{r.KeepWeekly, func(t time.Time) string {
+			y, w := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", y, w)
+		}},
+		{r.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") }},
+	}
+	for _, b := range buckets {
+		if b.n <= 0 {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, f := range files {
+			k := b.key(f.t)
+			if seen[k] {
+				continue
+			}
+			if len(seen) >= b.n {
+				break
+			}
+			seen[k] = true
+			keep[f.uri] = true
+		}
+	}
+
+	for i, f := range files {
+		if i < r.KeepLast {
+			keep[f.uri] = true
+		}
+		if within > 0 && !f.t.Before(now.Add(-within)) {
+			keep[f.uri] = true
+		}
+	}
+
+	var kept, pruned []string
+	for _, uri := range uris {
+		if keep[uri] {
+			kept = append(kept, uri)
+		} else {
+			pruned = append(pruned, uri)
+		}
+	}
+
+	return kept, pruned, nil
+}
+
+// ParseRetentionDuration parses Go durations plus day ("d") and week ("w") units
+func ParseRetentionDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration '%s'", s)
+			}
+			return time.Duration(v) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration '%s': %w", s, err)
+	}
+	return d, nil
+}
+
+// ParseURITime reads the time a report file was written from its name,
+// e.g. s3://bucket/prefix/report/2024-01-31.02.00.00.csv
+func ParseURITime(uri string) (time.Time, bool) {
+	name := path.Base(uri)
+	name = strings.TrimSuffix(name, path.Ext(name))
+	for _, layout := range uriTimeLayouts {
+		if t, err := time.ParseInLocation(layout, name, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ParseS3URI splits s3://bucket/key into its bucket and key
+func ParseS3URI(uri string) (string, string, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "s3" || u.Host == "" {
+		return "", "", fmt.Errorf("invalid S3 URI '%s'", uri)
+	}
+	return u.Host, strings.TrimPrefix(u.Path, "/"), nil
+}
+
+// PruneManifest applies the retention policy to a report's manifest and
+// returns the URIs it removed. In prefix mode the files under the report's
+// folder are pruned instead. With dryRun nothing is changed
+func (u *S3Uploader) PruneManifest(reportName string, dryRun bool) ([]string, error) {
+	retention := u.config.Retention
+	if !retention.Enabled() {
+		return nil, nil
+	}
+
+	if u.manifestMode() == ManifestModePrefix {
+		entries, err := u.ListReportObjects(reportName)
+		if err != nil {
+			return nil, err
+		}
+		uris := make([]string, len(entries))
+		for i, e := range entries {
+			uris[i] = e.URI
+		}
+		_, pruned, err := retention.Apply(uris, time.Now())
+		if err != nil || len(pruned) == 0 {
+			return pruned, err
+		}
+		if dryRun {
+			u.logger(reportName).Info("Retention dry run: would prune files from report folder", "uris", pruned)
+			return pruned, nil
+		}
+		return pruned, u.disposePruned(reportName, pruned)
+	}
+
+	if dryRun {
+		manifest, _, err := u.LoadManifest(reportName)
+		if err != nil {
+			return nil, err
+		}
+		_, pruned, err := retention.Apply(manifest.URIs(), time.Now())
+		return pruned, err
+	}
+
+	var pruned []string
+	err := u.UpdateManifest(reportName, func(m *QuickSightManifest) error {
+		var kept []string
+		var err error
+		kept, pruned, err = retention.Apply(m.URIs(), time.Now())
+		if err != nil {
+			return err
+		}
+		m.SetURIs(kept)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pruned) > 0 {
+		u.logger(reportName).Info("Pruned manifest", "pruned", len(pruned))
+	}
+	return pruned, u.disposePruned(reportName, pruned)
+}
+
+// disposePruned deletes or archives pruned files according to the retention
+// policy. Files outside the report's folder are only dropped from the manifest
+func (u *S3Uploader) disposePruned(reportName string, pruned []string) error {
+	action := u.config.Retention.PrunedObjects
+	if action == "" || action == PrunedObjectsKeep {
+		return nil
+	}
+
+	prefix := u.ReportKey(reportName, "") + "/"
+	var errs []error
+	for _, uri := range pruned {
+		bucket, key, err := ParseS3URI(uri)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if bucket != u.config.Bucket || !strings.HasPrefix(key, prefix) || path.Clean(key) != key {
+			u.logger(reportName).Warn("Leaving pruned file outside the report's folder", "uri", uri, "folder", u.ReportPrefixURI(reportName))
+			continue
+		}
+
+		if action == PrunedObjectsArchive {
+			dest := strings.ReplaceAll(path.Join(u.config.Retention.ArchivePrefix, reportName, path.Base(key)), "\\", "/")
+			input := &s3.CopyObjectInput{
+				Bucket:     aws.String(bucket),
+				Key:        aws.String(dest),
+				CopySource: aws.String(url.PathEscape(bucket) + "/" + escapeKey(key)),
+			}
+			if u.config.Retention.ArchiveStorageClass != "" {
+				input.StorageClass = types.StorageClass(u.config.Retention.ArchiveStorageClass)
+			}
+			_, err = u.client.CopyObject(context.Background(), input)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to archive %s: %w", uri, err))
+				continue
+			}
+			u.logger(reportName).Info("Archived pruned file", "uri", uri, "key", dest)
+		}
+
+		_, err = u.client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete %s: %w", uri, err))
+			continue
+		}
+		u.logger(reportName).Info("Deleted pruned file", "uri", uri)
+	}
+
+	return errors.Join(errs...)
+}
+
+// escapeKey URL-escapes each segment of an S3 key for use in a copy source
+func escapeKey(key string) string {
+	parts := strings.Split(key, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,248 @@
+package storage_test
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/encode/pkg/storage"
+)
+
+func dailyURIs(start time.Time, days int) []string {
+	var uris []string
+	for i := 0; i < days; i++ {
+		uris = append(uris, "s3://reports/encode/gate_counts/"+start.AddDate(0, 0, i).Format("2006-01-02.15.04.05")+".csv")
+	}
+	return uris
+}
+
+func TestManifestRetention_Apply(t *testing.T) {
+	start := time.Date(2024, 1, 1, 2, 0, 0, 0, time.Local)
+	// 2024-01-01 through 2024-03-31
+	uris := dailyURIs(start, 91)
+	now := start.AddDate(0, 0, 91)
+
+	tests := []struct {
+		name       string
+		retention  storage.ManifestRetention
+		uris       []string
+		expectKept []string
+	}{
+		{
+			name:       "No rules keeps everything",
+			uris:       uris[:3],
+			expectKept: uris[:3],
+		},
+		{
+			name:       "Keep last",
+			retention:  storage.ManifestRetention{KeepLast: 2},
+			uris:       uris,
+			expectKept: uris[89:],
+		},
+		{
+			name:       "Keep within days",
+			retention:  storage.ManifestRetention{KeepWithin: "7d"},
+			uris:       uris,
+			expectKept: uris[84:],
+		},
+		{
+			name:      "Keep monthly",
+			retention: storage.ManifestRetention{KeepMonthly: 2},
+			uris:      uris,
+			// Newest file of March and of February
+			expectKept: []string{uris[59], uris[90]},
+		},
+		{
+			name:      "Recent files plus one per month for older periods",
+			retention: storage.ManifestRetention{KeepLast: 1, KeepMonthly: 3},
+			uris:      uris,
+			// January 31, February 29 and March 31
+			expectKept: []string{uris[30], uris[59], uris[90]},
+		},
+		{
+			name:       "Undated files are kept",
+			retention:  storage.ManifestRetention{KeepLast: 1},
+			uris:       []string{"s3://reports/encode/gate_counts/master.csv", uris[0], uris[1]},
+			expectKept: []string{"s3://reports/encode/gate_counts/master.csv", uris[1]},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, pruned, err := tt.retention.Apply(tt.uris, now)
+			if err != nil {
+				t.Fatalf("Apply() failed: %v", err)
+			}
+			if fmt.Sprint(tt.expectKept) != fmt.Sprint(kept) {
+				t.Errorf("Expected kept %v, got %v", tt.expectKept, kept)
+			}
+			if len(kept)+len(pruned) != len(tt.uris) {
+				t.Errorf("Expected %d kept and pruned files, got %d", len(tt.uris), len(kept)+len(pruned))
+			}
+		})
+	}
+}
+
+func TestManifestRetention_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		retention   storage.ManifestRetention
+		expectError bool
+	}{
+		{name: "Empty", retention: storage.ManifestRetention{}},
+		{name: "Weeks", retention: storage.ManifestRetention{KeepWithin: "12w"}},
+		{name: "Hours", retention: storage.ManifestRetention{KeepWithin: "36h"}},
+		{name: "Bad duration", retention: storage.ManifestRetention{KeepWithin: "soon"}, expectError: true},
+		{name: "Archive without prefix", retention: storage.ManifestRetention{PrunedObjects: storage.PrunedObjectsArchive}, expectError: true},
+		{name: "Unknown action", retention: storage.ManifestRetention{PrunedObjects: "shred"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.retention.Validate()
+			if tt.expectError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestGenerateManifest_Retention(t *testing.T) {
+	today := time.Now()
+	old := []string{
+		today.AddDate(0, 0, -30).Format("2006-01-02.15.04.05") + ".csv",
+		today.AddDate(0, 0, -20).Format("2006-01-02.15.04.05") + ".csv",
+	}
+	recent := today.Format("2006-01-02.15.04.05") + ".csv"
+
+	tests := []struct {
+		name          string
+		retention     storage.ManifestRetention
+		expectURIs    []string
+		expectObjects []string
+	}{
+		{
+			name:          "Dry run keeps everything",
+			retention:     storage.ManifestRetention{KeepWithin: "7d", PrunedObjects: storage.PrunedObjectsDelete, DryRun: true},
+			expectURIs:    []string{old[0], old[1], recent},
+			expectObjects: []string{"encode/gate_counts/" + old[0], "encode/gate_counts/" + old[1]},
+		},
+		{
+			name:          "Prune manifest only",
+			retention:     storage.ManifestRetention{KeepWithin: "7d"},
+			expectURIs:    []string{recent},
+			expectObjects: []string{"encode/gate_counts/" + old[0], "encode/gate_counts/" + old[1]},
+		},
+		{
+			name:       "Delete pruned objects",
+			retention:  storage.ManifestRetention{KeepWithin: "7d", PrunedObjects: storage.PrunedObjectsDelete},
+			expectURIs: []string{recent},
+		},
+		{
+			name:          "Archive pruned objects",
+			retention:     storage.ManifestRetention{KeepWithin: "7d", PrunedObjects: storage.PrunedObjectsArchive, ArchivePrefix: "archive"},
+			expectURIs:    []string{recent},
+			expectObjects: []string{"archive/gate_counts/" + old[0], "archive/gate_counts/" + old[1]},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t)
+			uploader, err := storage.NewS3Uploader(storage.S3Config{
+				Enabled:       true,
+				Bucket:        "reports",
+				Prefix:        "encode",
+				ManifestPath:  t.TempDir(),
+				ManifestStore: storage.ManifestStoreS3,
+				Endpoint:      fake.server.URL,
+				UsePathStyle:  true,
+				Retention:     tt.retention,
+			})
+			if err != nil {
+				t.Fatalf("NewS3Uploader() failed: %v", err)
+			}
+
+			for _, name := range append(old, recent) {
+				fake.put("reports", "encode/gate_counts/"+name, []byte("id\n1\n"))
+				err = uploader.GenerateManifest("gate_counts", "s3://reports/encode/gate_counts/"+name, storage.DefaultUploadSettings)
+				if err != nil {
+					t.Fatalf("GenerateManifest() failed: %v", err)
+				}
+			}
+
+			var expectURIs []string
+			for _, name := range tt.expectURIs {
+				expectURIs = append(expectURIs, "s3://reports/encode/gate_counts/"+name)
+			}
+			uris := readManifestURIs(t, fake)
+			if fmt.Sprint(expectURIs) != fmt.Sprint(uris) {
+				t.Errorf("Expected manifest %v, got %v", expectURIs, uris)
+			}
+
+			expectKeys := []string{"reports/encode/gate_counts/" + recent, "reports/encode/manifests/gate_counts/manifest.json"}
+			for _, key := range tt.expectObjects {
+				expectKeys = append(expectKeys, "reports/"+key)
+			}
+			sort.Strings(expectKeys)
+			if fmt.Sprint(expectKeys) != fmt.Sprint(fake.keys()) {
+				t.Errorf("Expected objects %v, got %v", expectKeys, fake.keys())
+			}
+		})
+	}
+}
+
+func TestGenerateManifest_RetentionOutsideReportFolder(t *testing.T) {
+	today := time.Now()
+	old := today.AddDate(0, 0, -30).Format("2006-01-02.15.04.05") + ".csv"
+	recent := today.Format("2006-01-02.15.04.05") + ".csv"
+
+	fake := newFakeS3(t)
+	uploader, err := storage.NewS3Uploader(storage.S3Config{
+		Enabled:       true,
+		Bucket:        "reports",
+		Prefix:        "encode",
+		ManifestPath:  t.TempDir(),
+		ManifestStore: storage.ManifestStoreS3,
+		Endpoint:      fake.server.URL,
+		UsePathStyle:  true,
+		Retention:     storage.ManifestRetention{KeepWithin: "7d", PrunedObjects: storage.PrunedObjectsDelete},
+	})
+	if err != nil {
+		t.Fatalf("NewS3Uploader() failed: %v", err)
+	}
+
+	// Manifests can be edited by hand to list files encode didn't upload
+	foreign := []string{"payroll/" + old, "encode/gate_counts_2/" + old, "encode/circulation/" + old}
+	fake.put("finance", foreign[0], []byte("id\n1\n"))
+	fake.put("reports", foreign[1], []byte("id\n1\n"))
+	fake.put("reports", foreign[2], []byte("id\n1\n"))
+	fake.put("reports", "encode/gate_counts/"+recent, []byte("id\n1\n"))
+	uris := []string{"s3://finance/" + foreign[0], "s3://reports/" + foreign[1], "s3://reports/" + foreign[2], "s3://reports/encode/gate_counts/" + recent}
+	for _, uri := range uris {
+		err = uploader.GenerateManifest("gate_counts", uri, storage.DefaultUploadSettings)
+		if err != nil {
+			t.Fatalf("GenerateManifest() failed: %v", err)
+		}
+	}
+
+	expectURIs := []string{"s3://reports/encode/gate_counts/" + recent}
+	if fmt.Sprint(expectURIs) != fmt.Sprint(readManifestURIs(t, fake)) {
+		t.Errorf("Expected manifest %v, got %v", expectURIs, readManifestURIs(t, fake))
+	}
+	expectKeys := []string{
+		"finance/" + foreign[0],
+		"reports/" + foreign[2],
+		"reports/" + foreign[1],
+		"reports/encode/gate_counts/" + recent,
+		"reports/encode/manifests/gate_counts/manifest.json",
+	}
+	sort.Strings(expectKeys)
+	if fmt.Sprint(expectKeys) != fmt.Sprint(fake.keys()) {
+		t.Errorf("Expected objects outside the report folder to be left, got %v", fake.keys())
+	}
+}
@@ -1,17 +1,23 @@
 package storage
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"gopkg.in/yaml.v3"
 )
 
 type S3Config struct {
@@ -20,42 +26,201 @@ type S3Config struct {
 	Region       string `yaml:"region"`
 	Prefix       string `yaml:"prefix"`
 	ManifestPath string `yaml:"manifest_path"`
+	// ManifestPrefix is the key prefix manifests are uploaded under (default: {prefix}/manifests)
+	ManifestPrefix string `yaml:"manifest_prefix"`
+	// ManifestStore is where the manifest's source of truth lives: "local" (default) or "s3".
+	// With "s3", manifest_path is an optional local mirror
+	ManifestStore string `yaml:"manifest_store"`
+	// StorageClass applies to uploaded report files, e.g. STANDARD_IA (default: bucket default)
+	StorageClass string `yaml:"storage_class"`
+	// Profile selects a named profile from the shared AWS config and credentials files
+	Profile string `yaml:"profile"`
+	// Endpoint is the base URL of an S3-compatible service such as MinIO, Ceph or LocalStack
+	Endpoint string `yaml:"endpoint"`
+	// UsePathStyle addresses buckets as {endpoint}/{bucket} rather than {bucket}.{endpoint}
+	UsePathStyle bool `yaml:"use_path_style"`
+	// CABundle is a PEM file of extra certificate authorities to trust, e.g. for an on-prem endpoint
+	CABundle string `yaml:"ca_bundle"`
+	// ManifestMode is "uris" (default) to list every file, or "prefix" to point the manifest at the report's folder
+	ManifestMode string `yaml:"manifest_mode"`
+	// Retention prunes old files from the manifest each time it is regenerated
+	Retention ManifestRetention `yaml:"retention"`
 }
 
-type S3Uploader struct {
-	client *s3.Client
-	config S3Config
+// S3Override is a report's or sink's s3 block. Its toggles are nil unless
+// the block sets them, so an override can turn off what is enabled globally
+type S3Override struct {
+	S3Config
+	Enabled      *bool
+	UsePathStyle *bool
 }
 
-type QuickSightManifest struct {
-	FileLocations        []FileLocation       `json:"fileLocations"`
-	GlobalUploadSettings GlobalUploadSettings `json:"globalUploadSettings"`
+func (o *S3Override) UnmarshalYAML(value *yaml.Node) error {
+	err := value.Decode(&o.S3Config)
+	if err != nil {
+		return err
+	}
+	var toggles struct {
+		Enabled      *bool `yaml:"enabled"`
+		UsePathStyle *bool `yaml:"use_path_style"`
+	}
+	err = value.Decode(&toggles)
+	if err != nil {
+		return err
+	}
+	o.Enabled = toggles.Enabled
+	o.UsePathStyle = toggles.UsePathStyle
+	return nil
 }
 
-type FileLocation struct {
-	URIs []string `json:"URIs,omitempty"`
+// Merge returns c with every field that override sets replacing c's value.
+// Setting a bucket on the override enables uploads unless it sets enabled: false
+func (c S3Config) Merge(override S3Override) S3Config {
+	merged := c
+	if override.Bucket != "" {
+		merged.Enabled = true
+	}
+	if override.Enabled != nil {
+		merged.Enabled = *override.Enabled
+	}
+	if override.Bucket != "" {
+		merged.Bucket = override.Bucket
+	}
+	if override.Region != "" {
+		merged.Region = override.Region
+	}
+	if override.Prefix != "" {
+		merged.Prefix = override.Prefix
+	}
+	if override.ManifestPath != "" {
+		merged.ManifestPath = override.ManifestPath
+	}
+	if override.ManifestPrefix != "" {
+		merged.ManifestPrefix = override.ManifestPrefix
+	}
+	if override.ManifestStore != "" {
+		merged.ManifestStore = override.ManifestStore
+	}
+	if override.StorageClass != "" {
+		merged.StorageClass = override.StorageClass
+	}
+	if override.Profile != "" {
+		merged.Profile = override.Profile
+	}
+	if override.Endpoint != "" {
+		merged.Endpoint = override.Endpoint
+	}
+	if override.UsePathStyle != nil {
+		merged.UsePathStyle = *override.UsePathStyle
+	}
+	if override.CABundle != "" {
+		merged.CABundle = override.CABundle
+	}
+	if override.ManifestMode != "" {
+		merged.ManifestMode = override.ManifestMode
+	}
+	if override.Retention != (ManifestRetention{}) {
+		merged.Retention = override.Retention
+	}
+	return merged
 }
 
-type GlobalUploadSettings struct {
-	Format         string `json:"format"`
-	Delimiter      string `json:"delimiter,omitempty"`
-	TextQualifier  string `json:"textqualifier,omitempty"`
-	ContainsHeader string `json:"containsHeader"`
+// ClientCache shares one S3 client per region, credentials profile and endpoint
+type ClientCache struct {
+	mu      sync.Mutex
+	clients map[string]*s3.Client
 }
 
-func NewS3Uploader(s3Config S3Config) (*S3Uploader, error) {
-	if !s3Config.Enabled {
-		return &S3Uploader{config: s3Config}, nil
+// NewClientCache returns an empty client cache
+func NewClientCache() *ClientCache {
+	return &ClientCache{clients: make(map[string]*s3.Client)}
+}
+
+// Client returns the cached client for s3Config's region, profile and
+// endpoint settings, creating it on first use
+func (c *ClientCache) Client(s3Config S3Config) (*s3.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := strings.Join([]string{
+		s3Config.Region,
+		s3Config.Profile,
+		s3Config.Endpoint,
+		fmt.Sprint(s3Config.UsePathStyle),
+		s3Config.CABundle,
+	}, "|")
+	if client, ok := c.clients[key]; ok {
+		return client, nil
+	}
+
+	region := s3Config.Region
+	if region == "" && s3Config.Endpoint != "" {
+		// S3-compatible services generally accept any region but the SDK requires one
+		region = "us-east-1"
+	}
+
+	opts := []func(*config.LoadOptions) error{
+		config.WithRegion(region),
+	}
+	if s3Config.Profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(s3Config.Profile))
+	}
+	if s3Config.CABundle != "" {
+		bundle, err := os.ReadFile(s3Config.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle %s: %w", s3Config.CABundle, err)
+		}
+		opts = append(opts, config.WithCustomCABundle(bytes.NewReader(bundle)))
 	}
 
-	cfg, err := config.LoadDefaultConfig(context.Background(),
-		config.WithRegion(s3Config.Region),
-	)
+	cfg, err := config.LoadDefaultConfig(context.Background(), opts...)
 	if err != nil {
 		return nil, fmt.Errorf("unable to load AWS SDK config: %w", err)
 	}
 
-	client := s3.NewFromConfig(cfg)
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.UsePathStyle = s3Config.UsePathStyle
+		if s3Config.Endpoint != "" {
+			o.BaseEndpoint = aws.String(s3Config.Endpoint)
+			// Not every S3-compatible service supports the SDK's default request checksums
+			o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+			o.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenRequired
+		}
+	})
+	c.clients[key] = client
+	slog.Debug("Created S3 client", "region", region, "profile", s3Config.Profile, "endpoint", s3Config.Endpoint)
+	return client, nil
+}
+
+// Uploader returns an uploader for s3Config that shares a cached client
+func (c *ClientCache) Uploader(s3Config S3Config) (*S3Uploader, error) {
+	switch s3Config.ManifestStore {
+	case "", ManifestStoreLocal, ManifestStoreS3:
+	default:
+		return nil, fmt.Errorf("invalid manifest_store '%s': must be %q or %q", s3Config.ManifestStore, ManifestStoreLocal, ManifestStoreS3)
+	}
+	switch s3Config.ManifestMode {
+	case "", ManifestModeURIs:
+	case ManifestModePrefix:
+		// Every file under the prefix is imported, so pruning has to remove it
+		if s3Config.Retention.Enabled() && (s3Config.Retention.PrunedObjects == "" || s3Config.Retention.PrunedObjects == PrunedObjectsKeep) {
+			return nil, fmt.Errorf("retention with manifest_mode '%s' requires pruned_objects %q or %q", ManifestModePrefix, PrunedObjectsDelete, PrunedObjectsArchive)
+		}
+	default:
+		return nil, fmt.Errorf("invalid manifest_mode '%s': must be %q or %q", s3Config.ManifestMode, ManifestModeURIs, ManifestModePrefix)
+	}
+	if err := s3Config.Retention.Validate(); err != nil {
+		return nil, err
+	}
+
+	if !s3Config.Enabled {
+		return &S3Uploader{config: s3Config}, nil
+	}
+
+	client, err := c.Client(s3Config)
+	if err != nil {
+		return nil, err
+	}
 
 	return &S3Uploader{
 		client: client,
@@ -63,10 +228,46 @@ func NewS3Uploader(s3Config S3Config) (*S3Uploader, error) {
 	}, nil
 }
 
+type S3Uploader struct {
+	client *s3.Client
+	config S3Config
+	log    *slog.Logger
+}
+
+// WithLogger returns a copy of the uploader that logs to log, for the lines
+// of a report run to carry its run ID. The copy shares the S3 client
+func (u *S3Uploader) WithLogger(log *slog.Logger) *S3Uploader {
+	if u == nil {
+		return nil
+	}
+	c := *u
+	c.log = log
+	return &c
+}
+
+// logger is the uploader's logger, or one that tags lines with the report
+func (u *S3Uploader) logger(reportName string) *slog.Logger {
+	if u.log != nil {
+		return u.log
+	}
+	return slog.Default().With("report", reportName)
+}
+
+// NewS3Uploader returns an uploader with its own S3 client.
+// Use a ClientCache to share clients between uploaders
+func NewS3Uploader(s3Config S3Config) (*S3Uploader, error) {
+	return NewClientCache().Uploader(s3Config)
+}
+
+// Config returns the settings the uploader was created with
+func (u *S3Uploader) Config() S3Config {
+	return u.config
+}
+
 // UploadFile uploads a local file to S3
 func (u *S3Uploader) UploadFile(localPath, reportName string) (string, error) {
 	if !u.config.Enabled {
-		slog.Debug("S3 upload disabled, skipping")
+		u.logger(reportName).Debug("S3 upload disabled, skipping")
 		return "", nil
 	}
 
@@ -76,132 +277,87 @@ func (u *S3Uploader) UploadFile(localPath, reportName string) (string, error) {
 	}
 	defer file.Close()
 
-	// Generate S3 key: prefix/report_name/filename
-	filename := filepath.Base(localPath)
-	key := filepath.Join(u.config.Prefix, reportName, filename)
-	// Normalize path separators for S3 (always use forward slash)
-	key = strings.ReplaceAll(key, "\\", "/")
+	key := u.ReportKey(reportName, filepath.Base(localPath))
 
-	slog.Info("Uploading file to S3", "localPath", localPath, "bucket", u.config.Bucket, "key", key)
+	u.logger(reportName).Info("Uploading file to S3", "localPath", localPath, "bucket", u.config.Bucket, "key", key)
 
-	_, err = u.client.PutObject(context.Background(), &s3.PutObjectInput{
+	input := &s3.PutObjectInput{
 		Bucket: aws.String(u.config.Bucket),
 		Key:    aws.String(key),
 		Body:   file,
-	})
+	}
+	if u.config.StorageClass != "" {
+		input.StorageClass = types.StorageClass(u.config.StorageClass)
+	}
+
+	_, err = u.client.PutObject(context.Background(), input)
+	u.countUpload(uploadReport, localPath, err)
 	if err != nil {
 		return "", fmt.Errorf("failed to upload to S3: %w", err)
 	}
 
 	// Return the S3 URI
 	uri := fmt.Sprintf("s3://%s/%s", u.config.Bucket, key)
-	slog.Info("Successfully uploaded to S3", "uri", uri)
+	u.logger(reportName).Info("Successfully uploaded to S3", "uri", uri)
 	return uri, nil
 }
 
-// GenerateManifest creates or updates a QuickSight-compatible manifest file
-// It appends new URIs to existing ones to maintain historical data for QuickSight
-func (u *S3Uploader) GenerateManifest(reportName string, newS3URI string) error {
-	if !u.config.Enabled || u.config.ManifestPath == "" {
-		return nil
-	}
-
-	// Create manifest directory if it doesn't exist
-	manifestDir := filepath.Join(u.config.ManifestPath, reportName)
-	if err := os.MkdirAll(manifestDir, 0755); err != nil {
-		return fmt.Errorf("failed to create manifest directory: %w", err)
-	}
-
-	// Use a static manifest filename per report (not date-based)
-	manifestFile := filepath.Join(manifestDir, "manifest.json")
-
-	// Load existing manifest if it exists
-	var existingURIs []string
-	if existingData, err := os.ReadFile(manifestFile); err == nil {
-		var existingManifest QuickSightManifest
-		if err := json.Unmarshal(existingData, &existingManifest); err == nil {
-			if len(existingManifest.FileLocations) > 0 {
-				existingURIs = existingManifest.FileLocations[0].URIs
-			}
-		}
-	}
-
-	// Append new URI if not already present
-	allURIs := existingURIs
-	isDuplicate := false
-	for _, uri := range existingURIs {
-		if uri == newS3URI {
-			isDuplicate = true
-			break
-		}
-	}
-	if !isDuplicate {
-		allURIs = append(allURIs, newS3URI)
+// DownloadReportFile copies prefix/report_name/filename to dest. It returns
+// false when the object does not exist
+func (u *S3Uploader) DownloadReportFile(reportName, filename, dest string) (bool, error) {
+	key := u.ReportKey(reportName, filename)
+	resp, err := u.client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(u.config.Bucket),
+		Key:    aws.String(key),
+	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return false, nil
 	}
-
-	// Create updated manifest
-	manifest := QuickSightManifest{
-		FileLocations: []FileLocation{
-			{
-				URIs: allURIs,
-			},
-		},
-		GlobalUploadSettings: GlobalUploadSettings{
-			Format:         "CSV",
-			Delimiter:      ",",
-			TextQualifier:  "\"",
-			ContainsHeader: "true",
-		},
+	if err != nil {
+		return false, fmt.Errorf("failed to download s3://%s/%s: %w", u.config.Bucket, key, err)
 	}
+	defer resp.Body.Close()
 
-	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	file, err := os.Create(dest)
 	if err != nil {
-		return fmt.Errorf("failed to marshal manifest: %w", err)
+		return false, fmt.Errorf("failed to create %s: %w", dest, err)
 	}
+	defer file.Close()
 
-	if err := os.WriteFile(manifestFile, manifestJSON, 0644); err != nil {
-		return fmt.Errorf("failed to write manifest file: %w", err)
+	_, err = io.Copy(file, resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("failed to download s3://%s/%s: %w", u.config.Bucket, key, err)
 	}
 
-	slog.Info("Generated QuickSight manifest", "file", manifestFile, "totalURIs", len(allURIs))
-	return nil
+	u.logger(reportName).Info("Downloaded file from S3", "bucket", u.config.Bucket, "key", key, "localPath", dest)
+	return true, file.Close()
 }
 
-// UploadManifestForReport uploads the manifest file for a specific report to S3
-func (u *S3Uploader) UploadManifestForReport(reportName string) (string, error) {
-	if !u.config.Enabled {
-		return "", nil
-	}
-
-	manifestDir := filepath.Join(u.config.ManifestPath, reportName)
-	localManifestPath := filepath.Join(manifestDir, "manifest.json")
+// ReportKey is the S3 key of a report file: prefix/report_name/filename
+func (u *S3Uploader) ReportKey(reportName, filename string) string {
+	key := filepath.Join(u.config.Prefix, reportName, filename)
+	// Normalize path separators for S3 (always use forward slash)
+	return strings.ReplaceAll(key, "\\", "/")
+}
 
-	// Check if manifest exists
-	if _, err := os.Stat(localManifestPath); os.IsNotExist(err) {
-		return "", fmt.Errorf("manifest file does not exist: %s", localManifestPath)
-	}
+// ReportPrefixURI is the S3 URI of the folder a report's files are uploaded to
+func (u *S3Uploader) ReportPrefixURI(reportName string) string {
+	return fmt.Sprintf("s3://%s/%s/", u.config.Bucket, u.ReportKey(reportName, ""))
+}
 
-	file, err := os.Open(localManifestPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to open manifest file %s: %w", localManifestPath, err)
+// ObjectURL is the HTTPS URL of key, using the custom endpoint when one is set
+func (u *S3Uploader) ObjectURL(key string) string {
+	if u.config.Endpoint == "" {
+		return fmt.Sprintf("https://%s.s3-%s.amazonaws.com/%s", u.config.Bucket, u.config.Region, key)
 	}
-	defer file.Close()
-
-	key := filepath.Join(u.config.Prefix, "manifests", reportName, "manifest.json")
-	key = strings.ReplaceAll(key, "\\", "/")
 
-	slog.Info("Uploading manifest to S3", "localPath", localManifestPath, "bucket", u.config.Bucket, "key", key)
-
-	_, err = u.client.PutObject(context.Background(), &s3.PutObjectInput{
-		Bucket: aws.String(u.config.Bucket),
-		Key:    aws.String(key),
-		Body:   file,
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to upload manifest to S3: %w", err)
+	endpoint, err := url.Parse(u.config.Endpoint)
+	if err != nil || endpoint.Host == "" || u.config.UsePathStyle {
+		return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(u.config.Endpoint, "/"), u.config.Bucket, key)
 	}
 
-	manifestURL := fmt.Sprintf("https://%s.s3-%s.amazonaws.com/%s", u.config.Bucket, u.config.Region, key)
-	slog.Info("Successfully uploaded manifest to S3", "url", manifestURL)
-	return manifestURL, nil
+	endpoint.Host = u.config.Bucket + "." + endpoint.Host
+	endpoint.Path = "/" + key
+	return endpoint.String()
 }
@@ -0,0 +1,205 @@
+package storage_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lehigh-university-libraries/encode/pkg/storage"
+)
+
+func TestS3Config_Merge(t *testing.T) {
+	global := storage.S3Config{
+		Enabled:      true,
+		Bucket:       "library-reports",
+		Region:       "us-east-1",
+		Prefix:       "encode",
+		ManifestPath: "/tmp/manifests",
+	}
+	disabled := false
+
+	tests := []struct {
+		name     string
+		base     storage.S3Config
+		override storage.S3Override
+		expected storage.S3Config
+	}{
+		{
+			name:     "Empty override inherits everything",
+			base:     global,
+			override: storage.S3Override{},
+			expected: global,
+		},
+		{
+			name: "Override replaces only the fields it sets",
+			base: global,
+			override: storage.S3Override{S3Config: storage.S3Config{
+				Bucket:       "special-collections-reports",
+				Prefix:       "sc",
+				StorageClass: "STANDARD_IA",
+			}},
+			expected: storage.S3Config{
+				Enabled:      true,
+				Bucket:       "special-collections-reports",
+				Region:       "us-east-1",
+				Prefix:       "sc",
+				ManifestPath: "/tmp/manifests",
+				StorageClass: "STANDARD_IA",
+			},
+		},
+		{
+			name:     "Bucket on the override enables uploads",
+			base:     storage.S3Config{Region: "us-east-1"},
+			override: storage.S3Override{S3Config: storage.S3Config{Bucket: "library-reports"}},
+			expected: storage.S3Config{Enabled: true, Bucket: "library-reports", Region: "us-east-1"},
+		},
+		{
+			name:     "Use path style false on the override turns it off",
+			base:     storage.S3Config{Bucket: "library-reports", Endpoint: "https://minio.lib.lehigh.edu", UsePathStyle: true},
+			override: storage.S3Override{UsePathStyle: &disabled},
+			expected: storage.S3Config{Bucket: "library-reports", Endpoint: "https://minio.lib.lehigh.edu"},
+		},
+		{
+			name:     "Enabled false on the override disables uploads",
+			base:     global,
+			override: storage.S3Override{Enabled: &disabled},
+			expected: storage.S3Config{Bucket: "library-reports", Region: "us-east-1", Prefix: "encode", ManifestPath: "/tmp/manifests"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := tt.base.Merge(tt.override)
+			if !reflect.DeepEqual(tt.expected, merged) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, merged)
+			}
+		})
+	}
+}
+
+func TestClientCache_SharesClientPerRegion(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+	cache := storage.NewClientCache()
+
+	a, err := cache.Client(storage.S3Config{Region: "us-east-1", Bucket: "a"})
+	if err != nil {
+		t.Fatalf("Client() failed: %v", err)
+	}
+	b, err := cache.Client(storage.S3Config{Region: "us-east-1", Bucket: "b"})
+	if err != nil {
+		t.Fatalf("Client() failed: %v", err)
+	}
+	c, err := cache.Client(storage.S3Config{Region: "us-west-2", Bucket: "a"})
+	if err != nil {
+		t.Fatalf("Client() failed: %v", err)
+	}
+
+	if a != b {
+		t.Error("Expected buckets in the same region to share a client")
+	}
+	if a == c {
+		t.Error("Expected different regions to get different clients")
+	}
+}
+
+func TestS3Uploader_CustomEndpoint(t *testing.T) {
+	fake := newFakeS3(t)
+	tmpDir := t.TempDir()
+
+	uploader, err := storage.NewS3Uploader(storage.S3Config{
+		Enabled:      true,
+		Bucket:       "archive",
+		Prefix:       "encode",
+		ManifestPath: filepath.Join(tmpDir, "manifests"),
+		Endpoint:     fake.server.URL,
+		UsePathStyle: true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Uploader() failed: %v", err)
+	}
+
+	local := filepath.Join(tmpDir, "2024-01-01.00.00.00.csv")
+	err = os.WriteFile(local, []byte("id,name\n1,Alice\n"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	uri, err := uploader.UploadFile(local, "users")
+	if err != nil {
+		t.Fatalf("UploadFile() failed: %v", err)
+	}
+	if uri != "s3://archive/encode/users/2024-01-01.00.00.00.csv" {
+		t.Errorf("Unexpected URI %s", uri)
+	}
+	data, ok := fake.get("archive", "encode/users/2024-01-01.00.00.00.csv")
+	if !ok || string(data) != "id,name\n1,Alice\n" {
+		t.Errorf("Expected uploaded object, got %q", data)
+	}
+
+	err = uploader.GenerateManifest("users", uri, storage.DefaultUploadSettings)
+	if err != nil {
+		t.Fatalf("GenerateManifest() failed: %v", err)
+	}
+	manifestURL, err := uploader.UploadManifestForReport("users")
+	if err != nil {
+		t.Fatalf("UploadManifestForReport() failed: %v", err)
+	}
+
+	expectedURL := fake.server.URL + "/archive/encode/manifests/users/manifest.json"
+	if manifestURL != expectedURL {
+		t.Errorf("Expected manifest URL %s, got %s", expectedURL, manifestURL)
+	}
+
+	data, ok = fake.get("archive", "encode/manifests/users/manifest.json")
+	if !ok {
+		t.Fatalf("Expected manifest to be uploaded, have %v", fake.keys())
+	}
+	var manifest storage.QuickSightManifest
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+	if !reflect.DeepEqual([]string{uri}, manifest.FileLocations[0].URIs) {
+		t.Errorf("Expected manifest to list %s, got %v", uri, manifest.FileLocations[0].URIs)
+	}
+}
+
+func TestS3Uploader_ObjectURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   storage.S3Config
+		expected string
+	}{
+		{
+			name:     "AWS",
+			config:   storage.S3Config{Bucket: "reports", Region: "us-east-1"},
+			expected: "https://reports.s3-us-east-1.amazonaws.com/m/manifest.json",
+		},
+		{
+			name:     "Path style endpoint",
+			config:   storage.S3Config{Bucket: "reports", Endpoint: "https://minio.lib.lehigh.edu:9000/", UsePathStyle: true},
+			expected: "https://minio.lib.lehigh.edu:9000/reports/m/manifest.json",
+		},
+		{
+			name:     "Virtual host endpoint",
+			config:   storage.S3Config{Bucket: "reports", Endpoint: "https://ceph.lib.lehigh.edu"},
+			expected: "https://reports.ceph.lib.lehigh.edu/m/manifest.json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uploader, err := storage.NewS3Uploader(tt.config)
+			if err != nil {
+				t.Fatalf("NewS3Uploader() failed: %v", err)
+			}
+			if got := uploader.ObjectURL("m/manifest.json"); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}